@@ -6,6 +6,7 @@ package client
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 
 	pb "github.com/kelindar/talaria/proto"
@@ -17,6 +18,8 @@ import (
 const (
 	commandName        = "talaria"
 	defaultDialTimeout = 5 * time.Second
+	defaultPageSize    = 8 << 20
+	maxMessageSize     = 32 << 20 // The minimum size of a message received, above the 4MB of grpc
 )
 
 var (
@@ -30,9 +33,17 @@ func init() {
 
 // Client represents a client for Talaria.
 type Client struct {
-	netconf netconf          // The network pool configuration.
-	ingress pb.IngressClient // The underlying service.
-	conn    *grpc.ClientConn // The underlying connection
+	netconf    netconf                     // The network pool configuration.
+	ingress    pb.IngressClient            // The underlying service.
+	query      pb.QueryClient              // The underlying query service.
+	conn       *grpc.ClientConn            // The underlying connection
+	lock       sync.Mutex                  // The lock for the peer connections
+	peers      map[string]*grpc.ClientConn // The connections to the hosts of the splits
+	pageSize   int64                       // The maximum size of a page of rows, in bytes
+	validate   string                      // The table to validate the events against, if any
+	schema     Schema                      // The cached schema of the table to validate against
+	schemaTime time.Time                   // The time at which the schema was retrieved
+	schemaLock sync.Mutex                  // The lock for the cached schema
 }
 
 // Dial creates a new client and connect to Talaria grpc server.
@@ -49,6 +60,8 @@ func Dial(address string, options ...Option) (*Client, error) {
 			Address:     address,
 			DialTimeout: defaultDialTimeout,
 		},
+		peers:    make(map[string]*grpc.ClientConn),
+		pageSize: defaultPageSize,
 	}
 
 	// Apply the options to overwrite the defaults
@@ -65,7 +78,6 @@ func Dial(address string, options ...Option) (*Client, error) {
 }
 
 func (c *Client) connect() error {
-	var conn *grpc.ClientConn
 	timeoutCtx, cancel := context.WithTimeout(context.Background(), c.netconf.DialTimeout)
 	defer cancel()
 
@@ -78,30 +90,84 @@ func (c *Client) connect() error {
 		dialOptions = append(dialOptions, grpc.WithBlock())
 	}
 
-	if c.isConnectionInsecure() {
-		dialOptions = append(dialOptions, grpc.WithInsecure())
-	} else {
-		dialOptions = append(dialOptions, grpc.WithTransportCredentials(c.netconf.Credentials))
-	}
-
-	conn, err := grpc.DialContext(timeoutCtx, c.netconf.Address, dialOptions...)
+	conn, err := grpc.DialContext(timeoutCtx, c.netconf.Address, append(dialOptions, c.transport(), c.callOptions())...)
 	if err != nil {
 		return ErrUnableToConnect
 	}
+
+	c.conn = conn
 	c.ingress = pb.NewIngressClient(conn)
+	c.query = pb.NewQueryClient(conn)
 	return nil
 }
 
+// transport returns the dial option for the transport, secure if the credentials are set
+func (c *Client) transport() grpc.DialOption {
+	if c.isConnectionInsecure() {
+		return grpc.WithInsecure()
+	}
+	return grpc.WithTransportCredentials(c.netconf.Credentials)
+}
+
+// callOptions returns the dial option which allows to receive the pages of rows. A page may exceed
+// its maximum size by a block, hence twice the page size is allowed.
+func (c *Client) callOptions() grpc.DialOption {
+	size := maxMessageSize
+	if 2*c.pageSize > int64(size) {
+		size = int(2 * c.pageSize)
+	}
+	return grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(size))
+}
+
 func (c *Client) isConnectionInsecure() bool {
 	return c.netconf.Credentials == nil
 }
 
 // IngestBatch sends a batch of events to Talaria server.
 func (c *Client) IngestBatch(ctx context.Context, batch []Event) error {
-	encoded := newEncoder().Encode(batch)
+	schema, err := c.validator(ctx)
+	if err != nil {
+		return err
+	}
+
+	if schema != nil {
+		for _, event := range batch {
+			if err := schema.Validate(event); err != nil {
+				return err
+			}
+		}
+	}
+
+	return c.ingestBatch(ctx, newEncoder().Encode(batch))
+}
+
+// IngestStructs sends a slice of structs, or of pointers to structs, to Talaria server. The fields
+// are encoded according to their `talaria:"name,type"` tag.
+func (c *Client) IngestStructs(ctx context.Context, values interface{}) error {
+	schema, err := c.validator(ctx)
+	if err != nil {
+		return err
+	}
+
+	if schema != nil {
+		if err := schema.ValidateStruct(values); err != nil {
+			return err
+		}
+	}
+
+	encoded, err := newEncoder().EncodeStructs(values)
+	if err != nil {
+		return err
+	}
+
+	return c.ingestBatch(ctx, encoded)
+}
+
+// ingestBatch sends an encoded batch of events to Talaria server.
+func (c *Client) ingestBatch(ctx context.Context, batch *pb.Batch) error {
 	req := &pb.IngestRequest{
 		Data: &pb.IngestRequest_Batch{
-			Batch: encoded,
+			Batch: batch,
 		},
 	}
 
@@ -159,7 +225,14 @@ func (c *Client) IngestParquet(ctx context.Context, data []byte) error {
 	}, nil)
 }
 
-// Close connection
+// Close closes the connection along with the connections to the hosts of the splits
 func (c *Client) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for address, conn := range c.peers {
+		_ = conn.Close()
+		delete(c.peers, address)
+	}
+
 	return c.conn.Close()
 }
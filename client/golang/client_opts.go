@@ -66,3 +66,18 @@ func WithLoadBalancer(name string) Option {
 		client.netconf.LoadBalancer = name
 	}
 }
+
+// WithPageSize specifies the maximum size, in bytes, of a page of rows retrieved by a query
+func WithPageSize(bytes int64) Option {
+	return func(client *Client) {
+		client.pageSize = bytes
+	}
+}
+
+// WithValidation validates the events against the schema of a table before sending them, the
+// schema is retrieved from the server and cached for a minute.
+func WithValidation(table string) Option {
+	return func(client *Client) {
+		client.validate = table
+	}
+}
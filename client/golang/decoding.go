@@ -0,0 +1,123 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	pb "github.com/kelindar/talaria/proto"
+)
+
+// Batch represents a batch of rows in a columnar form.
+type Batch struct {
+	Columns []string        // The names of the columns
+	Values  [][]interface{} // The values of each column, nulls are represented as nil
+	Count   int             // The number of rows
+}
+
+// Row returns a row of the batch as an event
+func (b *Batch) Row(i int) Event {
+	row := make(Event, len(b.Columns))
+	for c, name := range b.Columns {
+		if v := b.Values[c][i]; v != nil {
+			row[name] = v
+		}
+	}
+	return row
+}
+
+// decodeBatch decodes a page of rows, the columns are returned in the requested order
+func decodeBatch(columns []string, page *pb.GetRowsResponse) (*Batch, error) {
+	batch := &Batch{
+		Columns: columns,
+		Values:  make([][]interface{}, 0, len(columns)),
+		Count:   int(page.RowCount),
+	}
+
+	if batch.Count == 0 {
+		return batch, nil
+	}
+
+	if len(page.Columns) != len(columns) {
+		return nil, fmt.Errorf("talaria: expected %d columns but received %d", len(columns), len(page.Columns))
+	}
+
+	for i, column := range page.Columns {
+		values, err := decodeColumn(column)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(values) != batch.Count {
+			return nil, fmt.Errorf("talaria: expected %d values for column %s but received %d", batch.Count, columns[i], len(values))
+		}
+
+		batch.Values = append(batch.Values, values)
+	}
+	return batch, nil
+}
+
+// decodeColumn converts a Talaria protobuf column to its values according to its type
+func decodeColumn(column *pb.Column) ([]interface{}, error) {
+	switch c := column.Value.(type) {
+	case *pb.Column_Int32:
+		return decodeValues(c.Int32.Nulls, func(i int) interface{} {
+			return c.Int32.Ints[i]
+		}), nil
+	case *pb.Column_Int64:
+		return decodeValues(c.Int64.Nulls, func(i int) interface{} {
+			return c.Int64.Longs[i]
+		}), nil
+	case *pb.Column_Float64:
+		return decodeValues(c.Float64.Nulls, func(i int) interface{} {
+			return c.Float64.Doubles[i]
+		}), nil
+	case *pb.Column_Bool:
+		return decodeValues(c.Bool.Nulls, func(i int) interface{} {
+			return c.Bool.Bools[i]
+		}), nil
+	case *pb.Column_Time: // The timestamps are in UNIX milliseconds
+		return decodeValues(c.Time.Nulls, func(i int) interface{} {
+			return time.Unix(0, c.Time.Longs[i]*int64(time.Millisecond)).UTC()
+		}), nil
+	case *pb.Column_String_:
+		strings := decodeStrings(c.String_)
+		return decodeValues(c.String_.Nulls, func(i int) interface{} {
+			return string(strings[i])
+		}), nil
+	case *pb.Column_Json:
+		strings := decodeStrings(c.Json)
+		return decodeValues(c.Json.Nulls, func(i int) interface{} {
+			return json.RawMessage(strings[i])
+		}), nil
+	default:
+		return nil, fmt.Errorf("talaria: unsupported column type %T", column.Value)
+	}
+}
+
+// decodeValues decodes the values of a column, using nil for the null values
+func decodeValues(nulls []bool, valueAt func(i int) interface{}) []interface{} {
+	values := make([]interface{}, len(nulls))
+	for i, null := range nulls {
+		if !null {
+			values[i] = valueAt(i)
+		}
+	}
+	return values
+}
+
+// decodeStrings splits the concatenated bytes of a string column by their sizes
+func decodeStrings(column *pb.ColumnOfString) [][]byte {
+	offset := 0
+	out := make([][]byte, len(column.Sizes))
+	for i, size := range column.Sizes {
+		if end := offset + int(size); end <= len(column.Bytes) {
+			out[i] = column.Bytes[offset:end]
+			offset = end
+		}
+	}
+	return out
+}
@@ -31,9 +31,7 @@ func (e *encoder) Encode(events []Event) *pb.Batch {
 	defer e.Unlock()
 
 	// Reset the buffer
-	e.next = 0
-	e.dictionary = make(map[string]uint32, len(events))
-	e.batch = &pb.Batch{Events: make([]*pb.Event, 0, len(events))}
+	e.reset(len(events))
 
 	// Write the events
 	for _, ev := range events {
@@ -46,6 +44,40 @@ func (e *encoder) Encode(events []Event) *pb.Batch {
 	return e.batch
 }
 
+// EncodeStructs encodes a slice of structs, or of pointers to structs, using the cached codec of their type
+func (e *encoder) EncodeStructs(values interface{}) (*pb.Batch, error) {
+	structs, codec, err := structsOf(values)
+	if err != nil {
+		return nil, err
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	// Reset the buffer
+	e.reset(len(structs))
+
+	// Write the events
+	for _, v := range structs {
+		encoded, err := e.encodeStruct(codec, v)
+		if err != nil {
+			return nil, err
+		}
+		e.batch.Events = append(e.batch.Events, encoded)
+	}
+
+	// Write the interned strings
+	e.writeDictionary()
+	return e.batch, nil
+}
+
+// reset resets the buffer for a number of events
+func (e *encoder) reset(count int) {
+	e.next = 0
+	e.dictionary = make(map[string]uint32, count)
+	e.batch = &pb.Batch{Events: make([]*pb.Event, 0, count)}
+}
+
 // updateDict maps a string to an integer
 func (e *encoder) updateDict(str string) uint32 {
 	// Fetch the value we previously dictionary first
@@ -0,0 +1,319 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/kelindar/talaria/proto"
+)
+
+// The types of the values, named as in the schema of a table
+const (
+	typeInt32     = "int32"
+	typeInt64     = "int64"
+	typeFloat64   = "float64"
+	typeString    = "string"
+	typeBool      = "bool"
+	typeTimestamp = "timestamp"
+	typeJSON      = "json"
+)
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	jsonType = reflect.TypeOf(json.RawMessage{})
+)
+
+// The cache of the codecs of each struct type
+var codecs sync.Map
+
+// codec represents an encoder of a struct type, with one encoder per field
+type codec struct {
+	fields []field
+}
+
+// field represents an encoder of a struct field, which writes a Talaria protobuf value directly
+type field struct {
+	index  []int                                                // The index of the field, for embedded structs
+	name   string                                               // The name of the column
+	kind   string                                               // The type of the column
+	encode func(e *encoder, v reflect.Value) (*pb.Value, error) // The encoder of the value
+}
+
+// codecOf returns the cached codec of a struct type, or creates it. The fields are encoded
+// according to their `talaria:"name,type"` tag, for example `talaria:"event,string"`. If the
+// name is omitted, the name of the field is used and if the type is omitted, it is inferred
+// from the type of the field. Fields tagged with `talaria:"-"` are ignored.
+func codecOf(t reflect.Type) (*codec, error) {
+	if c, ok := codecs.Load(t); ok {
+		return c.(*codec), nil
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("talaria: unable to encode %v, expected a struct", t)
+	}
+
+	c := new(codec)
+	if err := c.addFields(t, nil); err != nil {
+		return nil, err
+	}
+
+	codecs.Store(t, c)
+	return c, nil
+}
+
+// addFields adds the fields of a struct type, flattening the untagged embedded structs
+func (c *codec) addFields(t reflect.Type, index []int) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, tagged := f.Tag.Lookup("talaria")
+		if tag == "-" || (f.PkgPath != "" && !f.Anonymous) {
+			continue
+		}
+
+		path := append(append([]int{}, index...), i)
+		if f.Anonymous && !tagged && f.Type.Kind() == reflect.Struct && f.Type != timeType {
+			if err := c.addFields(f.Type, path); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if f.PkgPath != "" {
+			continue // Unexported
+		}
+
+		name, kind := parseTag(tag)
+		if name == "" {
+			name = f.Name
+		}
+
+		if kind == "" {
+			kind = typeOf(f.Type)
+		}
+
+		encode, err := encoderOf(f.Type, kind)
+		if err != nil {
+			return fmt.Errorf("talaria: unable to encode field %s, %v", f.Name, err)
+		}
+
+		c.fields = append(c.fields, field{
+			index:  path,
+			name:   name,
+			kind:   kind,
+			encode: encode,
+		})
+	}
+	return nil
+}
+
+// parseTag parses the name and the type of a struct tag
+func parseTag(tag string) (name, kind string) {
+	parts := strings.SplitN(tag, ",", 2)
+	name = strings.TrimSpace(parts[0])
+	if len(parts) > 1 {
+		kind = strings.ToLower(strings.TrimSpace(parts[1]))
+	}
+	return
+}
+
+// typeOf infers the type of a column from the type of a field
+func typeOf(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return typeTimestamp
+	case t == jsonType:
+		return typeJSON
+	}
+
+	switch t.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return typeInt32
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return typeInt64
+	case reflect.Float32, reflect.Float64:
+		return typeFloat64
+	case reflect.String:
+		return typeString
+	case reflect.Bool:
+		return typeBool
+	default:
+		return typeJSON
+	}
+}
+
+// encoderOf returns the encoder of a field of a type into a column of a type
+func encoderOf(t reflect.Type, kind string) (func(*encoder, reflect.Value) (*pb.Value, error), error) {
+	if t.Kind() == reflect.Ptr {
+		encode, err := encoderOf(t.Elem(), kind)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(e *encoder, v reflect.Value) (*pb.Value, error) {
+			if v.IsNil() {
+				return nil, nil
+			}
+			return encode(e, v.Elem())
+		}, nil
+	}
+
+	switch k := t.Kind(); kind {
+	case typeInt32:
+		switch {
+		case isInt(k):
+			return func(_ *encoder, v reflect.Value) (*pb.Value, error) {
+				return &pb.Value{Value: &pb.Value_Int32{Int32: int32(v.Int())}}, nil
+			}, nil
+		case isUint(k):
+			return func(_ *encoder, v reflect.Value) (*pb.Value, error) {
+				return &pb.Value{Value: &pb.Value_Int32{Int32: int32(v.Uint())}}, nil
+			}, nil
+		}
+
+	case typeInt64:
+		switch {
+		case isInt(k):
+			return func(_ *encoder, v reflect.Value) (*pb.Value, error) {
+				return &pb.Value{Value: &pb.Value_Int64{Int64: v.Int()}}, nil
+			}, nil
+		case isUint(k):
+			return func(_ *encoder, v reflect.Value) (*pb.Value, error) {
+				return &pb.Value{Value: &pb.Value_Int64{Int64: int64(v.Uint())}}, nil
+			}, nil
+		}
+
+	case typeFloat64:
+		switch {
+		case k == reflect.Float32 || k == reflect.Float64:
+			return func(_ *encoder, v reflect.Value) (*pb.Value, error) {
+				return &pb.Value{Value: &pb.Value_Float64{Float64: v.Float()}}, nil
+			}, nil
+		case isInt(k):
+			return func(_ *encoder, v reflect.Value) (*pb.Value, error) {
+				return &pb.Value{Value: &pb.Value_Float64{Float64: float64(v.Int())}}, nil
+			}, nil
+		}
+
+	case typeString:
+		if k == reflect.String {
+			return func(e *encoder, v reflect.Value) (*pb.Value, error) {
+				return &pb.Value{Value: &pb.Value_String_{String_: e.updateDict(v.String())}}, nil
+			}, nil
+		}
+
+	case typeBool:
+		if k == reflect.Bool {
+			return func(_ *encoder, v reflect.Value) (*pb.Value, error) {
+				return &pb.Value{Value: &pb.Value_Bool{Bool: v.Bool()}}, nil
+			}, nil
+		}
+
+	case typeTimestamp:
+		switch {
+		case t == timeType:
+			return func(_ *encoder, v reflect.Value) (*pb.Value, error) {
+				return &pb.Value{Value: &pb.Value_Time{Time: v.Interface().(time.Time).Unix()}}, nil
+			}, nil
+		case isInt(k): // Assume it's in UNIX seconds
+			return func(_ *encoder, v reflect.Value) (*pb.Value, error) {
+				return &pb.Value{Value: &pb.Value_Time{Time: v.Int()}}, nil
+			}, nil
+		}
+
+	case typeJSON:
+		switch {
+		case t == jsonType:
+			return func(e *encoder, v reflect.Value) (*pb.Value, error) {
+				return &pb.Value{Value: &pb.Value_Json{Json: e.updateDict(string(v.Bytes()))}}, nil
+			}, nil
+		case k == reflect.String:
+			return func(e *encoder, v reflect.Value) (*pb.Value, error) {
+				return &pb.Value{Value: &pb.Value_Json{Json: e.updateDict(v.String())}}, nil
+			}, nil
+		default:
+			return func(e *encoder, v reflect.Value) (*pb.Value, error) {
+				b, err := json.Marshal(v.Interface())
+				if err != nil {
+					return nil, err
+				}
+				return &pb.Value{Value: &pb.Value_Json{Json: e.updateDict(string(b))}}, nil
+			}, nil
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported type %s", kind)
+	}
+
+	return nil, fmt.Errorf("type %v can not be encoded as %s", t, kind)
+}
+
+// isInt returns whether the kind is a signed integer
+func isInt(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+// isUint returns whether the kind is an unsigned integer
+func isUint(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+// encodeStruct encodes a struct with its codec
+func (e *encoder) encodeStruct(c *codec, v reflect.Value) (*pb.Event, error) {
+	res := &pb.Event{
+		Value: make(map[uint32]*pb.Value, len(c.fields)),
+	}
+
+	for _, f := range c.fields {
+		value, err := f.encode(e, v.FieldByIndex(f.index))
+		if err != nil {
+			return nil, fmt.Errorf("talaria: unable to encode field %s, %v", f.name, err)
+		}
+
+		if value != nil {
+			res.Value[e.updateDict(f.name)] = value
+		}
+	}
+	return res, nil
+}
+
+// structsOf returns the elements of a slice of structs, or of pointers to structs, along with their codec
+func structsOf(values interface{}) ([]reflect.Value, *codec, error) {
+	slice := reflect.ValueOf(values)
+	if slice.Kind() != reflect.Slice {
+		return nil, nil, fmt.Errorf("talaria: unable to encode %T, expected a slice of structs", values)
+	}
+
+	elemType := slice.Type().Elem()
+	for elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+
+	c, err := codecOf(elemType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out := make([]reflect.Value, 0, slice.Len())
+	for i := 0; i < slice.Len(); i++ {
+		v := slice.Index(i)
+		for v.Kind() == reflect.Ptr && !v.IsNil() {
+			v = v.Elem()
+		}
+
+		if v.Kind() == reflect.Struct {
+			out = append(out, v)
+		}
+	}
+	return out, c, nil
+}
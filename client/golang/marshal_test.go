@@ -0,0 +1,123 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package client
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	pb "github.com/kelindar/talaria/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+type base struct {
+	Event string `talaria:"event"`
+}
+
+type click struct {
+	base
+	Time    time.Time       `talaria:"time"`
+	Count   int             `talaria:"count,int32"`
+	Score   *float64        `talaria:"score"`
+	Seen    int64           `talaria:"seen,timestamp"`
+	Data    json.RawMessage `talaria:"data"`
+	Tags    []string        `talaria:"tags,json"`
+	Ignored string          `talaria:"-"`
+	hidden  string
+}
+
+func TestEncodeStructs(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	batch, err := newEncoder().EncodeStructs([]*click{{
+		base:  base{Event: "click"},
+		Time:  now,
+		Count: 5,
+		Seen:  1600000001,
+		Data:  json.RawMessage(`{"a":1}`),
+		Tags:  []string{"x"},
+	}})
+	assert.NoError(t, err)
+	assert.Len(t, batch.Events, 1)
+
+	// Resolve the interned strings
+	values := make(map[string]*pb.Value)
+	for k, v := range batch.Events[0].Value {
+		values[string(batch.Strings[k])] = v
+	}
+
+	assert.Len(t, values, 6)
+	assert.Equal(t, "click", string(batch.Strings[values["event"].GetString_()]))
+	assert.Equal(t, now.Unix(), values["time"].GetTime())
+	assert.Equal(t, int32(5), values["count"].GetInt32())
+	assert.Equal(t, int64(1600000001), values["seen"].GetTime())
+	assert.Equal(t, `{"a":1}`, string(batch.Strings[values["data"].GetJson()]))
+	assert.Equal(t, `["x"]`, string(batch.Strings[values["tags"].GetJson()]))
+	assert.Nil(t, values["score"])
+}
+
+func TestTypeOf_Unsigned(t *testing.T) {
+	type counter struct {
+		Hits  uint    `talaria:"hits"`
+		Bytes uint64  `talaria:"bytes"`
+		Addr  uintptr `talaria:"addr"`
+	}
+
+	assert.Equal(t, typeInt64, typeOf(reflect.TypeOf(uint(0))))
+	assert.Equal(t, typeInt64, typeOf(reflect.TypeOf(uint64(0))))
+	assert.Equal(t, typeInt64, typeOf(reflect.TypeOf(uintptr(0))))
+
+	batch, err := newEncoder().EncodeStructs([]counter{{Hits: 1, Bytes: 2, Addr: 3}})
+	assert.NoError(t, err)
+	assert.Len(t, batch.Events, 1)
+
+	values := make(map[string]*pb.Value)
+	for k, v := range batch.Events[0].Value {
+		values[string(batch.Strings[k])] = v
+	}
+
+	assert.Equal(t, int64(1), values["hits"].GetInt64())
+	assert.Equal(t, int64(2), values["bytes"].GetInt64())
+	assert.Equal(t, int64(3), values["addr"].GetInt64())
+}
+
+func TestCodecOf(t *testing.T) {
+	type invalid struct {
+		Name string `talaria:"name,int64"`
+	}
+
+	_, err := newEncoder().EncodeStructs([]invalid{{Name: "x"}})
+	assert.Error(t, err)
+
+	_, err = newEncoder().EncodeStructs(click{})
+	assert.Error(t, err)
+
+	c, err := codecOf(reflect.TypeOf(click{}))
+	assert.NoError(t, err)
+	cached, _ := codecOf(reflect.TypeOf(click{}))
+	assert.True(t, c == cached)
+}
+
+func TestSchema_Validate(t *testing.T) {
+	schema := Schema{
+		"event": typeString,
+		"time":  typeTimestamp,
+		"count": typeInt32,
+		"score": typeFloat64,
+		"seen":  typeTimestamp,
+		"data":  typeJSON,
+		"tags":  typeJSON,
+	}
+
+	assert.NoError(t, schema.Validate(Event{"event": "click", "time": time.Now(), "count": int32(1)}))
+	assert.NoError(t, schema.Validate(Event{"evnt": "click"}))
+	assert.Error(t, schema.Validate(Event{"count": 1}))
+
+	assert.NoError(t, schema.ValidateStruct([]click{}))
+	delete(schema, "tags")
+	assert.NoError(t, schema.ValidateStruct(&click{}))
+	schema["tags"] = typeString
+	assert.Error(t, schema.ValidateStruct(&click{}))
+}
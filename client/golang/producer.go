@@ -0,0 +1,264 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultBatchCount    = 1000
+	defaultBatchBytes    = 1 << 20
+	defaultFlushInterval = time.Second
+	defaultBufferSize    = 10000
+	defaultBackoff       = 100 * time.Millisecond
+	defaultMaxBackoff    = 10 * time.Second
+	defaultMaxRetries    = 5
+)
+
+var (
+	// ErrProducerClosed is error when an event is sent to a producer which was closed
+	ErrProducerClosed = errors.New("producer is closed")
+
+	// ErrBufferFull is error when an event is dropped because the buffer of the producer is full
+	ErrBufferFull = errors.New("producer buffer is full")
+)
+
+// OverflowPolicy represents what a producer does with an event once its buffer is full.
+type OverflowPolicy int
+
+// Various overflow policies
+const (
+	Block OverflowPolicy = iota // Block the sender until there is space in the buffer
+	Drop                        // Drop the event and return ErrBufferFull
+)
+
+// Producer represents an asynchronous producer which buffers the events and sends them to
+// Talaria in batches, once a batch is large enough or periodically.
+type Producer struct {
+	client     *Client
+	lock       sync.RWMutex
+	once       sync.Once
+	closed     bool                 // Whether the producer was closed
+	queue      chan Event           // The buffered events, bounding the memory
+	closing    chan struct{}        // Closed once the producer starts closing
+	done       chan struct{}        // Closed once the remaining events were flushed
+	batch      []Event              // The batch being accumulated
+	size       int                  // The estimated size of the batch, in bytes
+	err        error                // The error of the last flush
+	dropped    int64                // The number of events dropped
+	policy     OverflowPolicy       // What to do when the buffer is full
+	bufferSize int                  // The maximum number of buffered events
+	maxCount   int                  // The maximum number of events in a batch
+	maxBytes   int                  // The maximum estimated size of a batch, in bytes
+	interval   time.Duration        // The maximum time an event is kept before being flushed
+	backoff    time.Duration        // The initial delay between the retries
+	maxBackoff time.Duration        // The maximum delay between the retries
+	maxRetries int                  // The maximum number of retries of a batch
+	onError    func([]Event, error) // The handler of the batches which could not be sent
+}
+
+// NewProducer creates a new asynchronous producer which sends the events through the client. An error
+// is returned if the options are invalid.
+func NewProducer(client *Client, options ...ProducerOption) (*Producer, error) {
+	p := &Producer{
+		client:     client,
+		closing:    make(chan struct{}),
+		done:       make(chan struct{}),
+		policy:     Block,
+		bufferSize: defaultBufferSize,
+		maxCount:   defaultBatchCount,
+		maxBytes:   defaultBatchBytes,
+		interval:   defaultFlushInterval,
+		backoff:    defaultBackoff,
+		maxBackoff: defaultMaxBackoff,
+		maxRetries: defaultMaxRetries,
+	}
+
+	// Apply the options to overwrite the defaults
+	for _, option := range options {
+		option(p)
+	}
+
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+
+	p.queue = make(chan Event, p.bufferSize)
+	go p.run()
+	return p, nil
+}
+
+// validate checks that the options of the producer are valid
+func (p *Producer) validate() error {
+	switch {
+	case p.bufferSize <= 0:
+		return fmt.Errorf("talaria: buffer size of %d events must be positive", p.bufferSize)
+	case p.maxCount <= 0:
+		return fmt.Errorf("talaria: batch size of %d events must be positive", p.maxCount)
+	case p.maxBytes <= 0:
+		return fmt.Errorf("talaria: batch size of %d bytes must be positive", p.maxBytes)
+	case p.interval <= 0:
+		return fmt.Errorf("talaria: flush interval of %v must be positive", p.interval)
+	case p.backoff < 0 || p.maxBackoff < p.backoff:
+		return fmt.Errorf("talaria: backoff of %v up to %v is invalid", p.backoff, p.maxBackoff)
+	case p.maxRetries < 0:
+		return fmt.Errorf("talaria: number of retries %d must not be negative", p.maxRetries)
+	default:
+		return nil
+	}
+}
+
+// Send buffers an event to be sent asynchronously. Once the buffer is full, it either blocks or
+// drops the event depending on the overflow policy.
+func (p *Producer) Send(event Event) error {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if p.closed {
+		return ErrProducerClosed
+	}
+
+	if p.policy == Drop {
+		select {
+		case p.queue <- event:
+			return nil
+		default:
+			atomic.AddInt64(&p.dropped, 1)
+			return ErrBufferFull
+		}
+	}
+
+	select {
+	case p.queue <- event:
+		return nil
+	case <-p.closing:
+		return ErrProducerClosed
+	}
+}
+
+// Dropped returns the number of events which were dropped, either because the buffer was full
+// or because their batch could not be sent.
+func (p *Producer) Dropped() int64 {
+	return atomic.LoadInt64(&p.dropped)
+}
+
+// Close stops accepting events, flushes the buffered events and returns the error of the last
+// flush, if any.
+func (p *Producer) Close() error {
+	p.once.Do(func() {
+		close(p.closing)
+	})
+
+	<-p.done
+	return p.err
+}
+
+// run accumulates the events into batches and flushes them
+func (p *Producer) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event := <-p.queue:
+			p.add(event)
+		case <-ticker.C:
+			p.flush()
+		case <-p.closing:
+			p.drain()
+			return
+		}
+	}
+}
+
+// drain flushes every event which remains in the buffer
+func (p *Producer) drain() {
+
+	// Wait for the senders which are in flight, the blocked ones give up once closing
+	p.lock.Lock()
+	p.closed = true
+	p.lock.Unlock()
+
+	for {
+		select {
+		case event := <-p.queue:
+			p.add(event)
+		default:
+			p.flush()
+			return
+		}
+	}
+}
+
+// add adds an event to the batch and flushes it once it is large enough
+func (p *Producer) add(event Event) {
+	p.batch = append(p.batch, event)
+	p.size += sizeOf(event)
+	if len(p.batch) >= p.maxCount || p.size >= p.maxBytes {
+		p.flush()
+	}
+}
+
+// flush sends the batch, retrying with exponential backoff while the server is unavailable
+func (p *Producer) flush() {
+	if len(p.batch) == 0 {
+		return
+	}
+
+	batch := p.batch
+	p.batch, p.size = nil, 0
+
+	backoff := p.backoff
+	for retry := 0; ; retry++ {
+		p.err = p.client.IngestBatch(context.Background(), batch)
+		if p.err == nil || !isRetryable(p.err) || retry >= p.maxRetries {
+			break
+		}
+
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > p.maxBackoff {
+			backoff = p.maxBackoff
+		}
+	}
+
+	if p.err != nil {
+		atomic.AddInt64(&p.dropped, int64(len(batch)))
+		if p.onError != nil {
+			p.onError(batch, p.err)
+		}
+	}
+}
+
+// isRetryable returns whether a failed batch should be sent again
+func isRetryable(err error) bool {
+	return err == hystrix.ErrCircuitOpen || status.Code(err) == codes.Unavailable
+}
+
+// sizeOf estimates the encoded size of an event, in bytes
+func sizeOf(event Event) (size int) {
+	for k, v := range event {
+		size += len(k)
+		switch v := v.(type) {
+		case string:
+			size += len(v)
+		case json.RawMessage:
+			size += len(v)
+		default:
+			size += 8
+		}
+	}
+	return
+}
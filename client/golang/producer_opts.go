@@ -0,0 +1,52 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package client
+
+import (
+	"time"
+)
+
+// ProducerOption is a functional parameter used to configure the producer.
+type ProducerOption func(producer *Producer)
+
+// WithBatch specifies the maximum number of events and the maximum estimated size, in bytes, of
+// a batch. A batch is flushed as soon as it reaches either of them.
+func WithBatch(count, bytes int) ProducerOption {
+	return func(producer *Producer) {
+		producer.maxCount = count
+		producer.maxBytes = bytes
+	}
+}
+
+// WithFlushInterval specifies how often the batch is flushed, regardless of its size. The interval must be positive.
+func WithFlushInterval(interval time.Duration) ProducerOption {
+	return func(producer *Producer) {
+		producer.interval = interval
+	}
+}
+
+// WithBuffer specifies the maximum number of buffered events and what to do once the buffer is full.
+func WithBuffer(size int, policy OverflowPolicy) ProducerOption {
+	return func(producer *Producer) {
+		producer.bufferSize = size
+		producer.policy = policy
+	}
+}
+
+// WithRetry specifies the exponential backoff of the retries while the server is unavailable and the
+// maximum number of retries, after which the batch is dropped.
+func WithRetry(backoff, maxBackoff time.Duration, maxRetries int) ProducerOption {
+	return func(producer *Producer) {
+		producer.backoff = backoff
+		producer.maxBackoff = maxBackoff
+		producer.maxRetries = maxRetries
+	}
+}
+
+// WithErrorHandler specifies a handler for the batches which could not be sent
+func WithErrorHandler(handler func(batch []Event, err error)) ProducerOption {
+	return func(producer *Producer) {
+		producer.onError = handler
+	}
+}
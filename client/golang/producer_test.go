@@ -0,0 +1,147 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package client
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/kelindar/talaria/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ingressServer records the size of each batch and fails while it is unavailable
+type ingressServer struct {
+	pb.UnimplementedIngressServer
+	lock        sync.Mutex
+	unavailable int
+	batches     []int
+}
+
+func (s *ingressServer) Ingest(_ context.Context, r *pb.IngestRequest) (*pb.IngestResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.unavailable > 0 {
+		s.unavailable--
+		return nil, status.Error(codes.Unavailable, "unavailable")
+	}
+
+	s.batches = append(s.batches, len(r.GetBatch().Events))
+	return &pb.IngestResponse{}, nil
+}
+
+func (s *ingressServer) sizes() []int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]int{}, s.batches...)
+}
+
+// runIngressServer starts an ingress server and connects a client to it
+func runIngressServer(t *testing.T, server *ingressServer, test func(client *Client)) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterIngressServer(grpcServer, server)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	client, err := Dial(lis.Addr().String(), WithCircuit(5*time.Second, 10, 100))
+	assert.NoError(t, err)
+	defer client.Close()
+	test(client)
+}
+
+func TestProducer_Count(t *testing.T) {
+	server := new(ingressServer)
+	runIngressServer(t, server, func(client *Client) {
+		producer, err := NewProducer(client, WithBatch(2, 1<<20), WithFlushInterval(time.Hour))
+		assert.NoError(t, err)
+		for i := 0; i < 5; i++ {
+			assert.NoError(t, producer.Send(Event{"event": "test", "value": i}))
+		}
+
+		// The remaining event is flushed on close
+		assert.NoError(t, producer.Close())
+		assert.Equal(t, []int{2, 2, 1}, server.sizes())
+		assert.Equal(t, ErrProducerClosed, producer.Send(Event{"event": "test"}))
+	})
+}
+
+func TestProducer_Interval(t *testing.T) {
+	server := new(ingressServer)
+	runIngressServer(t, server, func(client *Client) {
+		producer, err := NewProducer(client, WithFlushInterval(10*time.Millisecond))
+		assert.NoError(t, err)
+		defer producer.Close()
+
+		assert.NoError(t, producer.Send(Event{"event": "test"}))
+		assert.Eventually(t, func() bool {
+			return len(server.sizes()) == 1
+		}, time.Second, 5*time.Millisecond)
+	})
+}
+
+func TestProducer_Retry(t *testing.T) {
+	server := &ingressServer{unavailable: 2}
+	runIngressServer(t, server, func(client *Client) {
+		producer, err := NewProducer(client, WithRetry(time.Millisecond, time.Millisecond, 5))
+		assert.NoError(t, err)
+		assert.NoError(t, producer.Send(Event{"event": "test"}))
+		assert.NoError(t, producer.Close())
+		assert.Equal(t, []int{1}, server.sizes())
+		assert.Equal(t, int64(0), producer.Dropped())
+	})
+}
+
+func TestProducer_GiveUp(t *testing.T) {
+	server := &ingressServer{unavailable: 10}
+	runIngressServer(t, server, func(client *Client) {
+		var failed []Event
+		producer, err := NewProducer(client, WithRetry(time.Millisecond, time.Millisecond, 1),
+			WithErrorHandler(func(batch []Event, err error) {
+				failed = append(failed, batch...)
+			}))
+		assert.NoError(t, err)
+
+		assert.NoError(t, producer.Send(Event{"event": "test"}))
+		assert.Error(t, producer.Close())
+		assert.Len(t, failed, 1)
+		assert.Equal(t, int64(1), producer.Dropped())
+	})
+}
+
+func TestProducer_Invalid(t *testing.T) {
+	for _, option := range []ProducerOption{
+		WithBuffer(0, Block),
+		WithBatch(0, 1<<20),
+		WithBatch(1000, 0),
+		WithFlushInterval(0),
+		WithFlushInterval(-time.Second),
+		WithRetry(time.Second, time.Millisecond, 5),
+		WithRetry(time.Millisecond, time.Second, -1),
+	} {
+		producer, err := NewProducer(nil, option)
+		assert.Error(t, err)
+		assert.Nil(t, producer)
+	}
+}
+
+func TestProducer_Drop(t *testing.T) {
+	producer := &Producer{
+		policy:  Drop,
+		queue:   make(chan Event, 1),
+		closing: make(chan struct{}),
+	}
+
+	assert.NoError(t, producer.Send(Event{"event": "test"}))
+	assert.Equal(t, ErrBufferFull, producer.Send(Event{"event": "test"}))
+	assert.Equal(t, int64(1), producer.Dropped())
+}
@@ -0,0 +1,287 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+
+	pb "github.com/kelindar/talaria/proto"
+	"github.com/myteksi/hystrix-go/hystrix"
+	"google.golang.org/grpc"
+)
+
+// Table represents the metadata of a table.
+type Table struct {
+	Schema  string   // The schema of the table
+	Name    string   // The name of the table
+	Columns []Column // The columns of the table
+}
+
+// Column represents the metadata of a column.
+type Column struct {
+	Name    string // The name of the column (eg. tsi)
+	Type    string // The SQL type of the column (eg. varchar)
+	Comment string // The optional comment for the column
+}
+
+// Describe returns the tables of Talaria along with their columns.
+func (c *Client) Describe(ctx context.Context) ([]Table, error) {
+	var response *pb.DescribeResponse
+	if err := hystrix.Do(commandName, func() (err error) {
+		response, err = c.query.Describe(ctx, &pb.DescribeRequest{})
+		return err
+	}, nil); err != nil {
+		return nil, err
+	}
+
+	tables := make([]Table, 0, len(response.Tables))
+	for _, meta := range response.Tables {
+		table := Table{
+			Schema:  meta.Schema,
+			Name:    meta.Table,
+			Columns: make([]Column, 0, len(meta.Columns)),
+		}
+
+		for _, column := range meta.Columns {
+			table.Columns = append(table.Columns, Column{
+				Name:    column.Name,
+				Type:    column.Type,
+				Comment: column.Comment,
+			})
+		}
+
+		sort.Slice(table.Columns, func(i, j int) bool {
+			return table.Columns[i].Name < table.Columns[j].Name
+		})
+		tables = append(tables, table)
+	}
+
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].Name < tables[j].Name
+	})
+	return tables, nil
+}
+
+// Query retrieves the columns of the rows of a table which match every filter, for example
+// "event == 'click'". If no columns are specified, every column of the table is retrieved.
+// The returned iterator retrieves the rows of each split from the hosts which contain it.
+func (c *Client) Query(ctx context.Context, table string, columns []string, filters ...string) (*Rows, error) {
+	if len(columns) == 0 {
+		var err error
+		if columns, err = c.columnsOf(ctx, table); err != nil {
+			return nil, err
+		}
+	}
+
+	splits, err := c.getSplits(ctx, &pb.GetSplitsRequest{
+		Table:   table,
+		Columns: columns,
+		Filters: filters,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Rows{
+		ctx:     ctx,
+		client:  c,
+		columns: columns,
+		splits:  splits,
+	}, nil
+}
+
+// columnsOf returns the names of every column of a table
+func (c *Client) columnsOf(ctx context.Context, name string) ([]string, error) {
+	tables, err := c.Describe(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, table := range tables {
+		if table.Name == name {
+			columns := make([]string, 0, len(table.Columns))
+			for _, column := range table.Columns {
+				columns = append(columns, column.Name)
+			}
+			return columns, nil
+		}
+	}
+	return nil, fmt.Errorf("talaria: table %s not found", name)
+}
+
+// getSplits retrieves every split of a query, following the next token
+func (c *Client) getSplits(ctx context.Context, request *pb.GetSplitsRequest) ([]*pb.Split, error) {
+	var splits []*pb.Split
+	for {
+		var response *pb.GetSplitsResponse
+		if err := hystrix.Do(commandName, func() (err error) {
+			response, err = c.query.GetSplits(ctx, request)
+			return err
+		}, nil); err != nil {
+			return nil, err
+		}
+
+		splits = append(splits, response.Splits...)
+		if len(response.NextToken) == 0 {
+			return splits, nil
+		}
+		request.NextToken = response.NextToken
+	}
+}
+
+// getRows retrieves a page of rows of a split, trying each of its hosts in turn
+func (c *Client) getRows(ctx context.Context, split *pb.Split, request *pb.GetRowsRequest) (*pb.GetRowsResponse, error) {
+	var lastErr error
+	for _, client := range c.queryClientsOf(split) {
+		var response *pb.GetRowsResponse
+		if lastErr = hystrix.Do(commandName, func() (err error) {
+			response, err = client.GetRows(ctx, request)
+			return err
+		}, nil); lastErr == nil {
+			return response, nil
+		}
+	}
+	return nil, lastErr
+}
+
+// queryClientsOf returns the query clients for the hosts of a split, or the client of the
+// configured address if the split does not specify any host.
+func (c *Client) queryClientsOf(split *pb.Split) []pb.QueryClient {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	clients := make([]pb.QueryClient, 0, len(split.Hosts))
+	for _, host := range split.Hosts {
+		if host.Host == "" || host.Port == 0 {
+			continue
+		}
+
+		address := net.JoinHostPort(host.Host, strconv.Itoa(int(host.Port)))
+		conn, ok := c.peers[address]
+		if !ok {
+			var err error
+			if conn, err = grpc.Dial(address, c.transport(), c.callOptions()); err != nil {
+				continue
+			}
+			c.peers[address] = conn
+		}
+		clients = append(clients, pb.NewQueryClient(conn))
+	}
+
+	if len(clients) == 0 {
+		clients = append(clients, c.query)
+	}
+	return clients
+}
+
+// ------------------------------------------------------------------------------------------------------------
+
+// Rows represents an iterator over the result of a query. Use Next to iterate over the rows or NextBatch
+// to iterate over the batches of rows, each batch being a page retrieved from a split.
+type Rows struct {
+	ctx     context.Context
+	client  *Client
+	columns []string    // The columns requested
+	splits  []*pb.Split // The splits which were not retrieved yet
+	split   *pb.Split   // The split being retrieved
+	token   []byte      // The next token of the split being retrieved
+	batch   *Batch      // The current batch
+	row     int         // The index of the current row within the batch
+	err     error       // The error which stopped the iteration
+}
+
+// Next advances the iterator to the next row, returns false once there are no more rows or
+// an error occurred.
+func (r *Rows) Next() bool {
+	if r.batch != nil && r.row+1 < r.batch.Count {
+		r.row++
+		return true
+	}
+
+	if !r.NextBatch() {
+		return false
+	}
+
+	r.row = 0
+	return true
+}
+
+// NextBatch advances the iterator to the next batch of rows, returns false once there are no
+// more rows or an error occurred.
+func (r *Rows) NextBatch() bool {
+	r.batch = nil
+	for r.err == nil {
+		if r.split == nil {
+			if len(r.splits) == 0 {
+				return false
+			}
+
+			r.split, r.splits, r.token = r.splits[0], r.splits[1:], nil
+		}
+
+		page, err := r.client.getRows(r.ctx, r.split, &pb.GetRowsRequest{
+			SplitID:   r.split.SplitID,
+			Columns:   r.columns,
+			MaxBytes:  r.client.pageSize,
+			NextToken: r.token,
+		})
+		if err != nil {
+			r.err = err
+			return false
+		}
+
+		// If a page is empty but has the same token, a single block is larger than the page
+		if page.RowCount == 0 && len(page.NextToken) > 0 && bytes.Equal(page.NextToken, r.token) {
+			r.err = fmt.Errorf("talaria: page size of %d bytes is too small", r.client.pageSize)
+			return false
+		}
+
+		// Move to the next split once this one is exhausted
+		r.token = page.NextToken
+		if len(r.token) == 0 {
+			r.split = nil
+		}
+
+		batch, err := decodeBatch(r.columns, page)
+		if err != nil {
+			r.err = err
+			return false
+		}
+
+		if batch.Count > 0 {
+			r.batch, r.row = batch, 0
+			return true
+		}
+	}
+	return false
+}
+
+// Row returns the current row
+func (r *Rows) Row() Event {
+	if r.batch == nil {
+		return nil
+	}
+	return r.batch.Row(r.row)
+}
+
+// Batch returns the current batch of rows
+func (r *Rows) Batch() *Batch {
+	return r.batch
+}
+
+// Err returns the error, if any, which stopped the iteration
+func (r *Rows) Err() error {
+	return r.err
+}
+
+// Close stops the iteration, the remaining splits are not retrieved
+func (r *Rows) Close() error {
+	r.splits, r.split, r.batch = nil, nil, nil
+	return nil
+}
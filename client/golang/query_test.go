@@ -0,0 +1,253 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/kelindar/talaria/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+// queryServer serves two splits, the second one being retrieved in two pages
+type queryServer struct {
+	pb.UnimplementedQueryServer
+	port int32
+}
+
+func (s *queryServer) Describe(context.Context, *pb.DescribeRequest) (*pb.DescribeResponse, error) {
+	return &pb.DescribeResponse{
+		Tables: []*pb.TableMeta{{
+			Schema: "data",
+			Table:  "events",
+			Columns: []*pb.ColumnMeta{
+				{Name: "name", Type: "varchar"},
+				{Name: "count", Type: "bigint"},
+			},
+		}},
+	}, nil
+}
+
+func (s *queryServer) GetSplits(_ context.Context, r *pb.GetSplitsRequest) (*pb.GetSplitsResponse, error) {
+	host := &pb.Endpoint{Host: "127.0.0.1", Port: s.port}
+	if len(r.NextToken) == 0 {
+		return &pb.GetSplitsResponse{
+			Splits:    []*pb.Split{{SplitID: []byte("a"), Hosts: []*pb.Endpoint{host}}},
+			NextToken: []byte("next"),
+		}, nil
+	}
+
+	return &pb.GetSplitsResponse{
+		Splits: []*pb.Split{{SplitID: []byte("b"), Hosts: []*pb.Endpoint{{Host: "127.0.0.1", Port: 1}, host}}},
+	}, nil
+}
+
+func (s *queryServer) GetRows(_ context.Context, r *pb.GetRowsRequest) (*pb.GetRowsResponse, error) {
+	switch {
+	case string(r.SplitID) == "a":
+		return page([]string{"a", "b"}, []int64{1, 2}, nil), nil
+	case len(r.NextToken) == 0:
+		return page([]string{"c"}, []int64{3}, []byte("more")), nil
+	default:
+		return page([]string{"d"}, []int64{4}, nil), nil
+	}
+}
+
+// page creates a page of rows with a name and a count column
+func page(names []string, counts []int64, token []byte) *pb.GetRowsResponse {
+	name := &pb.ColumnOfString{}
+	count := &pb.ColumnOfInt64{}
+	for i := range names {
+		name.Nulls = append(name.Nulls, false)
+		name.Sizes = append(name.Sizes, int32(len(names[i])))
+		name.Bytes = append(name.Bytes, names[i]...)
+		count.Nulls = append(count.Nulls, false)
+		count.Longs = append(count.Longs, counts[i])
+	}
+
+	return &pb.GetRowsResponse{
+		Columns: []*pb.Column{
+			{Value: &pb.Column_String_{String_: name}},
+			{Value: &pb.Column_Int64{Int64: count}},
+		},
+		RowCount:  int32(len(names)),
+		NextToken: token,
+	}
+}
+
+// largeServer serves a single split, retrieved in a page larger than the default limit of grpc
+type largeServer struct {
+	queryServer
+}
+
+func (s *largeServer) GetSplits(context.Context, *pb.GetSplitsRequest) (*pb.GetSplitsResponse, error) {
+	host := &pb.Endpoint{Host: "127.0.0.1", Port: s.port}
+	return &pb.GetSplitsResponse{
+		Splits: []*pb.Split{{SplitID: []byte("a"), Hosts: []*pb.Endpoint{host}}},
+	}, nil
+}
+
+func (s *largeServer) GetRows(context.Context, *pb.GetRowsRequest) (*pb.GetRowsResponse, error) {
+	return page([]string{strings.Repeat("a", 6<<20)}, []int64{1}, nil), nil
+}
+
+// runQueryServer starts a query server and connects a client to it
+func runQueryServer(t *testing.T, test func(client *Client)) {
+	runServer(t, func(port int32) pb.QueryServer {
+		return &queryServer{port: port}
+	}, test)
+}
+
+// runServer starts a server and connects a client to it
+func runServer(t *testing.T, newServer func(port int32) pb.QueryServer, test func(client *Client)) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	server := grpc.NewServer()
+	pb.RegisterQueryServer(server, newServer(int32(lis.Addr().(*net.TCPAddr).Port)))
+	go server.Serve(lis)
+	defer server.Stop()
+
+	client, err := Dial(lis.Addr().String(), WithCircuit(5*time.Second, 10, 100))
+	assert.NoError(t, err)
+	defer client.Close()
+	test(client)
+}
+
+func TestDescribe(t *testing.T) {
+	runQueryServer(t, func(client *Client) {
+		tables, err := client.Describe(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []Table{{
+			Schema: "data",
+			Name:   "events",
+			Columns: []Column{
+				{Name: "count", Type: "bigint"},
+				{Name: "name", Type: "varchar"},
+			},
+		}}, tables)
+	})
+}
+
+func TestValidation(t *testing.T) {
+	runQueryServer(t, func(client *Client) {
+		client.validate = "events"
+		schema, err := client.validator(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, Schema{"name": typeString, "count": typeInt64}, schema)
+
+		// The invalid events are not sent
+		assert.Error(t, client.IngestBatch(context.Background(), []Event{{"name": 1, "cnt": 1}}))
+		assert.Error(t, client.IngestStructs(context.Background(), []struct {
+			Name  string `talaria:"name"`
+			Count int32  `talaria:"count"`
+		}{}))
+
+		// The schema is retrieved again once expired
+		client.schema = Schema{}
+		schema, err = client.validator(context.Background())
+		assert.NoError(t, err)
+		assert.Empty(t, schema)
+
+		client.schemaTime = time.Now().Add(-schemaTTL)
+		schema, err = client.validator(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, Schema{"name": typeString, "count": typeInt64}, schema)
+	})
+}
+
+func TestQuery(t *testing.T) {
+	runQueryServer(t, func(client *Client) {
+		rows, err := client.Query(context.Background(), "events", []string{"name", "count"}, "count > 0")
+		assert.NoError(t, err)
+		defer rows.Close()
+
+		var events []Event
+		for rows.Next() {
+			events = append(events, rows.Row())
+		}
+
+		assert.NoError(t, rows.Err())
+		assert.Equal(t, []Event{
+			{"name": "a", "count": int64(1)},
+			{"name": "b", "count": int64(2)},
+			{"name": "c", "count": int64(3)},
+			{"name": "d", "count": int64(4)},
+		}, events)
+	})
+}
+
+func TestQuery_Batches(t *testing.T) {
+	runQueryServer(t, func(client *Client) {
+
+		// Every column is retrieved if none is specified
+		rows, err := client.Query(context.Background(), "events", nil)
+		assert.NoError(t, err)
+
+		var counts []int
+		for rows.NextBatch() {
+			assert.Equal(t, []string{"count", "name"}, rows.Batch().Columns)
+			counts = append(counts, rows.Batch().Count)
+		}
+
+		assert.NoError(t, rows.Err())
+		assert.Equal(t, []int{2, 1, 1}, counts)
+	})
+}
+
+func TestQuery_LargePage(t *testing.T) {
+	runServer(t, func(port int32) pb.QueryServer {
+		return &largeServer{queryServer{port: port}}
+	}, func(client *Client) {
+		rows, err := client.Query(context.Background(), "events", []string{"name", "count"})
+		assert.NoError(t, err)
+		defer rows.Close()
+
+		assert.True(t, rows.Next())
+		assert.Len(t, rows.Row()["name"], 6<<20)
+		assert.False(t, rows.Next())
+		assert.NoError(t, rows.Err())
+	})
+}
+
+func TestDecodeColumn(t *testing.T) {
+	now := time.Unix(1600000000, 0).UTC()
+	tests := []struct {
+		column *pb.Column
+		expect []interface{}
+	}{
+		{
+			column: &pb.Column{Value: &pb.Column_Int32{Int32: &pb.ColumnOfInt32{Nulls: []bool{false, true}, Ints: []int32{1, 0}}}},
+			expect: []interface{}{int32(1), nil},
+		},
+		{
+			column: &pb.Column{Value: &pb.Column_Float64{Float64: &pb.ColumnOfFloat64{Nulls: []bool{false}, Doubles: []float64{1.5}}}},
+			expect: []interface{}{1.5},
+		},
+		{
+			column: &pb.Column{Value: &pb.Column_Bool{Bool: &pb.ColumnOfBools{Nulls: []bool{false}, Bools: []bool{true}}}},
+			expect: []interface{}{true},
+		},
+		{
+			column: &pb.Column{Value: &pb.Column_Time{Time: &pb.ColumnOfInt64{Nulls: []bool{false}, Longs: []int64{now.Unix() * 1000}}}},
+			expect: []interface{}{now},
+		},
+		{
+			column: &pb.Column{Value: &pb.Column_Json{Json: &pb.ColumnOfString{Nulls: []bool{true, false}, Sizes: []int32{0, 2}, Bytes: []byte("{}")}}},
+			expect: []interface{}{nil, json.RawMessage("{}")},
+		},
+	}
+
+	for _, tc := range tests {
+		values, err := decodeColumn(tc.column)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expect, values)
+	}
+}
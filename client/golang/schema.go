@@ -0,0 +1,149 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// The duration for which the schema of the table to validate against is cached
+const schemaTTL = time.Minute
+
+// The types of the columns for each SQL type returned by Describe
+var sqlTypes = map[string]string{
+	"INTEGER":   typeInt32,
+	"BIGINT":    typeInt64,
+	"DOUBLE":    typeFloat64,
+	"VARCHAR":   typeString,
+	"BOOLEAN":   typeBool,
+	"TIMESTAMP": typeTimestamp,
+	"JSON":      typeJSON,
+}
+
+// Schema represents the columns of a table along with their types (e.g. int64 or string).
+type Schema map[string]string
+
+// Schema retrieves the schema of a table from the server.
+func (c *Client) Schema(ctx context.Context, name string) (Schema, error) {
+	tables, err := c.Describe(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, table := range tables {
+		if table.Name != name {
+			continue
+		}
+
+		schema := make(Schema, len(table.Columns))
+		for _, column := range table.Columns {
+			if kind, ok := sqlTypes[strings.ToUpper(column.Type)]; ok {
+				schema[column.Name] = kind
+			}
+		}
+		return schema, nil
+	}
+	return nil, fmt.Errorf("talaria: table %s not found", name)
+}
+
+// Validate checks that every value of an event matches the type of its column in the schema. The
+// columns which are not in the schema are not checked, since new columns are added on ingestion.
+func (s Schema) Validate(event Event) error {
+	for name, value := range event {
+		if value == nil {
+			continue
+		}
+
+		if err := s.validate(name, kindOfValue(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateStruct checks that every field of a struct, or of a slice of structs, matches the type
+// of its column in the schema. The columns which are not in the schema are not checked.
+func (s Schema) ValidateStruct(value interface{}) error {
+	t := reflect.TypeOf(value)
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+
+	if t == nil {
+		return fmt.Errorf("talaria: unable to validate %T, expected a struct", value)
+	}
+
+	c, err := codecOf(t)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range c.fields {
+		if err := s.validate(f.name, f.kind); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks that a column has the type, if it exists in the schema
+func (s Schema) validate(name, kind string) error {
+	expect, ok := s[name]
+	switch {
+	case !ok:
+		return nil
+	case expect != kind:
+		return fmt.Errorf("talaria: column %s is of type %s but the value is of type %s", name, expect, kind)
+	default:
+		return nil
+	}
+}
+
+// kindOfValue returns the type of the column a value is encoded as
+func kindOfValue(v interface{}) string {
+	switch v.(type) {
+	case int8, int16, int32:
+		return typeInt32
+	case uint8, uint16, uint32, int, int64:
+		return typeInt64
+	case float32, float64:
+		return typeFloat64
+	case bool:
+		return typeBool
+	case time.Time:
+		return typeTimestamp
+	case json.RawMessage, map[string]interface{}:
+		return typeJSON
+	default:
+		return typeString
+	}
+}
+
+// validator returns the schema to validate the events against, if the validation is enabled. The
+// schema is retrieved again once it is older than its TTL, so that new columns are also checked.
+func (c *Client) validator(ctx context.Context) (Schema, error) {
+	if c.validate == "" {
+		return nil, nil
+	}
+
+	c.schemaLock.Lock()
+	defer c.schemaLock.Unlock()
+	if c.schema != nil && time.Since(c.schemaTime) < schemaTTL {
+		return c.schema, nil
+	}
+
+	schema, err := c.Schema(ctx, c.validate)
+	if err != nil {
+		return nil, err
+	}
+
+	c.schema = schema
+	c.schemaTime = time.Now()
+	return schema, nil
+}
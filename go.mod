@@ -14,6 +14,8 @@ require (
 	github.com/DataDog/datadog-go v3.7.1+incompatible
 	github.com/DataDog/zstd v1.4.5 // indirect
 	github.com/Knetic/govaluate v3.0.0+incompatible
+	github.com/apache/arrow/go/arrow v0.0.0-20201229220542-30ce2eb5d4dc
+	github.com/apache/thrift v0.13.0
 	github.com/armon/go-metrics v0.3.3 // indirect
 	github.com/aws/aws-sdk-go v1.30.25
 	github.com/crphang/orc v0.0.6
@@ -23,7 +25,10 @@ require (
 	github.com/emitter-io/address v1.0.0
 	github.com/fraugster/parquet-go v0.3.0
 	github.com/gogo/protobuf v1.3.2
+	github.com/golang/protobuf v1.5.1
 	github.com/golang/snappy v0.0.1
+	github.com/google/btree v1.0.0
+	github.com/google/go-cmp v0.5.9 // indirect
 	github.com/gopherjs/gopherjs v0.0.0-20200209183636-89e6cbcd0b6d // indirect
 	github.com/gorilla/mux v1.7.4
 	github.com/grab/async v0.0.5
@@ -32,26 +37,33 @@ require (
 	github.com/hashicorp/go-msgpack v1.1.5 // indirect
 	github.com/hashicorp/go-multierror v1.1.0
 	github.com/hashicorp/go-sockaddr v1.0.2 // indirect
-	github.com/hashicorp/golang-lru v0.5.4 // indirect
+	github.com/hashicorp/golang-lru v0.5.4
 	github.com/hashicorp/memberlist v0.2.2
 	github.com/imroc/req v0.3.0 // indirect
 	github.com/kelindar/binary v1.0.9
 	github.com/kelindar/loader v0.0.11
 	github.com/kelindar/lua v0.0.7
+	github.com/linkedin/goavro/v2 v2.11.1
 	github.com/miekg/dns v1.1.29 // indirect
 	github.com/mroth/weightedrand v0.4.1
 	github.com/myteksi/hystrix-go v1.1.3
+	github.com/prometheus/client_golang v1.7.1
 	github.com/samuel/go-thrift v0.0.0-20191111193933-5165175b40af
 	github.com/satori/go.uuid v1.2.0 // indirect
 	github.com/sercand/kuberesolver/v3 v3.0.0
 	github.com/smartystreets/goconvey v1.6.4 // indirect
-	github.com/stretchr/objx v0.2.0 // indirect
-	github.com/stretchr/testify v1.5.1
+	github.com/stretchr/objx v0.5.1 // indirect
+	github.com/stretchr/testify v1.8.2
 	github.com/twmb/murmur3 v1.1.3
 	github.com/yuin/gopher-lua v0.0.0-20191220021717-ab39c6098bdb // indirect
+	go.etcd.io/bbolt v1.3.6
+	go.opentelemetry.io/otel v1.14.0
+	go.opentelemetry.io/otel/sdk v1.14.0
+	go.opentelemetry.io/otel/trace v1.14.0
 	golang.org/x/net v0.0.0-20210326060303-6b1517762897 // indirect
 	golang.org/x/sync v0.0.0-20201020160332-67f06af15bc9
-	golang.org/x/time v0.0.0-20200416051211-89c76fbcd5d1 // indirect
+	golang.org/x/sys v0.10.0 // indirect
+	golang.org/x/time v0.0.0-20200416051211-89c76fbcd5d1
 	google.golang.org/api v0.24.0
 	google.golang.org/genproto v0.0.0-20210325224202-eed09b1b5210 // indirect
 	google.golang.org/grpc v1.36.1
@@ -5,6 +5,7 @@ package config
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/kelindar/talaria/internal/encoding/typeof"
@@ -25,6 +26,15 @@ const (
 	BadgerDefaultOption BadgerDefault = "default"
 )
 
+// MetricsExporter represents the system which the metrics are exported to
+type MetricsExporter string
+
+// Various metrics exporters
+const (
+	MetricsStatsD     MetricsExporter = "statsd"
+	MetricsPrometheus MetricsExporter = "prometheus"
+)
+
 // Config global
 type Config struct {
 	URI      string     `json:"uri" yaml:"uri" env:"URI"`
@@ -36,14 +46,54 @@ type Config struct {
 	Storage  Storage    `json:"storage" yaml:"storage" env:"STORAGE"`
 	Tables   Tables     `json:"tables" yaml:"tables"`
 	Statsd   *StatsD    `json:"statsd,omitempty" yaml:"statsd" env:"STATSD"`
+	Metrics  *Metrics   `json:"metrics,omitempty" yaml:"metrics" env:"METRICS"` // The exporter of the metrics
 	Computed []Computed `json:"computed" yaml:"computed" env:"COMPUTED"`
 	K8s      *K8s       `json:"k8s,omitempty" yaml:"k8s" env:"K8S"`
+	Auth     *Auth      `json:"auth,omitempty" yaml:"auth" env:"AUTH"`          // The authentication and authorization of the endpoints
+	Gossip   *Gossip    `json:"gossip,omitempty" yaml:"gossip" env:"GOSSIP"`    // The gossip of the cluster membership
+	Tracing  *Tracing   `json:"tracing,omitempty" yaml:"tracing" env:"TRACING"` // The distributed tracing of the requests
 }
 
 type K8s struct {
 	ProbePort int32 `json:"probePort" yaml:"probePort" env:"PROBEPORT"` // The port which is used for liveness and readiness probes (default: 8080)
 }
 
+// Gossip represents the configuration of the gossip between the nodes of the cluster
+type Gossip struct {
+	Keys []string `json:"keys,omitempty" yaml:"keys" env:"KEYS"` // The base64-encoded AES keys which encrypt the gossip, the first one being used for encryption
+}
+
+// Tracing represents the configuration of the distributed tracing. The spans are exported to an
+// OpenTelemetry collector over OTLP/HTTP, and no spans are recorded if no endpoint is configured.
+type Tracing struct {
+	Endpoint string            `json:"endpoint" yaml:"endpoint" env:"ENDPOINT"`  // The URL of the OTLP/HTTP traces endpoint (eg: http://localhost:4318/v1/traces)
+	Headers  map[string]string `json:"headers,omitempty" yaml:"headers"`         // The headers to send along with the spans (eg: for authentication)
+	Ratio    float64           `json:"ratio,omitempty" yaml:"ratio" env:"RATIO"` // The fraction of the traces started by the server to sample (default: 1)
+}
+
+// Auth represents the authentication and authorization of the gRPC, thrift, HTTP and Arrow Flight
+// endpoints. If principals are configured, every request needs to be authenticated as one of them.
+type Auth struct {
+	TLS        *TLS        `json:"tls,omitempty" yaml:"tls" env:"TLS"`     // The TLS of the listeners, mutual if a client CA is configured
+	Principals []Principal `json:"principals,omitempty" yaml:"principals"` // The principals along with their permissions
+}
+
+// TLS represents the TLS configuration of a listener, the files are reloaded when modified
+type TLS struct {
+	Cert     string `json:"cert" yaml:"cert" env:"CERT"`                       // The path of the PEM-encoded certificate
+	Key      string `json:"key" yaml:"key" env:"KEY"`                          // The path of the PEM-encoded private key
+	ClientCA string `json:"clientCA,omitempty" yaml:"clientCA" env:"CLIENTCA"` // The path of the PEM-encoded CA which verifies the client certificates, required if set
+}
+
+// Principal represents a client which is authenticated by a bearer token, an API key or the common
+// name of its client certificate, along with the tables it can read and write ("*" for every table).
+type Principal struct {
+	Name  string   `json:"name" yaml:"name"`             // The name of the principal, matched against the common name of a client certificate
+	Token string   `json:"token,omitempty" yaml:"token"` // The bearer token or API key of the principal
+	Read  []string `json:"read,omitempty" yaml:"read"`   // The tables the principal can query
+	Write []string `json:"write,omitempty" yaml:"write"` // The tables the principal can ingest into
+}
+
 // Tables is a list of table configs
 type Tables map[string]Table
 
@@ -55,12 +105,50 @@ type Table struct {
 	Schema  string      `json:"schema" yaml:"schema" env:"SCHEMA"`           // The schema of the table
 	Compact *Compaction `json:"compact" yaml:"compact" env:"COMPACT"`        // The compaction configuration for the table
 	Streams Streams     `json:"streams" yaml:"streams" env:"STREAMS"`        // The streams to stream data to for data in this table
+	Tiers   *Tiers      `json:"tiers,omitempty" yaml:"tiers" env:"TIERS"`    // The tiered storage configuration for the table, can not be used along with compaction
+	Memory  *Memory     `json:"memory,omitempty" yaml:"memory" env:"MEMORY"` // The in-memory storage configuration, if set the table is not persisted on disk
+	Engine  string      `json:"engine,omitempty" yaml:"engine" env:"ENGINE"` // The storage engine of the table, either "badger" or "bolt", defaults to "badger"
+	Limit   *Limit      `json:"limit,omitempty" yaml:"limit" env:"LIMIT"`    // The ingestion rate limit of the table
+}
+
+// Limit represents an ingestion rate limit, enforced with a token bucket which can hold up to one
+// second worth of rows and bytes. A zero value means unlimited.
+type Limit struct {
+	Rows  float64 `json:"rows,omitempty" yaml:"rows" env:"ROWS"`    // The maximum number of rows ingested per second
+	Bytes float64 `json:"bytes,omitempty" yaml:"bytes" env:"BYTES"` // The maximum number of bytes ingested per second
+}
+
+// Memory represents the in-memory storage of an ephemeral table
+type Memory struct {
+	MaxSize int64 `json:"maxSize" yaml:"maxSize" env:"MAXSIZE"` // The maximum size (in bytes) of the data kept in memory, the oldest blocks are evicted beyond it. 0 means unlimited
+}
+
+// Tiers represents the tiered storage of a table, where blocks are aged from the buffer into
+// parquet files of a warm tier (e.g. a local directory) and then of a cold tier (e.g. S3).
+type Tiers struct {
+	Interval int   `json:"interval" yaml:"interval" env:"INTERVAL"` // How often the blocks and files are aged into the next tier, in seconds. defaults to 60
+	Hot      int64 `json:"hot" yaml:"hot" env:"HOT"`                // The duration (in seconds) for which blocks are kept in the buffer, based on the time they were inserted
+	Warm     *Tier `json:"warm" yaml:"warm" env:"WARM"`             // The warm tier, usually a local directory
+	Cold     *Tier `json:"cold" yaml:"cold" env:"COLD"`             // The cold tier, usually an object store
+}
+
+// Tier represents a tier of parquet files
+type Tier struct {
+	TTL  int64     `json:"ttl" yaml:"ttl" env:"TTL"`              // The duration (in seconds) for which files are kept in the tier, based on the time they were written. 0 keeps them forever
+	File *FileSink `json:"file,omitempty" yaml:"file" env:"FILE"` // The local directory of the tier
+	S3   *S3Sink   `json:"s3,omitempty" yaml:"s3" env:"S3"`       // The S3 bucket of the tier
 }
 
 // Storage is the location to write the data
 type Storage struct {
-	Badger
 	Directory string `json:"dir" yaml:"dir" env:"DIR"`
+	Badger    Badger `json:"badger,omitempty" yaml:"badger" env:"BADGER"` // The options of the badger engine
+	Bolt      Bolt   `json:"bolt,omitempty" yaml:"bolt" env:"BOLT"`       // The options of the bolt engine
+}
+
+// Bolt configures the bbolt B+tree store of the tables using the bolt engine
+type Bolt struct {
+	NoSync bool `json:"noSync,omitempty" yaml:"noSync" env:"NOSYNC"` // Whether to skip the fsync after each commit, faster but the last writes may be lost on a crash. defaults to false
 }
 
 // Badger configures badger K-V store that we use underlying.
@@ -77,7 +165,16 @@ type Badger struct {
 
 // Readers are ways to read the data
 type Readers struct {
-	Presto *Presto `json:"presto" yaml:"presto" env:"PRESTO"`
+	Presto    *Presto    `json:"presto" yaml:"presto" env:"PRESTO"`
+	HTTP      *HTTP      `json:"http,omitempty" yaml:"http" env:"HTTP"`                // The HTTP/JSON query API
+	Flight    *Flight    `json:"flight,omitempty" yaml:"flight" env:"FLIGHT"`          // The Arrow Flight query API
+	Scheduler *Scheduler `json:"scheduler,omitempty" yaml:"scheduler" env:"SCHEDULER"` // The scheduler of the scans, shared by every reader
+}
+
+// Scheduler represents the configuration of the query scheduler
+type Scheduler struct {
+	Concurrency int   `json:"concurrency,omitempty" yaml:"concurrency" env:"CONCURRENCY"` // The maximum number of concurrent scans (default: number of CPUs)
+	Timeout     int64 `json:"timeout,omitempty" yaml:"timeout" env:"TIMEOUT"`             // The maximum duration of a request in seconds, including the queue wait (default: 30)
 }
 
 // Writers are sources to write data
@@ -88,7 +185,9 @@ type Writers struct {
 
 // GRPC represents the configuration for gRPC ingress
 type GRPC struct {
-	Port int32 `json:"port" yaml:"port" env:"PORT"` // The port for the gRPC listener (default: 8080)
+	Port  int32  `json:"port" yaml:"port" env:"PORT"`              // The port for the gRPC listener (default: 8080)
+	TLS   *TLS   `json:"tls,omitempty" yaml:"tls" env:"TLS"`       // The TLS of the gRPC listener (default: the TLS of the auth section)
+	Limit *Limit `json:"limit,omitempty" yaml:"limit" env:"LIMIT"` // The ingestion rate limit of each client
 }
 
 // S3SQS represents the aws S3 SQS configuration
@@ -104,6 +203,17 @@ type S3SQS struct {
 type Presto struct {
 	Port   int32  `json:"port" yaml:"port" env:"PORT"`
 	Schema string `json:"schema" yaml:"schema" env:"SCHEMA"`
+	TLS    *TLS   `json:"tls,omitempty" yaml:"tls" env:"TLS"` // The TLS of the thrift listener (default: the TLS of the auth section)
+}
+
+// HTTP represents the HTTP/JSON query API configuration
+type HTTP struct {
+	Port int32 `json:"port" yaml:"port" env:"PORT"` // The port for the HTTP listener
+}
+
+// Flight represents the Arrow Flight query API configuration
+type Flight struct {
+	Port int32 `json:"port" yaml:"port" env:"PORT"` // The port for the Arrow Flight listener
 }
 
 // StatsD represents the configuration for statsD client
@@ -112,6 +222,11 @@ type StatsD struct {
 	Port int64  `json:"port" port:"port" env:"PORT"`
 }
 
+// Metrics represents the configuration of the metrics
+type Metrics struct {
+	Exporter MetricsExporter `json:"exporter" yaml:"exporter" env:"EXPORTER"` // The exporter of the metrics, either "statsd" (default) or "prometheus"
+}
+
 // Computed represents a computed column
 type Computed struct {
 	Name string      `json:"name"`
@@ -121,10 +236,83 @@ type Computed struct {
 
 // Compaction represents a configuration for compaction sinks
 type Compaction struct {
-	Sinks    `yaml:",inline"`
-	Encoder  string `json:"encoder" yaml:"encoder"`                  // The default encoder for the compaction
-	NameFunc string `json:"nameFunc" yaml:"nameFunc" env:"NAMEFUNC"` // The lua script to compute file name given a row
-	Interval int    `json:"interval" yaml:"interval" env:"INTERVAL"` // The compaction interval, in seconds
+	Sinks       `yaml:",inline"`
+	Encoder     string       `json:"encoder" yaml:"encoder"`                                     // The default encoder for the compaction (orc, parquet, arrow, avro, ndjson or csv)
+	NameFunc    string       `json:"nameFunc" yaml:"nameFunc" env:"NAMEFUNC"`                    // The lua script to compute file name given a row
+	Interval    int          `json:"interval" yaml:"interval" env:"INTERVAL"`                    // The compaction interval, in seconds (how often the triggers are checked, if specified)
+	TargetSize  int64        `json:"targetSize,omitempty" yaml:"targetSize" env:"TARGETSIZE"`    // The accumulated size of a group of blocks (in bytes) after which it is compacted, files are split at this size
+	MaxAge      int          `json:"maxAge,omitempty" yaml:"maxAge" env:"MAXAGE"`                // The maximum time (in seconds) a group of blocks is buffered before it is compacted
+	Parquet     *Parquet     `json:"parquet,omitempty" yaml:"parquet" env:"PARQUET"`             // The options for the parquet encoder
+	Sort        *Sort        `json:"sort,omitempty" yaml:"sort" env:"SORT"`                      // The sorting of the rows within each compacted file, unsorted if not specified
+	Partition   *Partition   `json:"partition,omitempty" yaml:"partition" env:"PARTITION"`       // The hive-style partitioning of the compacted files, the name function then names the files within a partition
+	CommitLog   string       `json:"commitLog,omitempty" yaml:"commitLog" env:"COMMITLOG"`       // The path of the commit log of the compaction jobs in flight, defaults to the table directory
+	Retry       *Retry       `json:"retry,omitempty" yaml:"retry" env:"RETRY"`                   // The retry policy of the sinks, failed writes are retried on the next interval if not specified
+	ReadThrough bool         `json:"readThrough,omitempty" yaml:"readThrough" env:"READTHROUGH"` // Whether queries also read the compacted files back from the file or S3 sink
+	Name        string       `json:"name,omitempty" yaml:"name" env:"NAME"`                      // The name of the pipeline, defaults to the name of its sink
+	Pipelines   []Compaction `json:"pipelines,omitempty" yaml:"pipelines"`                       // The additional pipelines, each with its own sinks, encoder, interval and retry policy
+}
+
+// Split returns the independent compaction pipelines, one for each configured sink followed by
+// the pipelines which are explicitly configured.
+func (c *Compaction) Split() []Compaction {
+	var out []Compaction
+	for _, sink := range c.Sinks.split() {
+		pipeline := *c
+		pipeline.Sinks = sink.Sinks
+		pipeline.Name = sink.name
+		pipeline.Pipelines = nil
+		out = append(out, pipeline)
+	}
+
+	for _, pipeline := range c.Pipelines {
+		if pipeline.Name == "" {
+			var names []string
+			for _, sink := range pipeline.Sinks.split() {
+				names = append(names, sink.name)
+			}
+			pipeline.Name = strings.Join(names, "-")
+		}
+
+		pipeline.Pipelines = nil
+		out = append(out, pipeline)
+	}
+	return out
+}
+
+// Retry represents the retry policy of the compaction sinks
+type Retry struct {
+	Backoff     int    `json:"backoff" yaml:"backoff" env:"BACKOFF"`                    // The initial backoff (in seconds) after a sink fails, doubled on every consecutive failure
+	MaxBackoff  int    `json:"maxBackoff" yaml:"maxBackoff" env:"MAXBACKOFF"`           // The maximum backoff (in seconds)
+	MaxFailures int    `json:"maxFailures" yaml:"maxFailures" env:"MAXFAILURES"`        // The number of consecutive failures of a sink after which the data is dead-lettered
+	DeadLetter  *Sinks `json:"deadLetter,omitempty" yaml:"deadLetter" env:"DEADLETTER"` // The sinks for the data which could not be written, such as a local file
+}
+
+// Partition represents the hive-style partitioning of the compacted files
+type Partition struct {
+	By []string `json:"by" yaml:"by" env:"BY"` // The columns to partition by, timestamp columns are partitioned by their date
+}
+
+// Sort represents the sorting of the rows within each compacted file
+type Sort struct {
+	By   string   `json:"by,omitempty" yaml:"by" env:"BY"`       // The column to sort by, defaults to the sortBy column of the table
+	Then []string `json:"then,omitempty" yaml:"then" env:"THEN"` // The secondary columns to sort by
+}
+
+// Columns returns the full list of columns to sort by
+func (s *Sort) Columns() []string {
+	if s.By == "" {
+		return s.Then
+	}
+
+	return append([]string{s.By}, s.Then...)
+}
+
+// Parquet represents the options for the parquet encoder
+type Parquet struct {
+	RowGroupSize *int64 `json:"rowGroupSize" yaml:"rowGroupSize" env:"ROWGROUPSIZE"` // The target size of a row group, in bytes. defaults to 128MB
+	PageSize     *int64 `json:"pageSize" yaml:"pageSize" env:"PAGESIZE"`             // The maximum size of a data page, in bytes. defaults to unlimited
+	Dictionary   *bool  `json:"dictionary" yaml:"dictionary" env:"DICTIONARY"`       // Whether to use dictionary encoding when it is smaller. defaults to true
+	Statistics   *bool  `json:"statistics" yaml:"statistics" env:"STATISTICS"`       // Whether to write min/max column statistics. defaults to true
 }
 
 // Streams are lists of sinks to be streamed to
@@ -141,6 +329,38 @@ type Sinks struct {
 	PubSub   *PubSubSink   `json:"pubsub" yaml:"pubsub" `     // The Google Pub/Sub writer configuration
 }
 
+// namedSinks represents a configuration with a single sink
+type namedSinks struct {
+	Sinks
+	name string
+}
+
+// split returns a configuration for each configured sink, on its own
+func (s *Sinks) split() (out []namedSinks) {
+	if s.S3 != nil {
+		out = append(out, namedSinks{Sinks{S3: s.S3}, "s3"})
+	}
+	if s.Azure != nil {
+		out = append(out, namedSinks{Sinks{Azure: s.Azure}, "azure"})
+	}
+	if s.BigQuery != nil {
+		out = append(out, namedSinks{Sinks{BigQuery: s.BigQuery}, "bigquery"})
+	}
+	if s.GCS != nil {
+		out = append(out, namedSinks{Sinks{GCS: s.GCS}, "gcs"})
+	}
+	if s.File != nil {
+		out = append(out, namedSinks{Sinks{File: s.File}, "file"})
+	}
+	if s.Talaria != nil {
+		out = append(out, namedSinks{Sinks{Talaria: s.Talaria}, "talaria"})
+	}
+	if s.PubSub != nil {
+		out = append(out, namedSinks{Sinks{PubSub: s.PubSub}, "pubsub"})
+	}
+	return
+}
+
 // S3Sink represents a sink for AWS S3 and compatible stores.
 type S3Sink struct {
 	Region      string `json:"region" yaml:"region" env:"REGION"`                // The region of AWS bucket
@@ -25,3 +25,30 @@ func TestConfig(t *testing.T) {
 
 	assert.Equal(t, cfg().Storage.Directory, "dir-2")
 }
+
+func TestCompaction_Split(t *testing.T) {
+	cfg := config.Compaction{
+		Encoder: "parquet",
+		Sinks: config.Sinks{
+			S3:   &config.S3Sink{Bucket: "bucket"},
+			File: &config.FileSink{Directory: "dir"},
+		},
+		Pipelines: []config.Compaction{{
+			Encoder: "orc",
+			Sinks: config.Sinks{
+				GCS:  &config.GCSSink{Bucket: "bucket"},
+				File: &config.FileSink{Directory: "dir"},
+			},
+		}},
+	}
+
+	pipelines := cfg.Split()
+	assert.Len(t, pipelines, 3)
+	assert.Equal(t, "s3", pipelines[0].Name)
+	assert.Equal(t, "parquet", pipelines[0].Encoder)
+	assert.Nil(t, pipelines[0].File)
+	assert.Equal(t, "file", pipelines[1].Name)
+	assert.Nil(t, pipelines[1].S3)
+	assert.Equal(t, "gcs-file", pipelines[2].Name)
+	assert.Equal(t, "orc", pipelines[2].Encoder)
+}
@@ -23,7 +23,7 @@ func TestConfigure(t *testing.T) {
 		HashBy: "abc",
 	}
 
-	assert.Nil(t, c.Storage.MaxLevels)
+	assert.Nil(t, c.Storage.Badger.MaxLevels)
 	assert.Nil(t, err)
 }
 
@@ -0,0 +1,201 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package arrow
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/apache/arrow/go/arrow/memory"
+	"github.com/kelindar/talaria/internal/encoding/typeof"
+	"github.com/kelindar/talaria/internal/presto"
+)
+
+// ContentType is the MIME type of an Arrow IPC stream
+const ContentType = "application/vnd.apache.arrow.stream"
+
+// Allocator is the memory allocator used for all of the arrow buffers
+var allocator = memory.NewGoAllocator()
+
+// SchemaFor creates an arrow schema for a set of columns, in the specified order.
+func SchemaFor(columns []string, schema typeof.Schema) (*arrow.Schema, error) {
+	fields := make([]arrow.Field, 0, len(columns))
+	for _, name := range columns {
+		typ, ok := schema[name]
+		if !ok {
+			return nil, fmt.Errorf("arrow: column %s is not part of the schema", name)
+		}
+
+		dataType, err := typeFor(typ)
+		if err != nil {
+			return nil, err
+		}
+
+		fields = append(fields, arrow.Field{
+			Name:     name,
+			Type:     dataType,
+			Nullable: true,
+		})
+	}
+
+	return arrow.NewSchema(fields, nil), nil
+}
+
+// RecordOf converts a set of columns into an arrow record. The columns must be
+// provided in the same order as the fields of the schema.
+func RecordOf(schema *arrow.Schema, columns []presto.Column) (array.Record, error) {
+	if len(columns) != len(schema.Fields()) {
+		return nil, fmt.Errorf("arrow: expected %d columns but got %d", len(schema.Fields()), len(columns))
+	}
+
+	builder := array.NewRecordBuilder(allocator, schema)
+	defer builder.Release()
+
+	for i, column := range columns {
+		if err := appendColumn(builder.Field(i), column); err != nil {
+			return nil, err
+		}
+	}
+
+	return builder.NewRecord(), nil
+}
+
+// WriteStream writes one or multiple records into an Arrow IPC stream.
+func WriteStream(dst io.Writer, schema *arrow.Schema, records ...array.Record) error {
+	writer := ipc.NewWriter(dst, ipc.WithSchema(schema), ipc.WithAllocator(allocator))
+	for _, rec := range records {
+		if err := writer.Write(rec); err != nil {
+			return err
+		}
+	}
+
+	return writer.Close()
+}
+
+// WriteFile writes one or multiple records into an Arrow IPC file (also known as Feather v2).
+func WriteFile(dst io.Writer, schema *arrow.Schema, records ...array.Record) error {
+	writer, err := ipc.NewFileWriter(&seeker{Writer: dst}, ipc.WithSchema(schema), ipc.WithAllocator(allocator))
+	if err != nil {
+		return err
+	}
+
+	for _, rec := range records {
+		if err := writer.Write(rec); err != nil {
+			return err
+		}
+	}
+
+	return writer.Close()
+}
+
+// SerializeSchema encodes the schema as an IPC schema message, as expected by Arrow Flight.
+func SerializeSchema(schema *arrow.Schema) ([]byte, error) {
+	const eos = 8 // The end-of-stream marker length
+	var buffer bytes.Buffer
+	if err := WriteStream(&buffer, schema); err != nil {
+		return nil, err
+	}
+
+	// Drop the end-of-stream marker, we only need the schema message
+	return buffer.Bytes()[:buffer.Len()-eos], nil
+}
+
+// typeFor maps our type to the corresponding arrow data type.
+func typeFor(t typeof.Type) (arrow.DataType, error) {
+	switch t {
+	case typeof.Int32:
+		return arrow.PrimitiveTypes.Int32, nil
+	case typeof.Int64:
+		return arrow.PrimitiveTypes.Int64, nil
+	case typeof.Float64:
+		return arrow.PrimitiveTypes.Float64, nil
+	case typeof.String, typeof.JSON:
+		return arrow.BinaryTypes.String, nil
+	case typeof.Bool:
+		return arrow.FixedWidthTypes.Boolean, nil
+	case typeof.Timestamp:
+		return arrow.FixedWidthTypes.Timestamp_ms, nil
+	}
+
+	return nil, fmt.Errorf("arrow: unsupported type %v", t)
+}
+
+// appendColumn appends the column into a corresponding arrow builder
+func appendColumn(dst array.Builder, column presto.Column) error {
+	switch src := column.(type) {
+	case *presto.PrestoThriftInteger:
+		dst.(*array.Int32Builder).AppendValues(src.Ints, valid(src.Nulls))
+	case *presto.PrestoThriftBigint:
+		dst.(*array.Int64Builder).AppendValues(src.Longs, valid(src.Nulls))
+	case *presto.PrestoThriftDouble:
+		dst.(*array.Float64Builder).AppendValues(src.Doubles, valid(src.Nulls))
+	case *presto.PrestoThriftBoolean:
+		dst.(*array.BooleanBuilder).AppendValues(src.Booleans, valid(src.Nulls))
+	case *presto.PrestoThriftTimestamp:
+		b := dst.(*array.TimestampBuilder)
+		for i, v := range src.Timestamps {
+			if src.Nulls[i] {
+				b.AppendNull()
+				continue
+			}
+			b.Append(arrow.Timestamp(v))
+		}
+	case *presto.PrestoThriftVarchar:
+		appendStrings(dst.(*array.StringBuilder), src.Nulls, src.Sizes, src.Bytes)
+	case *presto.PrestoThriftJson:
+		appendStrings(dst.(*array.StringBuilder), src.Nulls, src.Sizes, src.Bytes)
+	default:
+		return fmt.Errorf("arrow: unsupported column %T", column)
+	}
+	return nil
+}
+
+// appendStrings appends a set of variable-length strings
+func appendStrings(b *array.StringBuilder, nulls []bool, sizes []int32, data []byte) {
+	var offset int32
+	for i, size := range sizes {
+		if nulls[i] {
+			b.AppendNull()
+			continue
+		}
+
+		b.Append(string(data[offset : offset+size]))
+		offset += size
+	}
+}
+
+// valid inverts the null bitmap into a validity bitmap
+func valid(nulls []bool) []bool {
+	out := make([]bool, len(nulls))
+	for i, isNull := range nulls {
+		out[i] = !isNull
+	}
+	return out
+}
+
+// seeker wraps a writer and keeps track of the current position, since the file writer
+// only ever seeks in order to retrieve the current offset.
+type seeker struct {
+	io.Writer
+	offset int64
+}
+
+// Write writes the buffer into the underlying writer
+func (s *seeker) Write(p []byte) (int, error) {
+	n, err := s.Writer.Write(p)
+	s.offset += int64(n)
+	return n, err
+}
+
+// Seek returns the current offset, only io.SeekCurrent with zero offset is supported.
+func (s *seeker) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekCurrent {
+		return 0, fmt.Errorf("arrow: seek is not supported")
+	}
+	return s.offset, nil
+}
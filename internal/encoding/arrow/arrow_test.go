@@ -0,0 +1,67 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package arrow
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/kelindar/talaria/internal/column"
+	"github.com/kelindar/talaria/internal/encoding/typeof"
+	"github.com/kelindar/talaria/internal/presto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteStream(t *testing.T) {
+	schema := typeof.Schema{
+		"a": typeof.Int32,
+		"b": typeof.String,
+		"c": typeof.Timestamp,
+		"d": typeof.Float64,
+	}
+
+	cols := column.MakeColumns(&schema)
+	cols.Append("a", int32(1), typeof.Int32)
+	cols.Append("b", "hello", typeof.String)
+	cols.Append("c", time.Unix(10, 0), typeof.Timestamp)
+	cols.Append("d", 1.5, typeof.Float64)
+	cols.Append("a", nil, typeof.Int32)
+	cols.Append("b", "world", typeof.String)
+	cols.Append("c", nil, typeof.Timestamp)
+	cols.Append("d", 2.5, typeof.Float64)
+
+	names := []string{"a", "b", "c", "d"}
+	arrowSchema, err := SchemaFor(names, schema)
+	assert.NoError(t, err)
+	assert.Len(t, arrowSchema.Fields(), 4)
+
+	rec, err := RecordOf(arrowSchema, []presto.Column{cols["a"], cols["b"], cols["c"], cols["d"]})
+	assert.NoError(t, err)
+	defer rec.Release()
+	assert.Equal(t, int64(2), rec.NumRows())
+
+	// Write and read back the stream
+	var buffer bytes.Buffer
+	assert.NoError(t, WriteStream(&buffer, arrowSchema, rec))
+
+	rdr, err := ipc.NewReader(&buffer)
+	assert.NoError(t, err)
+	defer rdr.Release()
+
+	assert.True(t, rdr.Next())
+	out := rdr.Record()
+	assert.Equal(t, int64(2), out.NumRows())
+	assert.Equal(t, int32(1), out.Column(0).(*array.Int32).Value(0))
+	assert.True(t, out.Column(0).IsNull(1))
+	assert.Equal(t, "world", out.Column(1).(*array.String).Value(1))
+	assert.Equal(t, 2.5, out.Column(3).(*array.Float64).Value(1))
+}
+
+func TestSchemaFor_Missing(t *testing.T) {
+	_, err := SchemaFor([]string{"x"}, typeof.Schema{"a": typeof.Int32})
+	assert.Error(t, err)
+}
@@ -0,0 +1,196 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package arrow
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/kelindar/talaria/internal/encoding/typeof"
+)
+
+// The magic bytes at the beginning of the arrow IPC file format (e.g. feather v2)
+var fileMagic = []byte("ARROW1")
+
+// Iterator represents arrow data frame.
+type Iterator interface {
+	io.Closer
+	Range(f func(int, []interface{}) bool, columns ...string) (int, bool)
+	Schema() typeof.Schema
+}
+
+// FromBuffer creates an iterator from a buffer which contains either an arrow IPC
+// file or an arrow IPC stream.
+func FromBuffer(b []byte) (Iterator, error) {
+	if bytes.HasPrefix(b, fileMagic) {
+		r, err := ipc.NewFileReader(bytes.NewReader(b), ipc.WithAllocator(allocator))
+		if err != nil {
+			return nil, err
+		}
+
+		return &iterator{schema: r.Schema(), count: r.NumRecords(), record: r.Record, closer: r.Close}, nil
+	}
+
+	r, err := ipc.NewReader(bytes.NewReader(b), ipc.WithAllocator(allocator))
+	if err != nil {
+		return nil, err
+	}
+
+	return &iterator{schema: r.Schema(), count: -1, record: func(int) (array.Record, error) {
+		if !r.Next() {
+			return nil, io.EOF
+		}
+		return r.Record(), nil
+	}, closer: func() error {
+		r.Release()
+		return nil
+	}}, nil
+}
+
+// Range is a helper function that ranges over a set of columns in an arrow buffer
+func Range(payload []byte, f func(int, []interface{}) bool, columns ...string) error {
+	i, err := FromBuffer(payload)
+	if err != nil {
+		return err
+	}
+
+	defer i.Close()
+	_, _ = i.Range(f, columns...)
+	return nil
+}
+
+// Iterator represents arrow data frame.
+type iterator struct {
+	schema *arrow.Schema
+	count  int // The number of records, or -1 if unknown (stream)
+	record func(int) (array.Record, error)
+	closer func() error
+}
+
+// Range iterates through the reader.
+func (i *iterator) Range(f func(int, []interface{}) bool, columns ...string) (index int, stop bool) {
+	indices := make([]int, len(columns))
+	for c, name := range columns {
+		indices[c] = -1
+		if found := i.schema.FieldIndices(name); len(found) > 0 {
+			indices[c] = found[0]
+		}
+	}
+
+	// Preallocate the colums slice (row)
+	arr := make([]interface{}, len(columns))
+	for r := 0; i.count < 0 || r < i.count; r++ {
+		rec, err := i.record(r)
+		if err != nil {
+			break
+		}
+
+		for row := 0; row < int(rec.NumRows()); row++ {
+			for c, idx := range indices {
+				arr[c] = nil
+				if idx >= 0 {
+					arr[c] = valueAt(rec.Column(idx), row)
+				}
+			}
+
+			index++
+			if stop = f(index-1, arr); stop {
+				return index, false
+			}
+		}
+	}
+	return index, true
+}
+
+// Schema gets the SQL schema for the iterator.
+func (i *iterator) Schema() typeof.Schema {
+	result := make(typeof.Schema, len(i.schema.Fields()))
+	for _, f := range i.schema.Fields() {
+		if t, supported := typeOf(f.Type); supported {
+			result[f.Name] = t
+		}
+	}
+	return result
+}
+
+// Close closes the iterator.
+func (i *iterator) Close() error {
+	return i.closer()
+}
+
+// typeOf maps the arrow data type to the corresponding type, if supported.
+func typeOf(t arrow.DataType) (typeof.Type, bool) {
+	switch t.ID() {
+	case arrow.INT8, arrow.INT16, arrow.INT32, arrow.UINT8, arrow.UINT16:
+		return typeof.Int32, true
+	case arrow.INT64, arrow.UINT32:
+		return typeof.Int64, true
+	case arrow.FLOAT32, arrow.FLOAT64:
+		return typeof.Float64, true
+	case arrow.STRING, arrow.BINARY:
+		return typeof.String, true
+	case arrow.BOOL:
+		return typeof.Bool, true
+	case arrow.TIMESTAMP:
+		return typeof.Timestamp, true
+	}
+
+	return typeof.Unsupported, false
+}
+
+// valueAt returns the value of the array at a specific index, converted to the
+// corresponding go type.
+func valueAt(arr array.Interface, i int) interface{} {
+	if arr.IsNull(i) {
+		return nil
+	}
+
+	switch a := arr.(type) {
+	case *array.Int8:
+		return int32(a.Value(i))
+	case *array.Int16:
+		return int32(a.Value(i))
+	case *array.Int32:
+		return a.Value(i)
+	case *array.Uint8:
+		return int32(a.Value(i))
+	case *array.Uint16:
+		return int32(a.Value(i))
+	case *array.Int64:
+		return a.Value(i)
+	case *array.Uint32:
+		return int64(a.Value(i))
+	case *array.Float32:
+		return float64(a.Value(i))
+	case *array.Float64:
+		return a.Value(i)
+	case *array.String:
+		return a.Value(i)
+	case *array.Binary:
+		return string(a.Value(i))
+	case *array.Boolean:
+		return a.Value(i)
+	case *array.Timestamp:
+		return timeOf(a.Value(i), a.DataType().(*arrow.TimestampType).Unit)
+	}
+	return nil
+}
+
+// timeOf converts the arrow timestamp to time
+func timeOf(v arrow.Timestamp, unit arrow.TimeUnit) time.Time {
+	switch unit {
+	case arrow.Second:
+		return time.Unix(int64(v), 0).UTC()
+	case arrow.Millisecond:
+		return time.Unix(0, int64(v)*int64(time.Millisecond)).UTC()
+	case arrow.Microsecond:
+		return time.Unix(0, int64(v)*int64(time.Microsecond)).UTC()
+	default:
+		return time.Unix(0, int64(v)).UTC()
+	}
+}
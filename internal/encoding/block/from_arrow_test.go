@@ -0,0 +1,53 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package block
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kelindar/talaria/internal/column"
+	"github.com/kelindar/talaria/internal/encoding/arrow"
+	"github.com/kelindar/talaria/internal/encoding/typeof"
+	"github.com/kelindar/talaria/internal/presto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromArrow(t *testing.T) {
+	schema := typeof.Schema{
+		"event": typeof.String,
+		"value": typeof.Int64,
+	}
+
+	cols := column.MakeColumns(&schema)
+	for i, event := range []string{"a", "b", "a"} {
+		cols.Append("event", event, typeof.String)
+		cols.Append("value", int64(i), typeof.Int64)
+	}
+
+	arrowSchema, err := arrow.SchemaFor([]string{"event", "value"}, schema)
+	assert.NoError(t, err)
+	rec, err := arrow.RecordOf(arrowSchema, []presto.Column{cols["event"], cols["value"]})
+	assert.NoError(t, err)
+	defer rec.Release()
+
+	// Both the stream and the file formats should be supported
+	stream, file := new(bytes.Buffer), new(bytes.Buffer)
+	assert.NoError(t, arrow.WriteStream(stream, arrowSchema, rec))
+	assert.NoError(t, arrow.WriteFile(file, arrowSchema, rec))
+
+	for _, payload := range [][]byte{stream.Bytes(), file.Bytes()} {
+		blocks, err := FromArrowBy(payload, "event", nil, Transform(nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(blocks))
+
+		rows := 0
+		for _, b := range blocks {
+			out, err := b.Select(typeof.Schema{"value": typeof.Int64})
+			assert.NoError(t, err)
+			rows += out["value"].Count()
+		}
+		assert.Equal(t, 3, rows)
+	}
+}
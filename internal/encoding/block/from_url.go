@@ -15,16 +15,8 @@ import (
 
 // FromURLBy creates a block from a remote url which should be loaded. It repartitions the batch by a given partition key at the same time.
 func FromURLBy(uri string, partitionBy string, filter *typeof.Schema, apply applyFunc) ([]Block, error) {
-	var handler func([]byte, string, *typeof.Schema, applyFunc) ([]Block, error)
-	switch strings.ToLower(filepath.Ext(uri)) {
-	case ".orc":
-		handler = FromOrcBy
-	case ".csv":
-		handler = FromCSVBy
-	case ".parquet":
-		handler = FromParquetBy
-	default:
-		return nil, errors.Newf("block: unsupported file extension %s", filepath.Ext(uri))
+	if _, err := handlerFor(uri); err != nil {
+		return nil, err
 	}
 
 	l := loader.New()
@@ -33,5 +25,48 @@ func FromURLBy(uri string, partitionBy string, filter *typeof.Schema, apply appl
 		return nil, err
 	}
 
-	return handler(b, partitionBy, filter, apply)
+	return FromFileBy(uri, b, partitionBy, filter, apply)
+}
+
+// FromFileBy decodes a set of blocks from the contents of a file, depending on the extension of its name. It
+// repartitions the rows by a given partition key at the same time.
+func FromFileBy(name string, payload []byte, partitionBy string, filter *typeof.Schema, apply applyFunc) ([]Block, error) {
+	handler, err := handlerFor(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(payload, partitionBy, filter, apply)
+}
+
+// FromFormatBy decodes a set of blocks from the contents of a file of a specific format (e.g. "orc" or "parquet"),
+// whatever the extension of its name. It repartitions the rows by a given partition key at the same time.
+func FromFormatBy(format string, payload []byte, partitionBy string, filter *typeof.Schema, apply applyFunc) ([]Block, error) {
+	handler, err := handlerOf(format)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(payload, partitionBy, filter, apply)
+}
+
+// handlerFor returns the decoder for a file, depending on its extension
+func handlerFor(name string) (func([]byte, string, *typeof.Schema, applyFunc) ([]Block, error), error) {
+	return handlerOf(strings.TrimPrefix(filepath.Ext(name), "."))
+}
+
+// handlerOf returns the decoder of a format
+func handlerOf(format string) (func([]byte, string, *typeof.Schema, applyFunc) ([]Block, error), error) {
+	switch strings.ToLower(format) {
+	case "orc":
+		return FromOrcBy, nil
+	case "csv":
+		return FromCSVBy, nil
+	case "parquet":
+		return FromParquetBy, nil
+	case "arrow", "arrows", "feather":
+		return FromArrowBy, nil
+	default:
+		return nil, errors.Newf("block: unsupported file format %s", format)
+	}
 }
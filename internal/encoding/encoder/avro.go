@@ -0,0 +1,241 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package encoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/kelindar/talaria/internal/encoding/block"
+	"github.com/kelindar/talaria/internal/encoding/typeof"
+	"github.com/kelindar/talaria/internal/monitor/errors"
+	"github.com/kelindar/talaria/internal/presto"
+	"github.com/linkedin/goavro/v2"
+)
+
+// The maximum number of avro codecs which are cached
+const maxCodecs = 1024
+
+// The cache of avro codecs, keyed by the avro schema. The schemas of the rows of a table can vary,
+// so only the most recently used codecs are kept.
+var codecs, _ = lru.New(maxCodecs)
+
+// invalidName matches characters which are not allowed in avro names
+var invalidName = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// AvroRow encodes a single row using avro single-object encoding, which embeds the
+// fingerprint of the schema so that consumers can resolve it.
+func AvroRow(row block.Row) ([]byte, error) {
+	schema := make(typeof.Schema, len(row.Values))
+	for name, v := range row.Values {
+		if typ, ok := row.Schema[name]; ok {
+			schema[name] = typ
+			continue
+		}
+
+		if typ, ok := typeof.FromType(reflect.TypeOf(v)); ok {
+			schema[name] = typ
+		}
+	}
+
+	codec, err := codecFor(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	native := make(map[string]interface{}, len(schema))
+	for name, typ := range schema {
+		native[avroName(name)] = avroValue(row.Values[name], typ)
+	}
+
+	return codec.SingleFromNative(nil, native)
+}
+
+// ToAvro encodes multiple blocks as an avro object container file
+func ToAvro(blocks []block.Block, schema typeof.Schema) ([]byte, error) {
+	codec, err := codecFor(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	writer, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:               &buffer,
+		Codec:           codec,
+		CompressionName: goavro.CompressionSnappyLabel,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect the rows so that they are written as a single block of the container
+	var rows []interface{}
+	names := schema.Columns()
+	if err := RangeRows(blocks, schema, func(row []interface{}) error {
+		native := make(map[string]interface{}, len(names))
+		for i, name := range names {
+			native[avroName(name)] = avroValue(row[i], schema[name])
+		}
+		rows = append(rows, native)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if len(rows) > 0 {
+		if err := writer.Append(rows); err != nil {
+			return nil, err
+		}
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// codecFor returns an avro codec for the schema
+func codecFor(schema typeof.Schema) (*goavro.Codec, error) {
+	spec, err := avroSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	if codec, ok := codecs.Get(spec); ok {
+		return codec.(*goavro.Codec), nil
+	}
+
+	codec, err := goavro.NewCodec(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	codecs.Add(spec, codec)
+	return codec, nil
+}
+
+// avroSchema generates the avro record schema, all of the fields are nullable
+func avroSchema(schema typeof.Schema) (string, error) {
+	fields := make([]map[string]interface{}, 0, len(schema))
+	names := make(map[string]string, len(schema))
+	for _, name := range schema.Columns() {
+		typ, err := avroType(schema[name])
+		if err != nil {
+			return "", err
+		}
+
+		// Columns which only differ by their invalid characters would overwrite each other
+		field := avroName(name)
+		if other, ok := names[field]; ok {
+			return "", errors.Newf("avro: columns '%s' and '%s' have the same field name '%s'", other, name, field)
+		}
+		names[field] = name
+
+		fields = append(fields, map[string]interface{}{
+			"name":    field,
+			"type":    []interface{}{"null", typ},
+			"default": nil,
+		})
+	}
+
+	spec, err := json.Marshal(map[string]interface{}{
+		"type":   "record",
+		"name":   "row",
+		"fields": fields,
+	})
+	return string(spec), err
+}
+
+// avroType maps our type to the avro type
+func avroType(t typeof.Type) (interface{}, error) {
+	switch t {
+	case typeof.Int32:
+		return "int", nil
+	case typeof.Int64:
+		return "long", nil
+	case typeof.Float64:
+		return "double", nil
+	case typeof.Bool:
+		return "boolean", nil
+	case typeof.String, typeof.JSON:
+		return "string", nil
+	case typeof.Timestamp:
+		return map[string]string{"type": "long", "logicalType": "timestamp-millis"}, nil
+	}
+
+	return nil, fmt.Errorf("encoder: unsupported avro type %v", t)
+}
+
+// avroValue converts the value into a native avro union value
+func avroValue(v interface{}, t typeof.Type) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	switch t {
+	case typeof.Int32:
+		if i, ok := toInt64(v); ok {
+			return goavro.Union("int", int32(i))
+		}
+	case typeof.Int64:
+		if i, ok := toInt64(v); ok {
+			return goavro.Union("long", i)
+		}
+	case typeof.Float64:
+		if f, ok := toFloat64(v); ok {
+			return goavro.Union("double", f)
+		}
+	case typeof.Bool:
+		if b, ok := v.(bool); ok {
+			return goavro.Union("boolean", b)
+		}
+	case typeof.String, typeof.JSON:
+		return goavro.Union("string", formatCSV(v))
+	case typeof.Timestamp:
+		switch ts := v.(type) {
+		case time.Time:
+			return goavro.Union("long.timestamp-millis", ts)
+		case int64:
+			return goavro.Union("long.timestamp-millis", presto.TimeOf(ts))
+		}
+	}
+	return nil
+}
+
+// toInt64 converts an integer value to int64
+func toInt64(v interface{}) (int64, bool) {
+	switch i := v.(type) {
+	case int:
+		return int64(i), true
+	case int32:
+		return int64(i), true
+	case int64:
+		return i, true
+	}
+	return 0, false
+}
+
+// toFloat64 converts a floating-point or an integer value to float64
+func toFloat64(v interface{}) (float64, bool) {
+	switch f := v.(type) {
+	case float64:
+		return f, true
+	case float32:
+		return float64(f), true
+	}
+
+	i, ok := toInt64(v)
+	return float64(i), ok
+}
+
+// avroName sanitizes the column name so it can be used as an avro field name
+func avroName(name string) string {
+	name = invalidName.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
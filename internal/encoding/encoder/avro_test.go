@@ -0,0 +1,89 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package encoder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/kelindar/talaria/internal/encoding/block"
+	"github.com/kelindar/talaria/internal/encoding/typeof"
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToAvro(t *testing.T) {
+	out, err := ToAvro(testBlocks(t), testSchema)
+	assert.NoError(t, err)
+
+	reader, err := goavro.NewOCFReader(bytes.NewReader(out))
+	assert.NoError(t, err)
+
+	var events []interface{}
+	for reader.Scan() {
+		record, err := reader.Read()
+		assert.NoError(t, err)
+		events = append(events, record.(map[string]interface{})["event"])
+	}
+
+	assert.ElementsMatch(t, []interface{}{
+		map[string]interface{}{"string": "a"},
+		map[string]interface{}{"string": "a"},
+		map[string]interface{}{"string": "b"},
+	}, events)
+}
+
+func TestAvroRow(t *testing.T) {
+	row := block.Row{
+		Values: map[string]interface{}{
+			"event":      "a",
+			"value":      int64(10),
+			"time":       time.Unix(1600000000, 0),
+			"event.name": "b",
+		},
+		Schema: typeof.Schema{
+			"event":      typeof.String,
+			"value":      typeof.Int64,
+			"time":       typeof.Timestamp,
+			"event.name": typeof.String,
+		},
+	}
+
+	out, err := AvroRow(row)
+	assert.NoError(t, err)
+
+	// Single-object encoding must start with the marker and the schema fingerprint
+	codec, err := codecFor(row.Schema)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xC3, 0x01}, out[:2])
+	assert.Equal(t, codec.Rabin, binary.LittleEndian.Uint64(out[2:10]))
+
+	native, _, err := codec.NativeFromSingle(out)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"long": int64(10)}, native.(map[string]interface{})["value"])
+	assert.Equal(t, map[string]interface{}{"string": "b"}, native.(map[string]interface{})["event_name"])
+}
+
+func TestAvroRow_Collision(t *testing.T) {
+	_, err := AvroRow(block.Row{
+		Values: map[string]interface{}{"event.name": "a", "event_name": "b"},
+		Schema: typeof.Schema{"event.name": typeof.String, "event_name": typeof.String},
+	})
+	assert.Error(t, err)
+
+	_, err = ToAvro(nil, typeof.Schema{"a-b": typeof.String, "a b": typeof.String})
+	assert.Error(t, err)
+}
+
+func TestAvroValue(t *testing.T) {
+	ts := time.Unix(1600000000, 0)
+	assert.Equal(t, goavro.Union("long.timestamp-millis", ts), avroValue(ts.Unix(), typeof.Timestamp))
+	assert.Equal(t, goavro.Union("long.timestamp-millis", ts), avroValue(ts.UnixNano()/1e6, typeof.Timestamp))
+	assert.Equal(t, goavro.Union("double", 1.5), avroValue(float32(1.5), typeof.Float64))
+	assert.Equal(t, goavro.Union("double", 2.0), avroValue(int64(2), typeof.Float64))
+	assert.Equal(t, goavro.Union("double", 3.0), avroValue(int32(3), typeof.Float64))
+	assert.Nil(t, avroValue("x", typeof.Float64))
+}
@@ -0,0 +1,120 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package encoder
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kelindar/talaria/internal/encoding/block"
+	"github.com/kelindar/talaria/internal/encoding/typeof"
+)
+
+// NewCSVRow creates a row encoder for a stream, which writes the header before the first row of the
+// stream and whenever the columns change. The columns of the schema of a row are written even if the
+// row has no value for them, so that the rows of a table share the same header.
+func NewCSVRow() RowFunc {
+	var lock sync.Mutex
+	var header []string
+	return func(row block.Row) ([]byte, error) {
+		names := make([]string, 0, len(row.Schema)+len(row.Values))
+		for name := range row.Schema {
+			names = append(names, name)
+		}
+		for name := range row.Values {
+			if _, ok := row.Schema[name]; !ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		record := make([]string, 0, len(names))
+		for _, name := range names {
+			record = append(record, formatCSV(row.Values[name]))
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		records := [][]string{record}
+		if !equalStrings(header, names) {
+			header = names
+			records = [][]string{names, record}
+		}
+
+		var buffer bytes.Buffer
+		if err := writeCSV(&buffer, records...); err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+	}
+}
+
+// ToCSV encodes multiple blocks as a CSV file with a header
+func ToCSV(blocks []block.Block, schema typeof.Schema) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	names := schema.Columns()
+	if err := writer.Write(names); err != nil {
+		return nil, err
+	}
+
+	record := make([]string, len(names))
+	if err := RangeRows(blocks, schema, func(row []interface{}) error {
+		for i, v := range row {
+			record[i] = formatCSV(v)
+		}
+		return writer.Write(record)
+	}); err != nil {
+		return nil, err
+	}
+
+	writer.Flush()
+	return buffer.Bytes(), writer.Error()
+}
+
+// writeCSV writes a set of records
+func writeCSV(buffer *bytes.Buffer, records ...[]string) error {
+	writer := csv.NewWriter(buffer)
+	if err := writer.WriteAll(records); err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// equalStrings returns whether two sets of column names are the same
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// formatCSV formats a value for a CSV field
+func formatCSV(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package encoder
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/kelindar/talaria/internal/column"
+	"github.com/kelindar/talaria/internal/encoding/block"
+	"github.com/kelindar/talaria/internal/encoding/typeof"
+	"github.com/kelindar/talaria/internal/monitor/errors"
+)
+
+// BlockFunc encodes a set of blocks into a single file (e.g. during compaction)
+type BlockFunc func([]block.Block, typeof.Schema) ([]byte, error)
+
+// RowFunc encodes a single row (e.g. during streaming)
+type RowFunc func(block.Row) ([]byte, error)
+
+// Encoder represents a named encoding which can encode blocks, rows or both.
+type Encoder struct {
+	Name      string         // The name of the encoder
+	Extension string         // The extension of the encoded files, defaults to the name
+	Blocks    BlockFunc      // The function used to encode blocks (optional)
+	Row       RowFunc        // The function used to encode a single row (optional)
+	NewRow    func() RowFunc // Creates the function used to encode the rows of a stream, for encoders which keep state across rows (optional)
+}
+
+// The registry of encoders
+var registry = struct {
+	sync.RWMutex
+	encoders map[string]Encoder
+}{encoders: make(map[string]Encoder)}
+
+func init() {
+	Register(Encoder{Name: "json", Extension: "json", Row: JSONRow})
+	Register(Encoder{Name: "ndjson", Extension: "ndjson", Blocks: ToNDJSON, Row: NDJSONRow})
+	Register(Encoder{Name: "csv", Extension: "csv", Blocks: ToCSV, NewRow: NewCSVRow})
+	Register(Encoder{Name: "avro", Extension: "avro", Blocks: ToAvro, Row: AvroRow})
+}
+
+// Register registers an encoder, replacing any existing encoder with the same name.
+func Register(encoder Encoder) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.encoders[strings.ToLower(encoder.Name)] = encoder
+}
+
+// ForBlocks returns a function which encodes a set of blocks for a named encoder.
+func ForBlocks(name string) (BlockFunc, error) {
+	if e, ok := lookup(name); ok && e.Blocks != nil {
+		return e.Blocks, nil
+	}
+
+	return nil, errors.Newf("encoder: unsupported block encoder '%s'", name)
+}
+
+// ForRows returns a function which encodes a single row for a named encoder. Each call returns a new
+// function for the encoders which keep state across the rows of a stream.
+func ForRows(name string) (RowFunc, error) {
+	e, ok := lookup(name)
+	switch {
+	case ok && e.NewRow != nil:
+		return e.NewRow(), nil
+	case ok && e.Row != nil:
+		return e.Row, nil
+	}
+
+	return nil, errors.Newf("encoder: unsupported row encoder '%s'", name)
+}
+
+// ExtensionOf returns the file extension, without the dot, of the files written by a named encoder.
+func ExtensionOf(name string) string {
+	if e, ok := lookup(name); ok && e.Extension != "" {
+		return e.Extension
+	}
+
+	return strings.ToLower(name)
+}
+
+// lookup finds an encoder by its name
+func lookup(name string) (Encoder, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	e, ok := registry.encoders[strings.ToLower(name)]
+	return e, ok
+}
+
+// ----------------------------------------------------------------------------
+
+// RangeRows iterates over every row of the blocks, with the columns ordered as in the schema.
+func RangeRows(blocks []block.Block, schema typeof.Schema, f func(row []interface{}) error) error {
+	names := schema.Columns()
+	for _, blk := range blocks {
+		rows, err := blk.Select(blk.Schema())
+		if err != nil {
+			continue
+		}
+
+		// Fetch columns that is required by the static schema
+		cols := make(column.Columns, len(schema))
+		for name, typ := range schema {
+			col, ok := rows[name]
+			if !ok || (col.Kind() != typ && !isTypeCompatible(col.Kind(), typ)) {
+				col = column.NewColumn(typ)
+			}
+
+			cols[name] = col
+		}
+
+		cols.FillNulls()
+		if len(names) == 0 {
+			continue
+		}
+
+		// Transpose the columns into rows
+		count := cols.Max()
+		values := make([][]interface{}, count)
+		for i := range values {
+			values[i] = make([]interface{}, len(names))
+		}
+
+		for j, name := range names {
+			_ = cols[name].Range(0, count, func(i int, v interface{}) error {
+				values[i][j] = v
+				return nil
+			})
+		}
+
+		for _, row := range values {
+			if err := f(row); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isTypeCompatible checks whether the column kind can be used for a desired type
+func isTypeCompatible(kind, desired typeof.Type) bool {
+	return kind == typeof.String && desired == typeof.JSON
+}
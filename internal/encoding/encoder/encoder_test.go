@@ -0,0 +1,118 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package encoder
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kelindar/talaria/internal/encoding/block"
+	"github.com/kelindar/talaria/internal/encoding/typeof"
+	"github.com/stretchr/testify/assert"
+)
+
+var testSchema = typeof.Schema{
+	"event": typeof.String,
+	"value": typeof.Int64,
+	"data":  typeof.JSON,
+}
+
+// testBlocks creates a set of blocks for testing
+func testBlocks(t *testing.T) []block.Block {
+	csv := []byte("event,value,data\na,1,\"{\"\"x\"\":1}\"\nb,2,\na,3,\"{\"\"x\"\":3}\"\n")
+	blocks, err := block.FromCSVBy(csv, "event", &testSchema, block.Transform(nil))
+	assert.NoError(t, err)
+	assert.Len(t, blocks, 2)
+	return blocks
+}
+
+// lines splits the output into lines
+func lines(b []byte) []string {
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+}
+
+func TestRegistry(t *testing.T) {
+	for _, name := range []string{"ndjson", "csv", "avro", "AVRO"} {
+		blocks, err := ForBlocks(name)
+		assert.NoError(t, err)
+		assert.NotNil(t, blocks)
+
+		row, err := ForRows(name)
+		assert.NoError(t, err)
+		assert.NotNil(t, row)
+	}
+
+	// JSON only supports rows
+	_, err := ForBlocks("json")
+	assert.Error(t, err)
+
+	_, err = ForRows("xxx")
+	assert.Error(t, err)
+
+	// Register a custom encoder
+	Register(Encoder{Name: "custom", Row: func(block.Row) ([]byte, error) {
+		return []byte("custom"), nil
+	}})
+
+	custom, err := ForRows("custom")
+	assert.NoError(t, err)
+	out, _ := custom(block.Row{})
+	assert.Equal(t, "custom", string(out))
+
+	// The extension defaults to the name of the encoder
+	assert.Equal(t, "ndjson", ExtensionOf("NDJSON"))
+	assert.Equal(t, "avro", ExtensionOf("avro"))
+	assert.Equal(t, "custom", ExtensionOf("custom"))
+}
+
+func TestNDJSON(t *testing.T) {
+	out, err := ToNDJSON(testBlocks(t), testSchema)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		`{"data":{"x":1},"event":"a","value":1}`,
+		`{"data":{"x":3},"event":"a","value":3}`,
+		`{"data":"","event":"b","value":2}`,
+	}, lines(out))
+
+	row, err := NDJSONRow(block.Row{Values: map[string]interface{}{"a": 1}})
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"a\":1}\n", string(row))
+}
+
+func TestCSV(t *testing.T) {
+	out, err := ToCSV(testBlocks(t), testSchema)
+	assert.NoError(t, err)
+	assert.Equal(t, "data,event,value", lines(out)[0])
+	assert.ElementsMatch(t, []string{
+		`"{""x"":1}",a,1`,
+		`"{""x"":3}",a,3`,
+		`,b,2`,
+	}, lines(out)[1:])
+
+	encode, err := ForRows("csv")
+	assert.NoError(t, err)
+	row, err := encode(block.Row{Values: map[string]interface{}{"b": "hello, world", "a": 1}})
+	assert.NoError(t, err)
+	assert.Equal(t, "a,b\n1,\"hello, world\"\n", string(row))
+
+	// The header is only written once, and the columns of the schema are always written
+	row, err = encode(block.Row{
+		Values: map[string]interface{}{"b": "x"},
+		Schema: typeof.Schema{"a": typeof.Int64, "b": typeof.String},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, ",x\n", string(row))
+
+	// The header is written again once the columns change
+	row, err = encode(block.Row{Values: map[string]interface{}{"c": 1}})
+	assert.NoError(t, err)
+	assert.Equal(t, "c\n1\n", string(row))
+
+	// Each stream starts with its own header
+	encode, err = ForRows("csv")
+	assert.NoError(t, err)
+	row, err = encode(block.Row{Values: map[string]interface{}{"c": 1}})
+	assert.NoError(t, err)
+	assert.Equal(t, "c\n1\n", string(row))
+}
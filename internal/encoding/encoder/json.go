@@ -0,0 +1,51 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package encoder
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/kelindar/talaria/internal/encoding/block"
+	"github.com/kelindar/talaria/internal/encoding/typeof"
+)
+
+// JSONRow encodes a single row as a JSON object
+func JSONRow(row block.Row) ([]byte, error) {
+	return json.Marshal(row.Values)
+}
+
+// NDJSONRow encodes a single row as a JSON object, followed by a new line
+func NDJSONRow(row block.Row) ([]byte, error) {
+	out, err := json.Marshal(row.Values)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(out, '\n'), nil
+}
+
+// ToNDJSON encodes multiple blocks as newline-delimited JSON, one object per row
+func ToNDJSON(blocks []block.Block, schema typeof.Schema) ([]byte, error) {
+	var buffer bytes.Buffer
+	names := schema.Columns()
+	encoder := json.NewEncoder(&buffer)
+	values := make(map[string]interface{}, len(names))
+	if err := RangeRows(blocks, schema, func(row []interface{}) error {
+		for i, name := range names {
+			values[name] = row[i]
+
+			// Embed JSON columns as they are, rather than as an escaped string
+			if s, ok := row[i].(string); ok && schema[name] == typeof.JSON && json.Valid([]byte(s)) {
+				values[name] = json.RawMessage(s)
+			}
+		}
+
+		return encoder.Encode(values)
+	}); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
@@ -22,10 +22,15 @@ type Key []byte
 
 // New generates a new key for the storage.
 func New(eventName string, tsi time.Time) Key {
+	return WithSequence(eventName, tsi, atomic.AddUint32(&next, 1))
+}
+
+// WithSequence generates a key with a specific sequence number, which is deterministic.
+func WithSequence(eventName string, tsi time.Time, seq uint32) Key {
 	out := make([]byte, size)
 	binary.BigEndian.PutUint32(out[0:4], murmur3.StringSum32(eventName))
 	binary.BigEndian.PutUint64(out[4:12], uint64(tsi.Unix()))
-	binary.BigEndian.PutUint32(out[12:16], atomic.AddUint32(&next, 1))
+	binary.BigEndian.PutUint32(out[12:16], seq)
 	return out
 }
 
@@ -34,6 +39,11 @@ func HashOf(k Key) uint32 {
 	return binary.BigEndian.Uint32(k[0:4])
 }
 
+// TimeOf returns the time of the key
+func TimeOf(k Key) time.Time {
+	return time.Unix(int64(binary.BigEndian.Uint64(k[4:12])), 0).UTC()
+}
+
 // Clone clones a key
 func Clone(k Key) Key {
 	b := make(Key, 16)
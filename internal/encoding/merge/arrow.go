@@ -0,0 +1,71 @@
+package merge
+
+import (
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/kelindar/talaria/internal/column"
+	"github.com/kelindar/talaria/internal/encoding/arrow"
+	"github.com/kelindar/talaria/internal/encoding/block"
+	"github.com/kelindar/talaria/internal/encoding/typeof"
+	"github.com/kelindar/talaria/internal/monitor/errors"
+	"github.com/kelindar/talaria/internal/presto"
+)
+
+// ToArrow merges multiple blocks together and outputs an Arrow IPC file (Feather v2),
+// with one record batch per block.
+func ToArrow(blocks []block.Block, schema typeof.Schema) ([]byte, error) {
+	columns := schema.Columns()
+	arrowSchema, err := arrow.SchemaFor(columns, schema)
+	if err != nil {
+		return nil, errors.Internal("merge: error generating arrow schema", err)
+	}
+
+	// Acquire a buffer to be used during the merging process
+	buffer := acquire()
+	defer release(buffer)
+
+	var records []array.Record
+	defer func() {
+		for _, rec := range records {
+			rec.Release()
+		}
+	}()
+
+	for _, blk := range blocks {
+		rows, err := blk.Select(blk.Schema())
+		if err != nil {
+			continue
+		}
+
+		// Fetch columns that is required by the static schema
+		cols := make(column.Columns, len(schema))
+		for name, typ := range schema {
+			col, ok := rows[name]
+			if !ok || (col.Kind() != typ && !isTypeCompatible(col, typ)) {
+				col = column.NewColumn(typ)
+			}
+
+			cols[name] = col
+		}
+
+		cols.FillNulls()
+
+		ordered := make([]presto.Column, 0, len(columns))
+		for _, name := range columns {
+			ordered = append(ordered, cols[name])
+		}
+
+		rec, err := arrow.RecordOf(arrowSchema, ordered)
+		if err != nil {
+			return nil, errors.Internal("merge: error creating arrow record", err)
+		}
+
+		records = append(records, rec)
+	}
+
+	if err := arrow.WriteFile(buffer, arrowSchema, records...); err != nil {
+		return nil, errors.Internal("merge: error writing arrow file", err)
+	}
+
+	// Always return a cloned buffer since we're reusing the working one
+	return clone(buffer), nil
+}
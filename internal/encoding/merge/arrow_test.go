@@ -0,0 +1,53 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package merge
+
+import (
+	"bytes"
+	"testing"
+
+	eorc "github.com/crphang/orc"
+	"github.com/kelindar/talaria/internal/encoding/arrow"
+	"github.com/kelindar/talaria/internal/encoding/block"
+	"github.com/kelindar/talaria/internal/encoding/orc"
+	"github.com/kelindar/talaria/internal/encoding/typeof"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToArrow(t *testing.T) {
+	schema := typeof.Schema{
+		"col0": typeof.String,
+		"col1": typeof.Int64,
+		"col2": typeof.Float64,
+	}
+	orcSchema, err := orc.SchemaFor(schema)
+	assert.NoError(t, err)
+
+	var blocks []block.Block
+	for i := 1; i <= 2; i++ {
+		buffer := &bytes.Buffer{}
+		writer, _ := eorc.NewWriter(buffer, eorc.SetSchema(orcSchema))
+		_ = writer.Write("eventName", i, float64(i))
+		_ = writer.Close()
+
+		blk, err := block.FromOrcBy(buffer.Bytes(), "col0", nil, block.Transform(nil))
+		assert.NoError(t, err)
+		blocks = append(blocks, blk...)
+	}
+
+	merged, err := ToArrow(blocks, schema)
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(merged, []byte("ARROW1")))
+
+	// Read the file back
+	var rows [][]interface{}
+	assert.NoError(t, arrow.Range(merged, func(_ int, v []interface{}) bool {
+		rows = append(rows, append([]interface{}{}, v...))
+		return false
+	}, "col0", "col1", "col2"))
+	assert.Equal(t, [][]interface{}{
+		{"eventName", int64(1), 1.0},
+		{"eventName", int64(2), 2.0},
+	}, rows)
+}
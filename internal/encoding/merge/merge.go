@@ -2,29 +2,37 @@ package merge
 
 import (
 	"bytes"
-	"strings"
 	"sync"
 
-	"github.com/kelindar/talaria/internal/encoding/block"
-	"github.com/kelindar/talaria/internal/encoding/typeof"
-	"github.com/kelindar/talaria/internal/monitor/errors"
+	"github.com/kelindar/talaria/internal/encoding/encoder"
 )
 
 // Func represents merge function
-type Func func([]block.Block, typeof.Schema) ([]byte, error)
+type Func = encoder.BlockFunc
 
-// New creates a new merge function
+func init() {
+	encoder.Register(encoder.Encoder{Name: "orc", Extension: "orc", Blocks: ToOrc})
+	encoder.Register(encoder.Encoder{Name: "parquet", Extension: "parquet", Blocks: ToParquet})
+	encoder.Register(encoder.Encoder{Name: "arrow", Extension: "arrow", Blocks: ToArrow})
+	encoder.Register(encoder.Encoder{Name: "feather", Extension: "feather", Blocks: ToArrow})
+}
+
+// New creates a new merge function, using any of the registered encoders
 func New(mergeFunc string) (Func, error) {
-	switch strings.ToLower(mergeFunc) {
-	case "orc":
-		return ToOrc, nil
-	case "parquet":
-		return ToParquet, nil
-	case "": // Default to "orc" so we don't break existing configs
+	if mergeFunc == "" { // Default to "orc" so we don't break existing configs
 		return ToOrc, nil
 	}
 
-	return nil, errors.Newf("unsupported merge function %v", mergeFunc)
+	return encoder.ForBlocks(mergeFunc)
+}
+
+// ExtensionOf returns the file extension of a merge function, using any of the registered encoders
+func ExtensionOf(mergeFunc string) string {
+	if mergeFunc == "" {
+		return "orc"
+	}
+
+	return encoder.ExtensionOf(mergeFunc)
 }
 
 // ----------------------------------------------------------------------------
@@ -4,17 +4,30 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"time"
 
 	goparquet "github.com/fraugster/parquet-go"
 	"github.com/fraugster/parquet-go/parquet"
 	"github.com/fraugster/parquet-go/parquetschema"
 	"github.com/kelindar/talaria/internal/column"
+	"github.com/kelindar/talaria/internal/config"
 	"github.com/kelindar/talaria/internal/encoding/block"
 	"github.com/kelindar/talaria/internal/encoding/typeof"
 	"github.com/kelindar/talaria/internal/monitor/errors"
 	"github.com/kelindar/talaria/internal/presto"
 )
 
+// The default target size of a row group, in bytes
+const defaultRowGroupSize = 128 << 20
+
+// parquetOptions represents the options of the parquet encoder
+type parquetOptions struct {
+	rowGroupSize int64 // The target size of a row group, in bytes
+	pageSize     int64 // The maximum size of a data page, in bytes (zero if unlimited)
+	dictionary   bool  // Whether dictionary encoding is allowed
+	statistics   bool  // Whether min/max column statistics are written
+}
+
 // ToParquet merges multiple blocks together and outputs a key and merged Parquet data
 func ToParquet(blocks []block.Block, schema typeof.Schema) ([]byte, error) {
 	parquetSchema, fieldHandlers, err := deriveSchema(schema)
@@ -33,6 +46,85 @@ func ToParquet(blocks []block.Block, schema typeof.Schema) ([]byte, error) {
 		goparquet.WithCreator("write-lowlevel"),
 	)
 
+	if err := writeRows(blocks, schema, fieldHandlers, writer.AddData); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, errors.Internal("flush: error closing writer", err)
+	}
+
+	// Always return a cloned buffer since we're reusing the working one
+	return clone(buffer), nil
+}
+
+// NewParquet creates a parquet merge function with the specified row group, page, dictionary
+// and statistics options. Options which are not specified fall back to their defaults.
+func NewParquet(conf *config.Parquet) Func {
+	options := newParquetOptions(conf)
+	return func(blocks []block.Block, schema typeof.Schema) ([]byte, error) {
+		return toParquet(blocks, schema, options)
+	}
+}
+
+// newParquetOptions creates the options for the parquet encoder from the configuration
+func newParquetOptions(conf *config.Parquet) parquetOptions {
+	options := parquetOptions{
+		rowGroupSize: defaultRowGroupSize,
+		dictionary:   true,
+		statistics:   true,
+	}
+
+	if conf == nil {
+		return options
+	}
+
+	if conf.RowGroupSize != nil && *conf.RowGroupSize > 0 {
+		options.rowGroupSize = *conf.RowGroupSize
+	}
+	if conf.PageSize != nil && *conf.PageSize > 0 {
+		options.pageSize = *conf.PageSize
+	}
+	if conf.Dictionary != nil {
+		options.dictionary = *conf.Dictionary
+	}
+	if conf.Statistics != nil {
+		options.statistics = *conf.Statistics
+	}
+	return options
+}
+
+// toParquet merges multiple blocks together and outputs merged Parquet data
+func toParquet(blocks []block.Block, schema typeof.Schema, options parquetOptions) ([]byte, error) {
+	parquetSchema, fieldHandlers, err := deriveSchema(schema)
+
+	if err != nil {
+		return nil, errors.Internal("merge: error generating parquet schema", err)
+	}
+
+	// Acquire a buffer to be used during the merging process
+	buffer := acquire()
+	defer release(buffer)
+
+	writer, err := newParquetWriter(buffer, parquetSchema, options)
+	if err != nil {
+		return nil, errors.Internal("merge: error creating parquet writer", err)
+	}
+
+	if err := writeRows(blocks, schema, fieldHandlers, writer.AddData); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, errors.Internal("flush: error closing writer", err)
+	}
+
+	// Always return a cloned buffer since we're reusing the working one
+	return clone(buffer), nil
+}
+
+// writeRows converts the rows of the blocks and writes them one by one
+func writeRows(blocks []block.Block, schema typeof.Schema, fieldHandlers []fieldHandler, addData func(map[string]interface{}) error) error {
 	for _, blk := range blocks {
 		rows, err := blk.Select(blk.Schema())
 		if err != nil {
@@ -72,19 +164,13 @@ func ToParquet(blocks []block.Block, schema typeof.Schema) ([]byte, error) {
 				data[colName] = finalData
 			}
 
-			if err := writer.AddData(data); err != nil {
-				return nil, errors.Internal("flush: error writing row", err)
+			if err := addData(data); err != nil {
+				return errors.Internal("flush: error writing row", err)
 				// TODO: should we ignore or continue?
 			}
 		}
 	}
-
-	if err := writer.Close(); err != nil {
-		return nil, errors.Internal("flush: error closing writer", err)
-	}
-
-	// Always return a cloned buffer since we're reusing the working one
-	return clone(buffer), nil
+	return nil
 }
 
 type fieldHandler func(interface{}) (interface{}, error)
@@ -136,7 +222,7 @@ func createColumn(field, typ string) (col *parquetschema.ColumnDefinition, field
 	case "byte_array":
 		col.SchemaElement.Type = parquet.TypePtr(parquet.Type_BYTE_ARRAY)
 		return col, optional(byteArrayHandler), nil
-	case "boolean":
+	case "bool", "boolean":
 		col.SchemaElement.Type = parquet.TypePtr(parquet.Type_BOOLEAN)
 		return col, optional(booleanHandler), nil
 	case "int8":
@@ -199,6 +285,15 @@ func createColumn(field, typ string) (col *parquetschema.ColumnDefinition, field
 		col.SchemaElement.LogicalType.INTEGER = &parquet.IntType{BitWidth: 64, IsSigned: true}
 		col.SchemaElement.ConvertedType = parquet.ConvertedTypePtr(parquet.ConvertedType_INT_64)
 		return col, optional(intHandler(64)), nil
+	case "timestamp":
+		col.SchemaElement.Type = parquet.TypePtr(parquet.Type_INT64)
+		col.SchemaElement.LogicalType = parquet.NewLogicalType()
+		col.SchemaElement.LogicalType.TIMESTAMP = &parquet.TimestampType{
+			IsAdjustedToUTC: true,
+			Unit:            &parquet.TimeUnit{MILLIS: parquet.NewMilliSeconds()},
+		}
+		col.SchemaElement.ConvertedType = parquet.ConvertedTypePtr(parquet.ConvertedType_TIMESTAMP_MILLIS)
+		return col, optional(timestampHandler), nil
 	case "json":
 		col.SchemaElement.Type = parquet.TypePtr(parquet.Type_BYTE_ARRAY)
 		col.SchemaElement.LogicalType = parquet.NewLogicalType()
@@ -306,6 +401,17 @@ func doubleHandler(s interface{}) (interface{}, error) {
 	}
 }
 
+func timestampHandler(s interface{}) (interface{}, error) {
+	switch v := s.(type) {
+	case time.Time:
+		return v.UnixNano() / int64(time.Millisecond), nil
+	case int64:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("toparquet: unable to parse as timestamp %v", s)
+	}
+}
+
 // Allows fieldHandlers to be chained
 func optional(next fieldHandler) fieldHandler {
 	return func(s interface{}) (interface{}, error) {
@@ -2,10 +2,18 @@ package merge
 
 import (
 	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
 	"testing"
+	"time"
 
+	"github.com/apache/thrift/lib/go/thrift"
 	goparquet "github.com/fraugster/parquet-go"
 	"github.com/fraugster/parquet-go/parquet"
+	"github.com/kelindar/talaria/internal/column"
+	"github.com/kelindar/talaria/internal/config"
 	"github.com/kelindar/talaria/internal/encoding/block"
 	"github.com/kelindar/talaria/internal/encoding/typeof"
 	"github.com/stretchr/testify/assert"
@@ -215,3 +223,269 @@ func TestMergeParquet_DifferentSchema(t *testing.T) {
 	}
 }
 
+func TestToParquet_Options(t *testing.T) {
+	schema := typeof.Schema{
+		"col0": typeof.String,
+		"col1": typeof.Int64,
+		"col2": typeof.Bool,
+	}
+
+	cols := column.MakeColumns(&schema)
+	for i := 0; i < 100; i++ {
+		cols.Append("col0", "event", typeof.String)
+		cols.Append("col1", int64(i), typeof.Int64)
+		cols.Append("col2", i%2 == 0, typeof.Bool)
+	}
+
+	blk, err := block.FromColumns("event", cols)
+	assert.NoError(t, err)
+
+	rowGroupSize := int64(500)
+	dictionary := false
+	statistics := true
+	merged, err := NewParquet(&config.Parquet{
+		RowGroupSize: &rowGroupSize,
+		Dictionary:   &dictionary,
+		Statistics:   &statistics,
+	})([]block.Block{blk}, schema)
+	assert.NoError(t, err)
+
+	// Must have multiple row groups, each with min/max statistics and no dictionary
+	meta := readParquetMeta(t, merged)
+	assert.Greater(t, len(meta.RowGroups), 1)
+	assert.Len(t, meta.ColumnOrders, 3)
+	for _, group := range meta.RowGroups {
+		for _, chunk := range group.Columns {
+			assert.Nil(t, chunk.MetaData.DictionaryPageOffset)
+			assert.NotNil(t, chunk.MetaData.Statistics.MinValue)
+			assert.NotNil(t, chunk.MetaData.Statistics.MaxValue)
+		}
+	}
+
+	// Boolean and string statistics are computed as well
+	assert.Equal(t, []byte("event"), meta.RowGroups[0].Columns[0].MetaData.Statistics.MinValue)
+	assert.Equal(t, []byte{0}, meta.RowGroups[0].Columns[2].MetaData.Statistics.MinValue)
+	assert.Equal(t, []byte{1}, meta.RowGroups[0].Columns[2].MetaData.Statistics.MaxValue)
+
+	// Read the file back
+	assertParquetRows(t, merged, 100)
+
+	// Disable the statistics
+	statistics = false
+	merged, err = NewParquet(&config.Parquet{
+		Statistics: &statistics,
+	})([]block.Block{blk}, schema)
+	assert.NoError(t, err)
+
+	meta = readParquetMeta(t, merged)
+	assert.Len(t, meta.RowGroups, 1)
+	assert.Nil(t, meta.ColumnOrders)
+	for _, chunk := range meta.RowGroups[0].Columns {
+		assert.Nil(t, chunk.MetaData.Statistics)
+	}
+}
+
+func TestToParquet_PageSize(t *testing.T) {
+	schema := typeof.Schema{
+		"col0": typeof.String,
+		"col1": typeof.Int64,
+		"col2": typeof.Bool,
+		"col3": typeof.String,
+	}
+
+	cols := column.MakeColumns(&schema)
+	for i := 0; i < 100; i++ {
+		cols.Append("col0", "event", typeof.String)
+		cols.Append("col1", int64(i), typeof.Int64)
+		cols.Append("col2", i%2 == 0, typeof.Bool)
+		cols.Append("col3", fmt.Sprintf("value-%d", i%10), typeof.String)
+	}
+
+	blk, err := block.FromColumns("event", cols)
+	assert.NoError(t, err)
+
+	pageSize := int64(40)
+	merged, err := NewParquet(&config.Parquet{
+		PageSize: &pageSize,
+	})([]block.Block{blk}, schema)
+	assert.NoError(t, err)
+
+	// Must have a single row group, with multiple pages per column chunk
+	meta := readParquetMeta(t, merged)
+	assert.Len(t, meta.RowGroups, 1)
+	assert.Equal(t, int64(100), meta.RowGroups[0].NumRows)
+	chunks := meta.RowGroups[0].Columns
+	for _, chunk := range chunks {
+		assert.Equal(t, int64(100), chunk.MetaData.NumValues)
+	}
+
+	// The repeated strings share a single dictionary page across their data pages
+	assert.NotNil(t, chunks[0].MetaData.DictionaryPageOffset)
+	assert.Equal(t, 1, countParquetPages(t, merged, chunks[0].MetaData))
+	assert.Nil(t, chunks[1].MetaData.DictionaryPageOffset)
+	assert.Greater(t, countParquetPages(t, merged, chunks[1].MetaData), 1)
+	assert.Nil(t, chunks[2].MetaData.DictionaryPageOffset)
+	assert.Greater(t, countParquetPages(t, merged, chunks[2].MetaData), 1)
+	assert.NotNil(t, chunks[3].MetaData.DictionaryPageOffset)
+	assert.Greater(t, countParquetPages(t, merged, chunks[3].MetaData), 1)
+
+	// The statistics cover every page
+	stats := chunks[1].MetaData.Statistics
+	assert.Equal(t, uint64(0), binary.LittleEndian.Uint64(stats.MinValue))
+	assert.Equal(t, uint64(99), binary.LittleEndian.Uint64(stats.MaxValue))
+
+	// Read the file back
+	assertParquetRows(t, merged, 100)
+
+	reader, err := goparquet.NewFileReader(bytes.NewReader(merged))
+	assert.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		row, err := reader.NextRow()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(fmt.Sprintf("value-%d", i%10)), row["col3"])
+	}
+}
+
+func TestToParquet_Statistics(t *testing.T) {
+	schema := typeof.Schema{
+		"col0": typeof.Int32,
+		"col1": typeof.Float64,
+		"col2": typeof.Timestamp,
+	}
+
+	cols := column.MakeColumns(&schema)
+	for i := 0; i < 10; i++ {
+		cols.Append("col0", int32(10-i), typeof.Int32)
+		cols.Append("col1", float64(i)/2, typeof.Float64)
+		cols.Append("col2", time.Unix(int64(1000+i), 0), typeof.Timestamp)
+	}
+
+	blk, err := block.FromColumns("event", cols)
+	assert.NoError(t, err)
+
+	merged, err := NewParquet(nil)([]block.Block{blk}, schema)
+	assert.NoError(t, err)
+
+	meta := readParquetMeta(t, merged)
+	assert.Len(t, meta.RowGroups, 1)
+	chunks := meta.RowGroups[0].Columns
+
+	// Integers are encoded on 4 bytes
+	assert.Equal(t, uint32(1), binary.LittleEndian.Uint32(chunks[0].MetaData.Statistics.MinValue))
+	assert.Equal(t, uint32(10), binary.LittleEndian.Uint32(chunks[0].MetaData.Statistics.MaxValue))
+
+	// Floats are encoded on 4 bytes as well
+	assert.Equal(t, float32(0), math.Float32frombits(binary.LittleEndian.Uint32(chunks[1].MetaData.Statistics.MinValue)))
+	assert.Equal(t, float32(4.5), math.Float32frombits(binary.LittleEndian.Uint32(chunks[1].MetaData.Statistics.MaxValue)))
+
+	// Timestamps are encoded as milliseconds
+	assert.Equal(t, uint64(1000000), binary.LittleEndian.Uint64(chunks[2].MetaData.Statistics.MinValue))
+	assert.Equal(t, uint64(1009000), binary.LittleEndian.Uint64(chunks[2].MetaData.Statistics.MaxValue))
+}
+
+func TestToParquet_Nulls(t *testing.T) {
+	schema := typeof.Schema{
+		"col0": typeof.String,
+		"col1": typeof.Int64,
+		"col2": typeof.Int64,
+	}
+
+	cols := column.MakeColumns(&schema)
+	for i := 0; i < 1000; i++ {
+		cols.Append("col2", int64(i), typeof.Int64)
+		if i%3 != 0 {
+			cols.Append("col0", fmt.Sprintf("value-%d", i%300), typeof.String)
+		}
+		if i < 500 || i%7 == 0 {
+			cols.Append("col1", int64(i/20), typeof.Int64)
+		}
+		cols.FillNulls()
+	}
+
+	blk, err := block.FromColumns("event", cols)
+	assert.NoError(t, err)
+
+	merged, err := NewParquet(nil)([]block.Block{blk}, schema)
+	assert.NoError(t, err)
+
+	// Long runs are run length encoded and the other values are bit-packed
+	reader, err := goparquet.NewFileReader(bytes.NewReader(merged))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), reader.NumRows())
+	for i := 0; i < 1000; i++ {
+		row, err := reader.NextRow()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(i), row["col2"])
+
+		if i%3 != 0 {
+			assert.Equal(t, []byte(fmt.Sprintf("value-%d", i%300)), row["col0"])
+		} else {
+			assert.Nil(t, row["col0"])
+		}
+
+		if i < 500 || i%7 == 0 {
+			assert.Equal(t, int64(i/20), row["col1"])
+		} else {
+			assert.Nil(t, row["col1"])
+		}
+	}
+}
+
+func TestEncodeStatistic(t *testing.T) {
+	assert.Equal(t, []byte{1, 0, 0, 0}, encodeStatistic(uint32(1)))
+	assert.Equal(t, []byte{0xff, 0xff, 0xff, 0xff}, encodeStatistic(int32(-1)))
+	assert.Equal(t, []byte{2, 0, 0, 0, 0, 0, 0, 0}, encodeStatistic(uint64(2)))
+	assert.Equal(t, 1, compareStatistic(uint32(math.MaxUint32), uint32(1), false))
+	assert.Equal(t, 1, compareStatistic(int32(-1), int32(1), true))
+	assert.Equal(t, -1, compareStatistic(int32(-1), int32(1), false))
+}
+
+// assertParquetRows reads every row of a parquet file back
+func assertParquetRows(t *testing.T, file []byte, count int) {
+	reader, err := goparquet.NewFileReader(bytes.NewReader(file))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(count), reader.NumRows())
+
+	for i := 0; i < count; i++ {
+		row, err := reader.NextRow()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("event"), row["col0"])
+		assert.Equal(t, int64(i), row["col1"])
+		assert.Equal(t, i%2 == 0, row["col2"])
+	}
+
+	_, err = reader.NextRow()
+	assert.Equal(t, io.EOF, err)
+}
+
+// countParquetPages counts the data pages of a column chunk by reading the headers of its pages
+func countParquetPages(t *testing.T, file []byte, meta *parquet.ColumnMetaData) (pages int) {
+	start := meta.DataPageOffset
+	if meta.DictionaryPageOffset != nil {
+		start = *meta.DictionaryPageOffset
+	}
+
+	reader := bytes.NewReader(file[start : start+meta.TotalCompressedSize])
+	for reader.Len() > 0 {
+		header := parquet.NewPageHeader()
+		assert.NoError(t, header.Read(thrift.NewTCompactProtocol(&thrift.StreamTransport{Reader: reader})))
+		_, err := reader.Seek(int64(header.CompressedPageSize), io.SeekCurrent)
+		assert.NoError(t, err)
+		if header.Type == parquet.PageType_DATA_PAGE {
+			pages++
+		}
+	}
+	return
+}
+
+// readParquetMeta reads the footer of a parquet file
+func readParquetMeta(t *testing.T, file []byte) *parquet.FileMetaData {
+	size := len(file)
+	start := size - 8 - int(binary.LittleEndian.Uint32(file[size-8:size-4]))
+
+	meta := parquet.NewFileMetaData()
+	assert.NoError(t, meta.Read(thrift.NewTCompactProtocol(&thrift.StreamTransport{
+		Reader: bytes.NewReader(file[start : size-8]),
+	})))
+	return meta
+}
@@ -0,0 +1,628 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package merge
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/fraugster/parquet-go/parquet"
+	"github.com/fraugster/parquet-go/parquetschema"
+	"github.com/golang/snappy"
+)
+
+// The magic bytes at the start and the end of a parquet file
+var parquetMagic = []byte("PAR1")
+
+// The maximum number of values of a dictionary
+const maxDictionarySize = math.MaxInt16
+
+// parquetWriter represents a parquet file writer which buffers the values of each column of a row
+// group and writes each column chunk as a dictionary page, if dictionary encoding is smaller, followed
+// by data pages of the target size. The min/max statistics of the column chunks are computed as well.
+type parquetWriter struct {
+	writer  io.Writer
+	options parquetOptions
+	schema  []*parquet.SchemaElement // The flattened schema, starting with the root
+	columns []*parquetColumn         // The columns, in the order of the schema
+	offset  int64                    // The number of bytes written so far
+	rows    int64                    // The number of rows in the current row group
+	size    int64                    // The plain encoded size of the current row group
+	total   int64                    // The number of rows in the flushed row groups
+	groups  []*parquet.RowGroup      // The row groups which were flushed
+}
+
+// parquetColumn represents the buffered values of a column of the current row group
+type parquetColumn struct {
+	elem     *parquet.SchemaElement
+	unsigned bool          // Whether the column is an unsigned integer
+	levels   []uint32      // The definition level of each row, zero when the value is null
+	values   []interface{} // The values which are not null
+	bounds   chunkBounds   // The min/max values
+}
+
+// chunkBounds represents the min/max values of a column chunk
+type chunkBounds struct {
+	min, max interface{}
+}
+
+// newParquetWriter creates a new parquet writer for a schema and writes the header of the file
+func newParquetWriter(w io.Writer, schema *parquetschema.SchemaDefinition, options parquetOptions) (*parquetWriter, error) {
+	children := schema.RootColumn.Children
+	count := int32(len(children))
+	writer := &parquetWriter{
+		writer:  w,
+		options: options,
+		schema:  []*parquet.SchemaElement{{Name: schema.RootColumn.SchemaElement.GetName(), NumChildren: &count}},
+		columns: make([]*parquetColumn, 0, len(children)),
+	}
+
+	for _, child := range children {
+		elem := child.SchemaElement
+		col := &parquetColumn{elem: elem}
+		if t := elem.LogicalType; t != nil && t.INTEGER != nil && !t.INTEGER.IsSigned {
+			col.unsigned = true
+		}
+
+		writer.schema = append(writer.schema, elem)
+		writer.columns = append(writer.columns, col)
+	}
+
+	if err := writer.write(parquetMagic); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// AddData adds a row and flushes the row group once it reaches the target row group size
+func (w *parquetWriter) AddData(row map[string]interface{}) error {
+	for _, col := range w.columns {
+		value, err := valueOf(col.elem.GetType(), row[col.elem.GetName()])
+		if err != nil {
+			return fmt.Errorf("toparquet: column %s: %v", col.elem.GetName(), err)
+		}
+
+		if value == nil {
+			col.levels = append(col.levels, 0)
+			continue
+		}
+
+		col.levels = append(col.levels, 1)
+		col.values = append(col.values, value)
+		col.bounds.add(value, col.unsigned)
+		w.size += sizeOfValue(value)
+	}
+
+	w.rows++
+	if w.size >= w.options.rowGroupSize {
+		return w.FlushRowGroup()
+	}
+	return nil
+}
+
+// FlushRowGroup writes the column chunks of the current row group
+func (w *parquetWriter) FlushRowGroup() error {
+	if w.rows == 0 {
+		return nil
+	}
+
+	group := &parquet.RowGroup{
+		Columns: make([]*parquet.ColumnChunk, 0, len(w.columns)),
+		NumRows: w.rows,
+	}
+
+	for _, col := range w.columns {
+		chunk, err := w.writeChunk(col)
+		if err != nil {
+			return err
+		}
+
+		group.Columns = append(group.Columns, chunk)
+		group.TotalByteSize += chunk.MetaData.TotalUncompressedSize
+		*col = parquetColumn{elem: col.elem, unsigned: col.unsigned}
+	}
+
+	w.groups = append(w.groups, group)
+	w.total += w.rows
+	w.rows = 0
+	w.size = 0
+	return nil
+}
+
+// Close flushes the remaining rows and writes the footer, either with the statistics of every column
+// chunk along with the column orders which allow query engines to use them, or without any statistics
+// if they are disabled.
+func (w *parquetWriter) Close() error {
+	if err := w.FlushRowGroup(); err != nil {
+		return err
+	}
+
+	creator := "write-lowlevel"
+	meta := &parquet.FileMetaData{
+		Version:   1,
+		Schema:    w.schema,
+		NumRows:   w.total,
+		RowGroups: w.groups,
+		CreatedBy: &creator,
+	}
+
+	// Without the column orders, query engines ignore the min/max values
+	if w.options.statistics {
+		for range w.columns {
+			meta.ColumnOrders = append(meta.ColumnOrders, &parquet.ColumnOrder{
+				TYPE_ORDER: parquet.NewTypeDefinedOrder(),
+			})
+		}
+	}
+
+	footer, err := encodeThrift(meta)
+	if err != nil {
+		return err
+	}
+
+	length := make([]byte, 4)
+	binary.LittleEndian.PutUint32(length, uint32(len(footer)))
+	return w.write(footer, length, parquetMagic)
+}
+
+// writeChunk writes the column chunk of the current row group and returns its metadata. A single
+// dictionary page is shared by all of the data pages of the chunk.
+func (w *parquetWriter) writeChunk(col *parquetColumn) (*parquet.ColumnChunk, error) {
+	start := w.offset
+	meta := &parquet.ColumnMetaData{
+		Type:           col.elem.GetType(),
+		Encodings:      []parquet.Encoding{parquet.Encoding_RLE, parquet.Encoding_PLAIN},
+		PathInSchema:   []string{col.elem.GetName()},
+		Codec:          parquet.CompressionCodec_SNAPPY,
+		NumValues:      int64(len(col.levels)),
+		DataPageOffset: start,
+	}
+
+	// Write the dictionary page first, if the data pages are dictionary encoded
+	dict, indices := col.dictionary(w.options.dictionary)
+	width := 0
+	if dict != nil {
+		width = bitWidthOf(len(dict) - 1)
+		values, err := encodePlain(dict)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := w.writePage(meta, &parquet.PageHeader{
+			Type: parquet.PageType_DICTIONARY_PAGE,
+			DictionaryPageHeader: &parquet.DictionaryPageHeader{
+				NumValues: int32(len(dict)),
+				Encoding:  parquet.Encoding_PLAIN,
+			},
+		}, values); err != nil {
+			return nil, err
+		}
+
+		meta.DictionaryPageOffset = &start
+		meta.DataPageOffset = w.offset
+		meta.Encodings = append(meta.Encodings, parquet.Encoding_RLE_DICTIONARY)
+	}
+
+	// Write the data pages, each with the rows whose values fit in the page size
+	first, from, used := 0, 0, int64(0)
+	for row, value := 0, 0; row < len(col.levels); row++ {
+		if col.levels[row] == 1 {
+			switch {
+			case dict != nil:
+				used += int64(width)
+			default:
+				used += 8 * sizeOfValue(col.values[value])
+			}
+			value++
+		}
+
+		if row+1 < len(col.levels) && (w.options.pageSize == 0 || used < 8*w.options.pageSize) {
+			continue
+		}
+
+		var page []byte
+		var err error
+		switch {
+		case dict != nil:
+			page, err = encodeDataPage(col.levels[first:row+1], nil, indices[from:value], width)
+		default:
+			page, err = encodeDataPage(col.levels[first:row+1], col.values[from:value], nil, 0)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		encoding := parquet.Encoding_PLAIN
+		if dict != nil {
+			encoding = parquet.Encoding_RLE_DICTIONARY
+		}
+
+		if err := w.writePage(meta, &parquet.PageHeader{
+			Type: parquet.PageType_DATA_PAGE,
+			DataPageHeader: &parquet.DataPageHeader{
+				NumValues:               int32(row + 1 - first),
+				Encoding:                encoding,
+				DefinitionLevelEncoding: parquet.Encoding_RLE,
+				RepetitionLevelEncoding: parquet.Encoding_RLE,
+			},
+		}, page); err != nil {
+			return nil, err
+		}
+
+		first, from, used = row+1, value, 0
+	}
+
+	if w.options.statistics {
+		nulls := int64(len(col.levels) - len(col.values))
+		meta.Statistics = &parquet.Statistics{NullCount: &nulls}
+		if col.bounds.min != nil {
+			meta.Statistics.MinValue = encodeStatistic(col.bounds.min)
+			meta.Statistics.MaxValue = encodeStatistic(col.bounds.max)
+		}
+	}
+
+	return &parquet.ColumnChunk{
+		FileOffset: start,
+		MetaData:   meta,
+	}, nil
+}
+
+// writePage compresses and writes a page along with its header, and accounts for its size
+func (w *parquetWriter) writePage(meta *parquet.ColumnMetaData, header *parquet.PageHeader, page []byte) error {
+	compressed := snappy.Encode(nil, page)
+	header.UncompressedPageSize = int32(len(page))
+	header.CompressedPageSize = int32(len(compressed))
+	head, err := encodeThrift(header)
+	if err != nil {
+		return err
+	}
+
+	if err := w.write(head, compressed); err != nil {
+		return err
+	}
+
+	meta.TotalUncompressedSize += int64(len(head) + len(page))
+	meta.TotalCompressedSize += int64(len(head) + len(compressed))
+	return nil
+}
+
+// write writes the buffers to the underlying writer and keeps track of the offset
+func (w *parquetWriter) write(buffers ...[]byte) error {
+	for _, b := range buffers {
+		n, err := w.writer.Write(b)
+		w.offset += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dictionary returns the distinct values of the column along with the index of each value, if
+// dictionary encoding is allowed and smaller than the plain encoding
+func (c *parquetColumn) dictionary(allowed bool) ([]interface{}, []uint32) {
+	if !allowed || len(c.values) == 0 || c.elem.GetType() == parquet.Type_BOOLEAN {
+		return nil, nil
+	}
+
+	var dict []interface{}
+	var dictSize, plainSize int64
+	lookup := make(map[interface{}]uint32)
+	indices := make([]uint32, 0, len(c.values))
+	for _, v := range c.values {
+		k := v
+		if b, ok := v.([]byte); ok {
+			k = string(b)
+		}
+
+		i, ok := lookup[k]
+		if !ok {
+			if len(dict) == maxDictionarySize {
+				return nil, nil
+			}
+
+			i = uint32(len(dict))
+			lookup[k] = i
+			dict = append(dict, v)
+			dictSize += sizeOfValue(v)
+		}
+
+		indices = append(indices, i)
+		plainSize += sizeOfValue(v)
+	}
+
+	if dictSize+int64(len(indices)*bitWidthOf(len(dict)-1)/8) >= plainSize {
+		return nil, nil
+	}
+	return dict, indices
+}
+
+// add adds a value to the bounds
+func (b *chunkBounds) add(v interface{}, unsigned bool) {
+	if v == nil {
+		return
+	}
+
+	if b.min == nil || compareStatistic(v, b.min, unsigned) < 0 {
+		b.min = v
+	}
+	if b.max == nil || compareStatistic(v, b.max, unsigned) > 0 {
+		b.max = v
+	}
+}
+
+// valueOf converts a value to the type of the values of a column, or returns nil if it is null
+func valueOf(typ parquet.Type, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	switch typ {
+	case parquet.Type_BYTE_ARRAY:
+		if b, ok := v.([]byte); ok {
+			return b, nil
+		}
+	case parquet.Type_BOOLEAN:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case parquet.Type_INT32:
+		switch x := v.(type) {
+		case int32:
+			return x, nil
+		case uint32:
+			return int32(x), nil
+		}
+	case parquet.Type_INT64:
+		switch x := v.(type) {
+		case int64:
+			return x, nil
+		case uint64:
+			return int64(x), nil
+		}
+	case parquet.Type_FLOAT:
+		switch x := v.(type) {
+		case float32:
+			return x, nil
+		case float64:
+			return float32(x), nil
+		}
+	case parquet.Type_DOUBLE:
+		switch x := v.(type) {
+		case float64:
+			return x, nil
+		case float32:
+			return float64(x), nil
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported value %T for type %v", v, typ)
+}
+
+// compareStatistic compares two values of the same column, as they are ordered by query engines
+func compareStatistic(a, b interface{}, unsigned bool) int {
+	switch x := a.(type) {
+	case []byte:
+		return bytes.Compare(x, b.([]byte))
+	case float32:
+		return compareValues(float64(x), float64(b.(float32)))
+	case int32:
+		if unsigned {
+			return compareUint64(uint64(uint32(x)), uint64(uint32(b.(int32))))
+		}
+	case int64:
+		if unsigned {
+			return compareUint64(uint64(x), uint64(b.(int64)))
+		}
+	case uint32:
+		return compareUint64(uint64(x), uint64(b.(uint32)))
+	case uint64:
+		return compareUint64(x, b.(uint64))
+	}
+
+	return compareValues(a, b)
+}
+
+// encodeStatistic encodes a statistic value using the plain encoding
+func encodeStatistic(v interface{}) []byte {
+	switch v := v.(type) {
+	case []byte:
+		return v
+	case bool:
+		return encodeBool(v)
+	case int32:
+		return encodeStatistic(uint32(v))
+	case uint32:
+		out := make([]byte, 4)
+		binary.LittleEndian.PutUint32(out, v)
+		return out
+	case int64:
+		return encodeStatistic(uint64(v))
+	case uint64:
+		out := make([]byte, 8)
+		binary.LittleEndian.PutUint64(out, v)
+		return out
+	case float32:
+		return encodeStatistic(math.Float32bits(v))
+	case float64:
+		return encodeStatistic(math.Float64bits(v))
+	default:
+		return nil
+	}
+}
+
+// encodeDataPage encodes the definition levels of a data page followed by either its plain encoded
+// values or the indices of its values in the dictionary
+func encodeDataPage(levels []uint32, values []interface{}, indices []uint32, width int) ([]byte, error) {
+	encoded := encodeHybrid(levels, 1)
+	page := bytes.NewBuffer(make([]byte, 0, 4+len(encoded)))
+	_ = binary.Write(page, binary.LittleEndian, uint32(len(encoded)))
+	page.Write(encoded)
+
+	if indices != nil {
+		page.WriteByte(byte(width))
+		page.Write(encodeHybrid(indices, width))
+		return page.Bytes(), nil
+	}
+
+	plain, err := encodePlain(values)
+	if err != nil {
+		return nil, err
+	}
+
+	page.Write(plain)
+	return page.Bytes(), nil
+}
+
+// encodePlain encodes the values using the plain encoding
+func encodePlain(values []interface{}) ([]byte, error) {
+	out := bytes.NewBuffer(nil)
+	flags := make([]uint32, 0)
+	for _, v := range values {
+		switch v := v.(type) {
+		case []byte:
+			_ = binary.Write(out, binary.LittleEndian, uint32(len(v)))
+			out.Write(v)
+		case bool:
+			flag := uint32(0)
+			if v {
+				flag = 1
+			}
+			flags = append(flags, flag)
+		case int32, int64, float32, float64:
+			_ = binary.Write(out, binary.LittleEndian, v)
+		default:
+			return nil, fmt.Errorf("toparquet: unsupported value %T", v)
+		}
+	}
+
+	if len(flags) > 0 {
+		out.Write(packBits(flags, 1))
+	}
+	return out.Bytes(), nil
+}
+
+// encodeHybrid encodes the values using the RLE/bit-packing hybrid encoding. The runs of at least
+// 8 repeated values are run length encoded and the other values are bit-packed in groups of 8.
+func encodeHybrid(values []uint32, width int) []byte {
+	out := bytes.NewBuffer(nil)
+	packed := make([]uint32, 0, 64)
+	header := make([]byte, binary.MaxVarintLen64)
+	flush := func() {
+		if len(packed) == 0 {
+			return
+		}
+
+		out.Write(header[:binary.PutUvarint(header, uint64(len(packed)/8)<<1|1)])
+		out.Write(packBits(packed, width))
+		packed = packed[:0]
+	}
+
+	for i := 0; i < len(values); {
+		run := 1
+		for i+run < len(values) && values[i+run] == values[i] {
+			run++
+		}
+
+		if run >= 8 {
+			flush()
+			out.Write(header[:binary.PutUvarint(header, uint64(run)<<1)])
+			value := make([]byte, 4)
+			binary.LittleEndian.PutUint32(value, values[i])
+			out.Write(value[:(width+7)/8])
+			i += run
+			continue
+		}
+
+		until := i + 8
+		if until > len(values) {
+			until = len(values)
+		}
+
+		packed = append(packed, values[i:until]...)
+		i = until
+	}
+
+	// The last group is padded, the readers know the number of values
+	for len(packed)%8 != 0 {
+		packed = append(packed, 0)
+	}
+
+	flush()
+	return out.Bytes()
+}
+
+// packBits packs the values using the given number of bits, starting with the least significant bit
+func packBits(values []uint32, width int) []byte {
+	out := make([]byte, (len(values)*width+7)/8)
+	for i, v := range values {
+		for b := 0; b < width; b++ {
+			if v&(1<<uint(b)) != 0 {
+				bit := i*width + b
+				out[bit/8] |= 1 << uint(bit%8)
+			}
+		}
+	}
+	return out
+}
+
+// encodeThrift encodes a thrift structure using the compact protocol
+func encodeThrift(v interface {
+	Write(thrift.TProtocol) error
+}) ([]byte, error) {
+	out := bytes.NewBuffer(nil)
+	if err := v.Write(thrift.NewTCompactProtocol(&thrift.StreamTransport{Writer: out})); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// bitWidthOf returns the number of bits required to encode the value, at least one
+func bitWidthOf(max int) int {
+	if width := bits.Len32(uint32(max)); width > 0 {
+		return width
+	}
+	return 1
+}
+
+// sizeOfValue returns the plain encoded size of a value
+func sizeOfValue(v interface{}) int64 {
+	switch v := v.(type) {
+	case []byte:
+		return 4 + int64(len(v))
+	case int32, uint32, float32:
+		return 4
+	case int64, uint64, float64:
+		return 8
+	case bool:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// compareUint64 compares two unsigned integers
+func compareUint64(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// encodeBool encodes a boolean statistic value
+func encodeBool(v bool) []byte {
+	if v {
+		return []byte{1}
+	}
+	return []byte{0}
+}
@@ -0,0 +1,149 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package merge
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kelindar/talaria/internal/column"
+	"github.com/kelindar/talaria/internal/encoding/block"
+	"github.com/kelindar/talaria/internal/encoding/encoder"
+	"github.com/kelindar/talaria/internal/encoding/typeof"
+	"github.com/kelindar/talaria/internal/monitor/errors"
+)
+
+// Sorted creates a merge function which sorts the merged rows by a set of columns before
+// encoding them with the underlying merge function, so each file has tight min/max ranges.
+func Sorted(next Func, sortBy ...string) Func {
+	if len(sortBy) == 0 {
+		return next
+	}
+
+	return func(blocks []block.Block, schema typeof.Schema) ([]byte, error) {
+		if len(blocks) == 0 {
+			return next(blocks, schema)
+		}
+
+		sorted, err := sortBlocks(blocks, schema, sortBy)
+		if err != nil {
+			return nil, err
+		}
+
+		return next([]block.Block{sorted}, schema)
+	}
+}
+
+// sortBlocks merges the rows of multiple blocks into a single block, sorted by the columns
+func sortBlocks(blocks []block.Block, schema typeof.Schema, sortBy []string) (block.Block, error) {
+	var rows [][]interface{}
+	if err := encoder.RangeRows(blocks, schema, func(row []interface{}) error {
+		rows = append(rows, row)
+		return nil
+	}); err != nil {
+		return block.Block{}, err
+	}
+
+	// Find the indices of the sort columns, ignoring the ones which are not in the schema
+	names := schema.Columns()
+	var indices []int
+	for _, name := range sortBy {
+		if i := sort.SearchStrings(names, name); i < len(names) && names[i] == name {
+			indices = append(indices, i)
+		}
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, idx := range indices {
+			if c := compareValues(rows[i][idx], rows[j][idx]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+
+	// Write the sorted rows back into a single block
+	cols := column.MakeColumns(&schema)
+	for _, row := range rows {
+		for i, name := range names {
+			cols.Append(name, row[i], schema[name])
+		}
+	}
+
+	output, err := block.FromColumns(string(blocks[0].Key), cols)
+	if err != nil {
+		return block.Block{}, errors.Internal("merge: unable to sort blocks", err)
+	}
+
+	for _, b := range blocks {
+		if b.Expires > output.Expires {
+			output.Expires = b.Expires
+		}
+	}
+	return output, nil
+}
+
+// compareValues compares two values of a column, nulls are sorted first
+func compareValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	switch x := a.(type) {
+	case int32:
+		if y, ok := b.(int32); ok {
+			return compareInt64(int64(x), int64(y))
+		}
+	case int64:
+		if y, ok := b.(int64); ok {
+			return compareInt64(x, y)
+		}
+	case float64:
+		if y, ok := b.(float64); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	case bool:
+		if y, ok := b.(bool); ok && x != y {
+			if y {
+				return -1
+			}
+			return 1
+		}
+		return 0
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return compareInt64(x.UnixNano(), y.UnixNano())
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y)
+		}
+	}
+
+	return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+}
+
+// compareInt64 compares two integers
+func compareInt64(x, y int64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,69 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package merge
+
+import (
+	"testing"
+
+	"github.com/kelindar/talaria/internal/column"
+	"github.com/kelindar/talaria/internal/encoding/block"
+	"github.com/kelindar/talaria/internal/encoding/encoder"
+	"github.com/kelindar/talaria/internal/encoding/typeof"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSorted(t *testing.T) {
+	schema := typeof.Schema{
+		"col0": typeof.String,
+		"col1": typeof.Int64,
+		"col2": typeof.Float64,
+	}
+
+	var blocks []block.Block
+	for _, values := range [][][]interface{}{
+		{{"b", int64(2), 1.0}, {"a", int64(3), 2.0}},
+		{{"a", int64(1), 3.0}, {"b", int64(2), 0.5}, {"c", nil, 4.0}},
+	} {
+		cols := column.MakeColumns(&schema)
+		for _, v := range values {
+			cols.Append("col0", v[0], typeof.String)
+			cols.Append("col1", v[1], typeof.Int64)
+			cols.Append("col2", v[2], typeof.Float64)
+		}
+
+		blk, err := block.FromColumns("test", cols)
+		assert.NoError(t, err)
+		blocks = append(blocks, blk)
+	}
+
+	var rows [][]interface{}
+	capture := func(blocks []block.Block, schema typeof.Schema) ([]byte, error) {
+		assert.Len(t, blocks, 1)
+		return nil, encoder.RangeRows(blocks, schema, func(row []interface{}) error {
+			rows = append(rows, row)
+			return nil
+		})
+	}
+
+	// Sort by the primary column and then by the secondary one
+	_, err := Sorted(capture, "col1", "unknown", "col2")(blocks, schema)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]interface{}{
+		{"c", nil, 4.0},
+		{"a", int64(1), 3.0},
+		{"b", int64(2), 0.5},
+		{"b", int64(2), 1.0},
+		{"a", int64(3), 2.0},
+	}, rows)
+}
+
+func TestCompareValues(t *testing.T) {
+	assert.Equal(t, 0, compareValues(nil, nil))
+	assert.Equal(t, -1, compareValues(nil, int32(1)))
+	assert.Equal(t, 1, compareValues(int32(1), nil))
+	assert.Equal(t, -1, compareValues(int32(1), int32(2)))
+	assert.Equal(t, 1, compareValues("b", "a"))
+	assert.Equal(t, -1, compareValues(false, true))
+	assert.Equal(t, 0, compareValues(true, true))
+}
@@ -0,0 +1,220 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package prometheus
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// The buckets of the histograms, timings are in seconds while other values span several orders of magnitude
+var (
+	timingBuckets = prometheus.DefBuckets
+	valueBuckets  = prometheus.ExponentialBuckets(1, 10, 12)
+)
+
+// Client is a statsd client which keeps the metrics in memory so that they can be scraped by
+// Prometheus. Each metric is registered as a vector whose labels are the ones of its first
+// observation, the tags being either "key:value" pairs or a single label with an empty value.
+type Client struct {
+	lock     sync.Mutex
+	metrics  map[string]*metric   // The metrics, by name
+	registry *prometheus.Registry // The registry with the metrics and the runtime collectors
+}
+
+// New creates a new prometheus client.
+func New() *Client {
+	c := &Client{
+		metrics:  make(map[string]*metric),
+		registry: prometheus.NewRegistry(),
+	}
+
+	c.registry.MustRegister(prometheus.NewGoCollector())
+	c.registry.MustRegister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+	return c
+}
+
+// Handler returns the HTTP handler which exposes the metrics.
+func (c *Client) Handler() http.Handler {
+	return promhttp.HandlerFor(c.registry, promhttp.HandlerOpts{})
+}
+
+// Timing records a duration in a histogram, in seconds
+func (c *Client) Timing(name string, value time.Duration, tags []string, rate float64) error {
+	m, labels, err := c.metricOf(name+"_seconds", name, kindHistogram, tags)
+	if err != nil {
+		return err
+	}
+
+	m.histogram.With(labels).Observe(value.Seconds())
+	return nil
+}
+
+// Gauge sets the value of a gauge
+func (c *Client) Gauge(name string, value float64, tags []string, rate float64) error {
+	m, labels, err := c.metricOf(name, name, kindGauge, tags)
+	if err != nil {
+		return err
+	}
+
+	m.gauge.With(labels).Set(value)
+	return nil
+}
+
+// Histogram records a value in a histogram
+func (c *Client) Histogram(name string, value float64, tags []string, rate float64) error {
+	m, labels, err := c.metricOf(name, name, kindValues, tags)
+	if err != nil {
+		return err
+	}
+
+	m.histogram.With(labels).Observe(value)
+	return nil
+}
+
+// Count adds a value to a counter, scaled up if the value is sampled
+func (c *Client) Count(name string, value int64, tags []string, rate float64) error {
+	if value < 0 {
+		return fmt.Errorf("prometheus: counter %s can not be decreased", name)
+	}
+
+	m, labels, err := c.metricOf(name+"_total", name, kindCounter, tags)
+	if err != nil {
+		return err
+	}
+
+	if rate > 0 && rate < 1 {
+		m.counter.With(labels).Add(float64(value) / rate)
+		return nil
+	}
+
+	m.counter.With(labels).Add(float64(value))
+	return nil
+}
+
+// metricOf returns the metric along with the labels of an observation, every label of the metric
+// which is missing from the tags being empty. The metric is registered on its first observation.
+func (c *Client) metricOf(name, help string, kind valueKind, tags []string) (*metric, prometheus.Labels, error) {
+	name = sanitize(name)
+	labels := labelsOf(tags)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	m, ok := c.metrics[name]
+	if !ok {
+		var err error
+		if m, err = c.register(name, help, kind, labels); err != nil {
+			return nil, nil, err
+		}
+		c.metrics[name] = m
+	}
+
+	if m.kind != kind {
+		return nil, nil, fmt.Errorf("prometheus: metric %s is already registered with another type", name)
+	}
+
+	for k := range labels {
+		if !m.labels[k] {
+			return nil, nil, fmt.Errorf("prometheus: metric %s is registered without the label %s", name, k)
+		}
+	}
+
+	for k := range m.labels {
+		if _, ok := labels[k]; !ok {
+			labels[k] = ""
+		}
+	}
+	return m, labels, nil
+}
+
+// register registers a new metric with the labels of its first observation
+func (c *Client) register(name, help string, kind valueKind, labels prometheus.Labels) (*metric, error) {
+	m := &metric{kind: kind, labels: make(map[string]bool, len(labels))}
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		m.labels[k] = true
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var collector prometheus.Collector
+	switch kind {
+	case kindCounter:
+		m.counter = prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, names)
+		collector = m.counter
+	case kindGauge:
+		m.gauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: help}, names)
+		collector = m.gauge
+	case kindHistogram:
+		m.histogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: name, Help: help, Buckets: timingBuckets}, names)
+		collector = m.histogram
+	case kindValues:
+		m.histogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: name, Help: help, Buckets: valueBuckets}, names)
+		collector = m.histogram
+	}
+
+	if err := c.registry.Register(collector); err != nil {
+		return nil, fmt.Errorf("prometheus: unable to register metric %s: %v", name, err)
+	}
+	return m, nil
+}
+
+// ------------------------------------------------------------------------------------------------------------
+
+type valueKind int
+
+const (
+	kindCounter   valueKind = iota // A counter
+	kindGauge                      // A gauge
+	kindHistogram                  // A histogram of durations
+	kindValues                     // A histogram of values
+)
+
+// metric represents a registered vector of a metric
+type metric struct {
+	kind      valueKind                // The type of the metric
+	labels    map[string]bool          // The labels of the metric
+	counter   *prometheus.CounterVec   // The vector of a counter
+	gauge     *prometheus.GaugeVec     // The vector of a gauge
+	histogram *prometheus.HistogramVec // The vector of a histogram
+}
+
+// labelsOf converts the tags to labels
+func labelsOf(tags []string) prometheus.Labels {
+	labels := make(prometheus.Labels, len(tags))
+	for _, tag := range tags {
+		if i := strings.IndexByte(tag, ':'); i >= 0 {
+			labels[sanitize(tag[:i])] = tag[i+1:]
+			continue
+		}
+		labels[sanitize(tag)] = ""
+	}
+	return labels
+}
+
+// sanitize replaces the characters which are not allowed in the names of the metrics and labels,
+// which also can not start with a digit
+func sanitize(name string) string {
+	out := []byte(name)
+	for i, c := range out {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '_':
+		default:
+			out[i] = '_'
+		}
+	}
+
+	if len(out) == 0 || (out[0] >= '0' && out[0] <= '9') {
+		return "_" + string(out)
+	}
+	return string(out)
+}
@@ -0,0 +1,56 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package prometheus
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kelindar/talaria/internal/monitor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImplements(t *testing.T) {
+	assert.Implements(t, (*monitor.StatsdClient)(nil), New())
+}
+
+func TestPrometheus(t *testing.T) {
+	c := New()
+	assert.NoError(t, c.Count("server.events.ingest.count", 2, []string{"app_name:talaria", "env:test"}, 1))
+	assert.NoError(t, c.Count("server.events.ingest.count", 3, []string{"app_name:talaria", "env:test"}, 1))
+	assert.NoError(t, c.Count("limit.exceeded", 1, []string{"type:client", "name:producer"}, 1))
+	assert.NoError(t, c.Count("limit.exceeded", 1, []string{"type:table"}, 1))
+	assert.NoError(t, c.Gauge("scheduler.queued", 4, nil, 1))
+	assert.NoError(t, c.Timing("scheduler.scan", 300*time.Millisecond, []string{"table:events"}, 1))
+	assert.NoError(t, c.Histogram("compaction.deletelatency", 150, nil, 1))
+
+	// A metric can not change its type, gain labels or be decreased
+	assert.Error(t, c.Histogram("scheduler.queued", 1, nil, 1))
+	assert.Error(t, c.Gauge("scheduler.queued", 1, []string{"table:events"}, 1))
+	assert.Error(t, c.Count("limit.exceeded", -1, nil, 1))
+
+	rec := httptest.NewRecorder()
+	c.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := ioutil.ReadAll(rec.Body)
+	assert.NoError(t, err)
+
+	out := string(body)
+	assert.Contains(t, out, `server_events_ingest_count_total{app_name="talaria",env="test"} 5`)
+	assert.Contains(t, out, `limit_exceeded_total{name="producer",type="client"} 1`)
+	assert.Contains(t, out, `limit_exceeded_total{name="",type="table"} 1`)
+	assert.Contains(t, out, `scheduler_queued 4`)
+	assert.Contains(t, out, `scheduler_scan_seconds_bucket{table="events",le="0.25"} 0`)
+	assert.Contains(t, out, `scheduler_scan_seconds_bucket{table="events",le="0.5"} 1`)
+	assert.Contains(t, out, `scheduler_scan_seconds_count{table="events"} 1`)
+	assert.Contains(t, out, `compaction_deletelatency_bucket{le="1000"} 1`)
+	assert.Contains(t, out, `compaction_deletelatency_sum 150`)
+	assert.Contains(t, out, `go_goroutines`)
+}
+
+func TestSanitize(t *testing.T) {
+	assert.Equal(t, "server_events_ingest_count", sanitize("server.events.ingest.count"))
+	assert.Equal(t, "_1st_table", sanitize("1st-table"))
+}
@@ -0,0 +1,261 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kelindar/talaria/internal/monitor/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const exportTimeout = 10 * time.Second
+
+// Exporter exports the spans to an OpenTelemetry collector, using the JSON encoding of OTLP/HTTP. The
+// otlptracehttp and otlptracegrpc exporters are not used since they require grpc 1.46 or later, along
+// with newer protobuf and genproto modules than the ones the generated services are built with. It
+// should be replaced by otlptracehttp once grpc is upgraded.
+type Exporter struct {
+	endpoint string            // The URL of the traces endpoint
+	headers  map[string]string // The headers to send along with the spans
+	client   *http.Client      // The HTTP client
+}
+
+// NewExporter creates a new OTLP/HTTP exporter.
+func NewExporter(endpoint string, headers map[string]string) *Exporter {
+	return &Exporter{
+		endpoint: endpoint,
+		headers:  headers,
+		client:   &http.Client{Timeout: exportTimeout},
+	}
+}
+
+// ExportSpans sends a batch of spans to the collector.
+func (e *Exporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(encodeSpans(spans))
+	if err != nil {
+		return errors.Internal("tracing: unable to encode the spans", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errors.Internal("tracing: unable to create the request", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return errors.Internal("tracing: unable to export the spans", err)
+	}
+
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Newf("tracing: unable to export the spans, collector responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// Shutdown shuts down the exporter.
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// ------------------------------------------------------------------------------------------------------------
+
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resourceOf   `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resourceOf struct {
+	Attributes []keyValue `json:"attributes,omitempty"`
+}
+
+type scopeSpans struct {
+	Scope scope  `json:"scope"`
+	Spans []span `json:"spans"`
+}
+
+type scope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type span struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Events            []event    `json:"events,omitempty"`
+	Status            status     `json:"status"`
+}
+
+type event struct {
+	TimeUnixNano string     `json:"timeUnixNano"`
+	Name         string     `json:"name"`
+	Attributes   []keyValue `json:"attributes,omitempty"`
+}
+
+type status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string     `json:"stringValue,omitempty"`
+	BoolValue   *bool       `json:"boolValue,omitempty"`
+	IntValue    *string     `json:"intValue,omitempty"`
+	DoubleValue *float64    `json:"doubleValue,omitempty"`
+	ArrayValue  *arrayValue `json:"arrayValue,omitempty"`
+}
+
+type arrayValue struct {
+	Values []anyValue `json:"values"`
+}
+
+// encodeSpans groups the spans by resource and by instrumentation scope
+func encodeSpans(spans []sdktrace.ReadOnlySpan) exportRequest {
+	var out exportRequest
+	resources := make(map[string]int)
+	scopes := make(map[[2]string]int)
+	for _, s := range spans {
+		res := s.Resource().String()
+		ri, ok := resources[res]
+		if !ok {
+			ri = len(out.ResourceSpans)
+			resources[res] = ri
+			out.ResourceSpans = append(out.ResourceSpans, resourceSpans{
+				Resource: resourceOf{Attributes: encodeAttributes(s.Resource().Attributes())},
+			})
+		}
+
+		sc := s.InstrumentationScope()
+		si, ok := scopes[[2]string{res, sc.Name}]
+		if !ok {
+			si = len(out.ResourceSpans[ri].ScopeSpans)
+			scopes[[2]string{res, sc.Name}] = si
+			out.ResourceSpans[ri].ScopeSpans = append(out.ResourceSpans[ri].ScopeSpans, scopeSpans{
+				Scope: scope{Name: sc.Name, Version: sc.Version},
+			})
+		}
+
+		out.ResourceSpans[ri].ScopeSpans[si].Spans = append(out.ResourceSpans[ri].ScopeSpans[si].Spans, encodeSpan(s))
+	}
+	return out
+}
+
+// encodeSpan encodes a single span
+func encodeSpan(s sdktrace.ReadOnlySpan) span {
+	out := span{
+		TraceID:           s.SpanContext().TraceID().String(),
+		SpanID:            s.SpanContext().SpanID().String(),
+		Name:              s.Name(),
+		Kind:              int(s.SpanKind()),
+		StartTimeUnixNano: strconv.FormatInt(s.StartTime().UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.EndTime().UnixNano(), 10),
+		Attributes:        encodeAttributes(s.Attributes()),
+		Status:            status{Message: s.Status().Description},
+	}
+
+	if parent := s.Parent(); parent.HasSpanID() {
+		out.ParentSpanID = parent.SpanID().String()
+	}
+
+	// The status codes of OTLP are ordered differently
+	switch s.Status().Code {
+	case codes.Ok:
+		out.Status.Code = 1
+	case codes.Error:
+		out.Status.Code = 2
+	}
+
+	for _, e := range s.Events() {
+		out.Events = append(out.Events, event{
+			TimeUnixNano: strconv.FormatInt(e.Time.UnixNano(), 10),
+			Name:         e.Name,
+			Attributes:   encodeAttributes(e.Attributes),
+		})
+	}
+	return out
+}
+
+// encodeAttributes encodes a set of attributes
+func encodeAttributes(attrs []attribute.KeyValue) []keyValue {
+	out := make([]keyValue, 0, len(attrs))
+	for _, kv := range attrs {
+		out = append(out, keyValue{Key: string(kv.Key), Value: encodeValue(kv.Value)})
+	}
+	return out
+}
+
+// encodeValue encodes the value of an attribute
+func encodeValue(v attribute.Value) (out anyValue) {
+	switch v.Type() {
+	case attribute.BOOL:
+		b := v.AsBool()
+		out.BoolValue = &b
+	case attribute.INT64:
+		i := strconv.FormatInt(v.AsInt64(), 10)
+		out.IntValue = &i
+	case attribute.FLOAT64:
+		f := v.AsFloat64()
+		out.DoubleValue = &f
+	case attribute.BOOLSLICE:
+		out.ArrayValue = new(arrayValue)
+		for _, b := range v.AsBoolSlice() {
+			out.ArrayValue.Values = append(out.ArrayValue.Values, encodeValue(attribute.BoolValue(b)))
+		}
+	case attribute.INT64SLICE:
+		out.ArrayValue = new(arrayValue)
+		for _, i := range v.AsInt64Slice() {
+			out.ArrayValue.Values = append(out.ArrayValue.Values, encodeValue(attribute.Int64Value(i)))
+		}
+	case attribute.FLOAT64SLICE:
+		out.ArrayValue = new(arrayValue)
+		for _, f := range v.AsFloat64Slice() {
+			out.ArrayValue.Values = append(out.ArrayValue.Values, encodeValue(attribute.Float64Value(f)))
+		}
+	case attribute.STRINGSLICE:
+		out.ArrayValue = new(arrayValue)
+		for _, s := range v.AsStringSlice() {
+			out.ArrayValue.Values = append(out.ArrayValue.Values, encodeValue(attribute.StringValue(s)))
+		}
+	default:
+		s := v.Emit()
+		out.StringValue = &s
+	}
+	return
+}
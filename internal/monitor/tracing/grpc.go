@@ -0,0 +1,87 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// ServerOptions returns the gRPC server options which start a span for every call, continuing
+// the trace propagated by the client in the metadata of the request.
+func ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryInterceptor),
+		grpc.ChainStreamInterceptor(streamInterceptor),
+	}
+}
+
+// unaryInterceptor traces the unary calls
+func unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ interface{}, err error) {
+	ctx, span := startServer(ctx, info.FullMethod)
+	defer func() { End(span, err) }()
+	return handler(ctx, req)
+}
+
+// streamInterceptor traces the streaming calls
+func streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	ctx, span := startServer(ss.Context(), info.FullMethod)
+	defer func() { End(span, err) }()
+	return handler(srv, &tracedStream{ServerStream: ss, ctx: ctx})
+}
+
+// startServer starts a server span, as a child of the span propagated in the metadata
+func startServer(ctx context.Context, method string) (context.Context, trace.Span) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, carrier(md))
+	}
+
+	return otel.Tracer(instrumentation).Start(ctx, method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.String("rpc.system", "grpc"), attribute.String("rpc.method", method)),
+	)
+}
+
+// tracedStream replaces the context of a server stream
+type tracedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context of the stream, along with the span
+func (s *tracedStream) Context() context.Context {
+	return s.ctx
+}
+
+// ------------------------------------------------------------------------------------------------------------
+
+// carrier adapts the gRPC metadata to the propagators
+type carrier metadata.MD
+
+// Get returns the first value of a key
+func (c carrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// Set sets the value of a key
+func (c carrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+// Keys returns the keys of the metadata
+func (c carrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,69 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package tracing
+
+import (
+	"context"
+
+	"github.com/kelindar/talaria/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentation = "github.com/kelindar/talaria"
+
+// Setup configures the global tracer provider which exports the spans to the configured OTLP
+// endpoint. If tracing is not configured, the default no-op provider is kept. The returned
+// function flushes the pending spans and shuts down the provider.
+func Setup(conf *config.Tracing, appName, env string) func(context.Context) error {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+
+	if conf == nil || conf.Endpoint == "" {
+		return func(context.Context) error { return nil }
+	}
+
+	ratio := conf.Ratio
+	if ratio <= 0 {
+		ratio = 1
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(NewExporter(conf.Endpoint, conf.Headers)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceName(appName),
+			semconv.DeploymentEnvironment(env),
+		)),
+	)
+
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown
+}
+
+// Start starts a new span as a child of the span in the context, if any.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records the error on the span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Table returns the attribute for the name of a table
+func Table(name string) attribute.KeyValue {
+	return attribute.String("table", name)
+}
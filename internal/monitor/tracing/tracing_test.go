@@ -0,0 +1,115 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kelindar/talaria/internal/config"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestSetup_Noop(t *testing.T) {
+	shutdown := Setup(nil, "talaria", "test")
+	assert.NoError(t, shutdown(context.Background()))
+
+	shutdown = Setup(&config.Tracing{}, "talaria", "test")
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestExporter(t *testing.T) {
+	var body exportRequest
+	var apiKey string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		assert.NoError(t, json.Unmarshal(b, &body))
+		apiKey = r.Header.Get("x-api-key")
+	}))
+	defer collector.Close()
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(NewExporter(collector.URL, map[string]string{"x-api-key": "secret"})))
+	ctx, parent := provider.Tracer(instrumentation).Start(context.Background(), "ingest")
+	_, child := provider.Tracer(instrumentation).Start(ctx, "ingest.append", trace.WithAttributes(
+		Table("events"), attribute.Int("blocks", 2), attribute.StringSlice("tags", []string{"a"}),
+	))
+	End(child, errors.New("boom"))
+	assert.NoError(t, provider.Shutdown(context.Background()))
+
+	assert.Equal(t, "secret", apiKey)
+	assert.Len(t, body.ResourceSpans, 1)
+	assert.Len(t, body.ResourceSpans[0].ScopeSpans, 1)
+	assert.Equal(t, instrumentation, body.ResourceSpans[0].ScopeSpans[0].Scope.Name)
+
+	spans := body.ResourceSpans[0].ScopeSpans[0].Spans
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "ingest.append", spans[0].Name)
+	assert.Equal(t, parent.SpanContext().TraceID().String(), spans[0].TraceID)
+	assert.Equal(t, parent.SpanContext().SpanID().String(), spans[0].ParentSpanID)
+	assert.Equal(t, 2, spans[0].Status.Code)
+	assert.Equal(t, "boom", spans[0].Status.Message)
+	assert.Len(t, spans[0].Events, 1)
+	assert.Equal(t, "exception", spans[0].Events[0].Name)
+
+	assert.Len(t, spans[0].Attributes, 3)
+	assert.Equal(t, "events", *spans[0].Attributes[0].Value.StringValue)
+	assert.Equal(t, "2", *spans[0].Attributes[1].Value.IntValue)
+	assert.Equal(t, "a", *spans[0].Attributes[2].Value.ArrayValue.Values[0].StringValue)
+}
+
+func TestExporter_Rejected(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer collector.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	_, span := provider.Tracer(instrumentation).Start(context.Background(), "query.scan")
+	span.End()
+
+	err := NewExporter(collector.URL, nil).ExportSpans(context.Background(), recorder.Ended())
+	assert.Error(t, err)
+}
+
+func TestServerOptions(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+	Setup(nil, "talaria", "test")
+
+	// The trace of the client is propagated in the metadata
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"traceparent", "00-"+traceID+"-00f067aa0ba902b7-01",
+	))
+
+	var inner trace.SpanContext
+	_, err := unaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/talaria.Ingress/Ingest"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			inner = trace.SpanContextFromContext(ctx)
+			return nil, nil
+		})
+	assert.NoError(t, err)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "/talaria.Ingress/Ingest", spans[0].Name())
+	assert.Equal(t, trace.SpanKindServer, spans[0].SpanKind())
+	assert.Equal(t, traceID, spans[0].SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+	assert.Equal(t, spans[0].SpanContext().SpanID(), inner.SpanID())
+}
@@ -130,6 +130,12 @@ func toTime(t int64, ok bool) time.Time {
 		return time.Unix(t, 0)
 	}
 
+	return TimeOf(t)
+}
+
+// TimeOf converts a unix time to a golang time, the unit of the time (seconds, milliseconds, microseconds
+// or nanoseconds) being inferred from its magnitude.
+func TimeOf(t int64) time.Time {
 	watermark := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
 	switch {
 	case t > watermark.UnixNano():
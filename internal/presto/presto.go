@@ -5,6 +5,7 @@ package presto
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/rpc"
@@ -31,20 +32,24 @@ type Column interface {
 	At(index int) interface{}
 }
 
-// Serve creates and serves thrift RPC for presto. Context is used for cancellation purposes.
-func Serve(ctx context.Context, port int32, service PrestoThriftService) error {
-	if err := rpc.RegisterName("Thrift", &PrestoThriftServiceServer{
-		Implementation: service,
-	}); err != nil {
-		return err
-	}
+// ServiceFunc creates the service of a connection, given the TLS state of the connection if served over TLS.
+// The connection is closed if the service can not be created, for example if the client is not authenticated.
+type ServiceFunc func(ctx context.Context, state *tls.ConnectionState) (PrestoThriftService, error)
 
+// Serve creates and serves thrift RPC for presto. Context is used for cancellation purposes. If
+// the TLS configuration is specified, the connections are served over TLS.
+func Serve(ctx context.Context, port int32, tlsConfig *tls.Config, newService ServiceFunc) error {
 	// Create a TCP listener for our thrift
 	ln, err := net.Listen("tcp", fmt.Sprintf(":%v", port))
 	if err != nil {
 		return err
 	}
 
+	// Only accept TLS connections, if configured
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+
 	// Close the listener if context is cancelled
 	go func() {
 		<-ctx.Done()
@@ -58,13 +63,67 @@ func Serve(ctx context.Context, port int32, service PrestoThriftService) error {
 			return nil
 		default:
 			if conn, err := ln.Accept(); err == nil {
-				t := thrift.NewTransport(thrift.NewFramedReadWriteCloser(conn, frameSize), thrift.BinaryProtocol)
-				go ServeConn(t)
+				go serveConn(ctx, conn, newService)
 			}
 		}
 	}
 }
 
+// serveConn serves a connection with its own service. The context of the service is cancelled once the
+// connection can no longer be read, for example once the client has closed it, which stops its requests.
+func serveConn(ctx context.Context, conn net.Conn, newService ServiceFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var state *tls.ConnectionState
+	if c, ok := conn.(*tls.Conn); ok {
+		if err := c.Handshake(); err != nil {
+			_ = conn.Close()
+			return
+		}
+
+		s := c.ConnectionState()
+		state = &s
+	}
+
+	service, err := newService(ctx, state)
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("Thrift", &PrestoThriftServiceServer{
+		Implementation: service,
+	}); err != nil {
+		_ = conn.Close()
+		return
+	}
+
+	t := thrift.NewTransport(thrift.NewFramedReadWriteCloser(conn, frameSize), thrift.BinaryProtocol)
+	server.ServeCodec(&cancelCodec{
+		ServerCodec: NewServerCodec(t),
+		cancel:      cancel,
+	})
+}
+
+// cancelCodec represents a codec which cancels the context of a connection once a request can not be read.
+// Requests are served concurrently, so the next request is read while the previous ones are still running.
+type cancelCodec struct {
+	rpc.ServerCodec
+	cancel context.CancelFunc
+}
+
+// ReadRequestHeader reads the header of the next request, and cancels the context of the connection if
+// it can not be read.
+func (c *cancelCodec) ReadRequestHeader(request *rpc.Request) error {
+	err := c.ServerCodec.ReadRequestHeader(request)
+	if err != nil {
+		c.cancel()
+	}
+	return err
+}
+
 // ------------------------------------------------------------------------------------------------------------
 
 // Size returns the size of the block.
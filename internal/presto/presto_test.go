@@ -5,7 +5,9 @@ package presto
 
 import (
 	"context"
+	"crypto/tls"
 	"math"
+	"net"
 	"testing"
 	"time"
 
@@ -16,10 +18,28 @@ func TestServeCancellation(t *testing.T) {
 	assert.NotPanics(t, func() {
 		ctx, cancel := context.WithCancel(context.Background())
 		cancel()
-		_ = Serve(ctx, 9999, nil)
+		_ = Serve(ctx, 9999, nil, nil)
 	})
 }
 
+func TestServeConn_Closed(t *testing.T) {
+	server, client := net.Pipe()
+	served := make(chan context.Context, 1)
+	go serveConn(context.Background(), server, func(ctx context.Context, _ *tls.ConnectionState) (PrestoThriftService, error) {
+		served <- ctx
+		return nil, nil
+	})
+
+	// The context of the connection is cancelled once the client closes it
+	ctx := <-served
+	assert.NoError(t, client.Close())
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		assert.Fail(t, "context of the connection was not cancelled")
+	}
+}
+
 func Test_toTime(t *testing.T) {
 	tests := []struct {
 		input  int64
@@ -0,0 +1,283 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"net/http"
+	"strings"
+
+	"github.com/kelindar/talaria/internal/config"
+	"github.com/kelindar/talaria/internal/monitor/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Permission represents an operation on a table
+type Permission int
+
+// Various permissions
+const (
+	Read Permission = iota
+	Write
+)
+
+const (
+	headerAuthorization = "authorization"
+	headerAPIKey        = "x-api-key"
+	any                 = "*"
+)
+
+// Internal is the principal of the requests which originate from the server itself, such as the
+// ingestion from S3/SQS, it can read and write every table.
+var Internal = &Principal{
+	Name:  "internal",
+	read:  map[string]bool{any: true},
+	write: map[string]bool{any: true},
+}
+
+// Principal represents an authenticated client along with its permissions.
+type Principal struct {
+	Name  string          // The name of the principal
+	token string          // The bearer token or API key
+	read  map[string]bool // The tables which can be read
+	write map[string]bool // The tables which can be written
+}
+
+// Can returns whether the principal has a permission on a table
+func (p *Principal) Can(permission Permission, table string) bool {
+	tables := p.read
+	if permission == Write {
+		tables = p.write
+	}
+	return tables[any] || tables[table]
+}
+
+// ------------------------------------------------------------------------------------------------------------
+
+// Authenticator authenticates the requests with a bearer token, an API key or a client certificate
+// and authorizes the operations on the tables. A nil authenticator allows every request.
+type Authenticator struct {
+	tls        *tls.Config           // The TLS configuration of the listeners
+	principals []*Principal          // The principals which can be authenticated
+	byName     map[string]*Principal // The principals by their name
+}
+
+// New creates a new authenticator, or returns nil if no authentication is configured.
+func New(conf *config.Auth) (*Authenticator, error) {
+	if conf == nil {
+		return nil, nil
+	}
+
+	a := &Authenticator{byName: make(map[string]*Principal, len(conf.Principals))}
+	for _, c := range conf.Principals {
+		p := &Principal{
+			Name:  c.Name,
+			token: c.Token,
+			read:  setOf(c.Read),
+			write: setOf(c.Write),
+		}
+
+		if _, ok := a.byName[p.Name]; ok || p.Name == "" {
+			return nil, errors.Newf("auth: principal name '%s' must be unique and not empty", p.Name)
+		}
+
+		a.byName[p.Name] = p
+		a.principals = append(a.principals, p)
+	}
+
+	var err error
+	if a.tls, err = NewTLS(conf.TLS); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// TLS returns the TLS configuration of the listeners, or nil if TLS is not configured
+func (a *Authenticator) TLS() *tls.Config {
+	if a == nil {
+		return nil
+	}
+	return a.tls
+}
+
+// ServerOptions returns the options of a gRPC server, which authenticate every call
+func (a *Authenticator) ServerOptions() []grpc.ServerOption {
+	if !a.Enabled() {
+		return nil
+	}
+
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(a.unaryInterceptor),
+		grpc.StreamInterceptor(a.streamInterceptor),
+	}
+}
+
+// Middleware authenticates every HTTP request
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	if !a.Enabled() {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := a.identify(tokenOf(r.Header.Get(headerAuthorization), r.Header.Get(headerAPIKey)), r.TLS)
+		if !ok {
+			http.Error(w, "unauthenticated", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// Authorize checks whether the principal of the request has a permission on a table
+func (a *Authenticator) Authorize(ctx context.Context, permission Permission, table string) error {
+	if !a.Enabled() {
+		return nil
+	}
+
+	principal, ok := PrincipalOf(ctx)
+	switch {
+	case !ok:
+		return errors.Unauthenticated("auth: the request is not authenticated")
+	case !principal.Can(permission, table):
+		return errors.PermissionDenied("auth: principal " + principal.Name + " is not allowed to access table " + table)
+	default:
+		return nil
+	}
+}
+
+// Enabled returns whether the requests need to be authenticated
+func (a *Authenticator) Enabled() bool {
+	return a != nil && len(a.principals) > 0
+}
+
+// Authenticate identifies the principal of a gRPC call and attaches it to the context
+func (a *Authenticator) Authenticate(ctx context.Context) (context.Context, error) {
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		token = tokenOf(first(md.Get(headerAuthorization)), first(md.Get(headerAPIKey)))
+	}
+
+	var state *tls.ConnectionState
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			state = &info.State
+		}
+	}
+
+	principal, ok := a.identify(token, state)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "auth: the request is not authenticated")
+	}
+	return WithPrincipal(ctx, principal), nil
+}
+
+// AuthenticateConn identifies the principal of a connection by its verified client certificate, for the
+// protocols which carry no credentials in their requests such as thrift, and attaches it to the context
+func (a *Authenticator) AuthenticateConn(ctx context.Context, state *tls.ConnectionState) (context.Context, error) {
+	if !a.Enabled() {
+		return ctx, nil
+	}
+
+	principal, ok := a.identify("", state)
+	if !ok {
+		return nil, errors.Unauthenticated("auth: the connection is not authenticated with a client certificate")
+	}
+	return WithPrincipal(ctx, principal), nil
+}
+
+// identify finds the principal by its token or by the common name of its verified client certificate
+func (a *Authenticator) identify(token string, state *tls.ConnectionState) (*Principal, bool) {
+	if token != "" {
+		for _, p := range a.principals {
+			if p.token != "" && subtle.ConstantTimeCompare([]byte(p.token), []byte(token)) == 1 {
+				return p, true
+			}
+		}
+		return nil, false
+	}
+
+	if state != nil && len(state.VerifiedChains) > 0 && len(state.PeerCertificates) > 0 {
+		p, ok := a.byName[state.PeerCertificates[0].Subject.CommonName]
+		return p, ok
+	}
+	return nil, false
+}
+
+// unaryInterceptor authenticates a unary gRPC call
+func (a *Authenticator) unaryInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := a.Authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// streamInterceptor authenticates a streaming gRPC call
+func (a *Authenticator) streamInterceptor(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.Authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticatedStream represents a server stream with the principal attached to its context
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context of the stream
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// ------------------------------------------------------------------------------------------------------------
+
+type principalKey struct{}
+
+// WithPrincipal attaches a principal to a context
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalOf returns the principal attached to a context
+func PrincipalOf(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// tokenOf returns the bearer token of an authorization header, or the API key
+func tokenOf(authorization, apiKey string) string {
+	const prefix = "bearer "
+	if len(authorization) > len(prefix) && strings.EqualFold(authorization[:len(prefix)], prefix) {
+		return strings.TrimSpace(authorization[len(prefix):])
+	}
+	return strings.TrimSpace(apiKey)
+}
+
+// first returns the first value of a metadata key
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// setOf converts a list of tables to a set
+func setOf(tables []string) map[string]bool {
+	out := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		out[t] = true
+	}
+	return out
+}
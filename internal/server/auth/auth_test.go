@@ -0,0 +1,140 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kelindar/talaria/internal/config"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func newTestAuth(t *testing.T) *Authenticator {
+	a, err := New(&config.Auth{
+		Principals: []config.Principal{
+			{Name: "reader", Token: "r-token", Read: []string{"events"}},
+			{Name: "writer", Token: "w-token", Read: []string{"*"}, Write: []string{"events"}},
+		},
+	})
+	assert.NoError(t, err)
+	return a
+}
+
+func TestNew(t *testing.T) {
+	a, err := New(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, a)
+	assert.Nil(t, a.TLS())
+	assert.Nil(t, a.ServerOptions())
+	assert.NoError(t, a.Authorize(context.Background(), Write, "events"))
+
+	_, err = New(&config.Auth{Principals: []config.Principal{{Name: "a"}, {Name: "a"}}})
+	assert.Error(t, err)
+
+	_, err = New(&config.Auth{TLS: &config.TLS{Cert: "missing.pem", Key: "missing.key"}})
+	assert.Error(t, err)
+}
+
+func TestAuthorize(t *testing.T) {
+	a := newTestAuth(t)
+	reader := WithPrincipal(context.Background(), a.byName["reader"])
+	writer := WithPrincipal(context.Background(), a.byName["writer"])
+
+	assert.Error(t, a.Authorize(context.Background(), Read, "events"))
+	assert.NoError(t, a.Authorize(reader, Read, "events"))
+	assert.Error(t, a.Authorize(reader, Read, "logs"))
+	assert.Error(t, a.Authorize(reader, Write, "events"))
+	assert.NoError(t, a.Authorize(writer, Read, "logs"))
+	assert.NoError(t, a.Authorize(writer, Write, "events"))
+	assert.Error(t, a.Authorize(writer, Write, "logs"))
+
+	internal := WithPrincipal(context.Background(), Internal)
+	assert.NoError(t, a.Authorize(internal, Write, "logs"))
+}
+
+func TestAuthenticateConn(t *testing.T) {
+	a := newTestAuth(t)
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "reader"}}
+
+	// Only a verified client certificate identifies the principal of a connection
+	_, err := a.AuthenticateConn(context.Background(), nil)
+	assert.Error(t, err)
+	_, err = a.AuthenticateConn(context.Background(), &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}})
+	assert.Error(t, err)
+
+	ctx, err := a.AuthenticateConn(context.Background(), &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+		VerifiedChains:   [][]*x509.Certificate{{cert}},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, a.Authorize(ctx, Read, "events"))
+
+	// Without authentication, every connection is allowed
+	var none *Authenticator
+	_, err = none.AuthenticateConn(context.Background(), nil)
+	assert.NoError(t, err)
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	a := newTestAuth(t)
+	handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		p, _ := PrincipalOf(ctx)
+		return p.Name, nil
+	}
+
+	tests := []struct {
+		md     metadata.MD
+		expect string
+	}{
+		{md: metadata.Pairs("authorization", "Bearer r-token"), expect: "reader"},
+		{md: metadata.Pairs("x-api-key", "w-token"), expect: "writer"},
+		{md: metadata.Pairs("authorization", "Bearer invalid")},
+		{md: metadata.MD{}},
+	}
+
+	for _, tc := range tests {
+		ctx := metadata.NewIncomingContext(context.Background(), tc.md)
+		out, err := a.unaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+		if tc.expect == "" {
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+			continue
+		}
+
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expect, out)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	a := newTestAuth(t)
+	handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, _ := PrincipalOf(r.Context())
+		_, _ = w.Write([]byte(p.Name))
+	}))
+
+	{
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/v1/describe", nil)
+		req.Header.Set("Authorization", "bearer w-token")
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "writer", rec.Body.String())
+	}
+
+	{
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/describe", nil))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kelindar/talaria/internal/config"
+	"github.com/kelindar/talaria/internal/monitor/errors"
+)
+
+// reloadInterval is the minimum interval between two checks of the certificate files
+const reloadInterval = 30 * time.Second
+
+// NewTLS creates a TLS configuration for a listener, or returns nil if TLS is not configured. The
+// certificate, the key and the client CA are reloaded during the handshakes whenever the files
+// are modified, so the certificates can be rotated without a restart.
+func NewTLS(conf *config.TLS) (*tls.Config, error) {
+	if conf == nil {
+		return nil, nil
+	}
+
+	r := &reloader{conf: conf}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.config(), nil
+		},
+	}, nil
+}
+
+// reloader keeps a TLS configuration up to date with its files
+type reloader struct {
+	sync.Mutex
+	conf    *config.TLS // The paths of the files
+	current *tls.Config // The currently loaded configuration
+	modTime time.Time   // The latest modification time of the files
+	checked time.Time   // The last time the files were checked
+}
+
+// config returns the current configuration, reloading it if the files have been modified
+func (r *reloader) config() *tls.Config {
+	r.Lock()
+	defer r.Unlock()
+
+	if now := time.Now(); now.Sub(r.checked) >= reloadInterval {
+		r.checked = now
+		if modTime := r.lastModified(); modTime.After(r.modTime) {
+			_ = r.reload() // Keep serving the previous certificate if the new one is invalid
+		}
+	}
+	return r.current
+}
+
+// load loads the configuration
+func (r *reloader) load() error {
+	r.Lock()
+	defer r.Unlock()
+	r.checked = time.Now()
+	return r.reload()
+}
+
+// reload loads the certificate of the server and the optional CA of the clients
+func (r *reloader) reload() error {
+	modTime := r.lastModified()
+	cert, err := tls.LoadX509KeyPair(r.conf.Cert, r.conf.Key)
+	if err != nil {
+		return errors.Internal("tls: unable to load the certificate", err)
+	}
+
+	out := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2", "http/1.1"}, // Overrides the protocols of the listener
+	}
+
+	if r.conf.ClientCA != "" {
+		pem, err := ioutil.ReadFile(r.conf.ClientCA)
+		if err != nil {
+			return errors.Internal("tls: unable to load the client CA", err)
+		}
+
+		out.ClientCAs = x509.NewCertPool()
+		if !out.ClientCAs.AppendCertsFromPEM(pem) {
+			return errors.New("tls: unable to parse the client CA")
+		}
+		out.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	r.current = out
+	r.modTime = modTime
+	return nil
+}
+
+// lastModified returns the latest modification time of the files
+func (r *reloader) lastModified() (latest time.Time) {
+	for _, path := range []string{r.conf.Cert, r.conf.Key, r.conf.ClientCA} {
+		if path == "" {
+			continue
+		}
+
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return
+}
@@ -0,0 +1,104 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kelindar/talaria/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeCert generates a self-signed certificate and writes it along with its key
+func writeCert(t *testing.T, dir, name string) *config.TLS {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	conf := &config.TLS{
+		Cert: filepath.Join(dir, "cert.pem"),
+		Key:  filepath.Join(dir, "key.pem"),
+	}
+
+	assert.NoError(t, ioutil.WriteFile(conf.Cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.NoError(t, ioutil.WriteFile(conf.Key, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return conf
+}
+
+func TestNewTLS(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "tls-")
+	defer os.RemoveAll(dir)
+
+	conf := writeCert(t, dir, "first")
+	out, err := NewTLS(conf)
+	assert.NoError(t, err)
+	assert.NotNil(t, out.GetConfigForClient)
+
+	current, err := out.GetConfigForClient(&tls.ClientHelloInfo{})
+	assert.NoError(t, err)
+	assert.Len(t, current.Certificates, 1)
+	assert.Equal(t, tls.NoClientCert, current.ClientAuth)
+
+	_, err = NewTLS(&config.TLS{Cert: conf.Cert, Key: conf.Key, ClientCA: filepath.Join(dir, "missing.pem")})
+	assert.Error(t, err)
+
+	// Mutual TLS, the certificate is its own CA
+	mutual, err := NewTLS(&config.TLS{Cert: conf.Cert, Key: conf.Key, ClientCA: conf.Cert})
+	assert.NoError(t, err)
+	current, err = mutual.GetConfigForClient(&tls.ClientHelloInfo{})
+	assert.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, current.ClientAuth)
+}
+
+func TestReload(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "tls-")
+	defer os.RemoveAll(dir)
+
+	r := &reloader{conf: writeCert(t, dir, "first")}
+	assert.NoError(t, r.load())
+	first := r.config()
+
+	// Not modified, the configuration is kept
+	r.checked = time.Time{}
+	assert.True(t, first == r.config())
+
+	// Rotate the certificate
+	writeCert(t, dir, "second")
+	future := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(r.conf.Cert, future, future))
+
+	// Only reloaded after the interval
+	assert.True(t, first == r.config())
+	r.checked = time.Time{}
+	second := r.config()
+	assert.False(t, first == second)
+
+	leaf, err := x509.ParseCertificate(second.Certificates[0].Certificate[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "second", leaf.Subject.CommonName)
+}
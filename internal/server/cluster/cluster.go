@@ -4,6 +4,8 @@
 package cluster
 
 import (
+	"encoding/base64"
+	"fmt"
 	"io/ioutil"
 	"net"
 	"strconv"
@@ -29,13 +31,22 @@ type Cluster struct {
 	addr string
 }
 
-// New creates a new gossip cluster.
-func New(port int) *Cluster {
+// New creates a new gossip cluster. If keys are specified, the gossip is encrypted with the first
+// key and can be decrypted with any of them, which allows the keys to be rotated.
+func New(port int, keys ...[]byte) *Cluster {
 	cfg := memberlist.DefaultWANConfig()
 	cfg.BindPort = port
 	cfg.AdvertisePort = port
 	cfg.AdvertiseAddr = getAddress()
 	cfg.LogOutput = ioutil.Discard // Ignore memberlist logs
+	if len(keys) > 0 {
+		keyring, err := memberlist.NewKeyring(keys[1:], keys[0])
+		if err != nil {
+			panic("failed to create gossip keyring: " + err.Error())
+		}
+		cfg.Keyring = keyring
+	}
+
 	list, err := memberlist.Create(cfg)
 	if err != nil {
 		panic("failed to create gossip memberlist: " + err.Error())
@@ -47,6 +58,25 @@ func New(port int) *Cluster {
 	}
 }
 
+// DecodeKeys decodes a set of base64-encoded keys, each key must be 16, 24 or 32 bytes long
+// in order to select AES-128, AES-192 or AES-256.
+func DecodeKeys(encoded []string) ([][]byte, error) {
+	keys := make([][]byte, 0, len(encoded))
+	for i, v := range encoded {
+		key, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("gossip key #%d is not base64-encoded: %v", i, err)
+		}
+
+		if n := len(key); n != 16 && n != 24 && n != 32 {
+			return nil, fmt.Errorf("gossip key #%d must be 16, 24 or 32 bytes long, got %d", i, n)
+		}
+
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
 // Members returns the current set of nodes available.
 func (c *Cluster) Members() (nodes []string) {
 	for _, m := range c.list.Members() {
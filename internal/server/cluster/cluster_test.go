@@ -23,3 +23,25 @@ func TestClusterNew(t *testing.T) {
 
 	})
 }
+
+func TestClusterEncrypted(t *testing.T) {
+	keys, err := DecodeKeys([]string{
+		"T9jncgl9mbLus+baTTa7q7nPSUrXwbDi2dhbtqir37s=",
+		"HvY8ubRZMgafUOWvrOadwOckVa1wN3QWAo46FVKbVN8=",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, keys, 2)
+
+	assert.NotPanics(t, func() {
+		cluster := New(rand.Intn(30000)+2000, keys...)
+		defer cluster.Close()
+
+		assert.NoError(t, cluster.Join("127.0.0.1"))
+		assert.NotEmpty(t, cluster.Members())
+	})
+
+	_, err = DecodeKeys([]string{"c2hvcnQ="})
+	assert.Error(t, err)
+	_, err = DecodeKeys([]string{"!"})
+	assert.Error(t, err)
+}
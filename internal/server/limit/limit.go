@@ -0,0 +1,248 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package limit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/kelindar/talaria/internal/config"
+	"github.com/kelindar/talaria/internal/monitor"
+	"github.com/kelindar/talaria/internal/monitor/errors"
+	"github.com/kelindar/talaria/internal/server/auth"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	ctxTag      = "limit"
+	idleTimeout = 10 * time.Minute
+)
+
+// Usage represents the amount of data ingested by a request
+type Usage struct {
+	Rows  int // The number of rows
+	Bytes int // The number of bytes
+}
+
+// Limiter enforces the ingestion rate limits of the tables and of the clients.
+type Limiter struct {
+	lock    sync.Mutex
+	conf    config.Func        // The configuration with the limits
+	monitor monitor.Monitor    // The monitoring layer
+	tables  map[string]*bucket // The buckets of each table
+	clients map[string]*bucket // The buckets of each client
+	purged  time.Time          // The last time the idle clients were purged
+}
+
+// New creates a new limiter.
+func New(conf config.Func, monitor monitor.Monitor) *Limiter {
+	return &Limiter{
+		conf:    conf,
+		monitor: monitor,
+		tables:  make(map[string]*bucket),
+		clients: make(map[string]*bucket),
+		purged:  time.Now(),
+	}
+}
+
+// Admit checks whether a client can ingest the data into a set of tables. Either every limit
+// admits the request and the tokens are consumed, or none of them are and a ResourceExhausted
+// error is returned along with the delay after which the request can be retried.
+func (l *Limiter) Admit(client string, usage map[string]Usage) (time.Duration, error) {
+	now := time.Now()
+	conf := l.conf()
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.purge(now)
+
+	// Reserve the tokens of the client, for the largest usage across the tables
+	var reserved []*rate.Reservation
+	var delay time.Duration
+	var limited [][]string // The tags of the exceeded limits
+	if conf.Writers.GRPC != nil && conf.Writers.GRPC.Limit != nil && client != "" {
+		var total Usage
+		for _, u := range usage {
+			total.Rows = max(total.Rows, u.Rows)
+			total.Bytes = max(total.Bytes, u.Bytes)
+		}
+
+		b := bucketOf(l.clients, client, conf.Writers.GRPC.Limit, now)
+		if d := b.reserve(total, now, &reserved); d > 0 {
+			delay, limited = d, append(limited, []string{"type:client", "name:" + client})
+		}
+	}
+
+	// Reserve the tokens of each table
+	for table, u := range usage {
+		if t, ok := conf.Tables[table]; ok && t.Limit != nil {
+			b := bucketOf(l.tables, table, t.Limit, now)
+			if d := b.reserve(u, now, &reserved); d > 0 {
+				delay, limited = maxDuration(delay, d), append(limited, []string{"type:table", "name:" + table})
+			}
+		}
+	}
+
+	if delay == 0 {
+		return 0, nil
+	}
+
+	// Give back the tokens, since the request is rejected, latest reservations first
+	for i := len(reserved) - 1; i >= 0; i-- {
+		reserved[i].CancelAt(now)
+	}
+
+	for _, tags := range limited {
+		l.monitor.Count1(ctxTag, "exceeded", tags...)
+	}
+
+	return delay, errors.ResourceExhausted(
+		fmt.Sprintf("ingestion rate limit exceeded, retry after %v", delay.Round(time.Millisecond)),
+		errors.WithTag("retryAfter", delay.String()),
+	)
+}
+
+// purge removes the buckets of the clients which have been idle for a while
+func (l *Limiter) purge(now time.Time) {
+	if now.Sub(l.purged) < idleTimeout {
+		return
+	}
+
+	l.purged = now
+	for client, b := range l.clients {
+		if now.Sub(b.seen) >= idleTimeout {
+			delete(l.clients, client)
+		}
+	}
+}
+
+// ------------------------------------------------------------------------------------------------------------
+
+// bucket represents the token buckets of the rows and bytes
+type bucket struct {
+	rows  *rate.Limiter // The bucket of rows
+	bytes *rate.Limiter // The bucket of bytes
+	seen  time.Time     // The last time the bucket was used
+}
+
+// bucketOf returns the bucket for a key, creating it or updating it with the latest limits
+func bucketOf(buckets map[string]*bucket, key string, limit *config.Limit, now time.Time) *bucket {
+	b, ok := buckets[key]
+	if !ok {
+		b = &bucket{
+			rows:  newLimiter(limit.Rows),
+			bytes: newLimiter(limit.Bytes),
+		}
+		buckets[key] = b
+	}
+
+	setLimit(b.rows, limit.Rows, now)
+	setLimit(b.bytes, limit.Bytes, now)
+	b.seen = now
+	return b
+}
+
+// reserve reserves the tokens and returns the delay until they are available
+func (b *bucket) reserve(u Usage, now time.Time, reserved *[]*rate.Reservation) (delay time.Duration) {
+	for _, v := range []struct {
+		limiter *rate.Limiter
+		n       int
+	}{{b.rows, u.Rows}, {b.bytes, u.Bytes}} {
+		if v.limiter.Limit() == rate.Inf || v.n == 0 {
+			continue
+		}
+
+		delay = maxDuration(delay, reserveN(v.limiter, v.n, now, reserved))
+	}
+	return
+}
+
+// reserveN reserves n tokens and returns the delay until they are available. A request larger than
+// the bucket is admitted once the bucket is full and borrows the remaining tokens, so the following
+// requests are delayed until the debt is repaid.
+func reserveN(limiter *rate.Limiter, n int, now time.Time, reserved *[]*rate.Reservation) time.Duration {
+	burst := limiter.Burst()
+	r := limiter.ReserveN(now, min(n, burst))
+	*reserved = append(*reserved, r)
+	if delay := r.DelayFrom(now); delay > 0 || n <= burst {
+		return delay
+	}
+
+	// The bucket can only reserve up to its size, so it is grown for the time of the reservation
+	limiter.SetBurstAt(now, max(burst, n-burst))
+	*reserved = append(*reserved, limiter.ReserveN(now, n-burst))
+	limiter.SetBurstAt(now, burst)
+	return 0
+}
+
+// newLimiter creates a token bucket which holds up to one second worth of tokens
+func newLimiter(perSecond float64) *rate.Limiter {
+	return rate.NewLimiter(limitOf(perSecond), burstOf(perSecond))
+}
+
+// setLimit updates the limit of a token bucket, if it has changed
+func setLimit(limiter *rate.Limiter, perSecond float64, now time.Time) {
+	if limit := limitOf(perSecond); limiter.Limit() != limit {
+		limiter.SetLimitAt(now, limit)
+		limiter.SetBurstAt(now, burstOf(perSecond))
+	}
+}
+
+// limitOf converts a number of tokens per second to a limit
+func limitOf(perSecond float64) rate.Limit {
+	if perSecond <= 0 {
+		return rate.Inf
+	}
+	return rate.Limit(perSecond)
+}
+
+// burstOf returns the size of a bucket which holds one second worth of tokens
+func burstOf(perSecond float64) int {
+	return int(math.Max(1, math.Ceil(perSecond)))
+}
+
+// ------------------------------------------------------------------------------------------------------------
+
+// ClientOf returns the identity of the client of a request, which is either the name of the
+// authenticated principal or the address of the peer. The identity must not be chosen by the
+// client itself, otherwise a client could get a new bucket with every request.
+func ClientOf(ctx context.Context) string {
+	if p, ok := auth.PrincipalOf(ctx); ok {
+		return p.Name
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return host
+		}
+		return p.Addr.String()
+	}
+	return ""
+}
+
+func min(x, y int) int {
+	if x < y {
+		return x
+	}
+	return y
+}
+
+func max(x, y int) int {
+	if x > y {
+		return x
+	}
+	return y
+}
+
+func maxDuration(x, y time.Duration) time.Duration {
+	if x > y {
+		return x
+	}
+	return y
+}
@@ -0,0 +1,124 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package limit
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/kelindar/talaria/internal/config"
+	"github.com/kelindar/talaria/internal/monitor"
+	"github.com/kelindar/talaria/internal/monitor/errors"
+	"github.com/kelindar/talaria/internal/server/auth"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func newTestLimiter() (*Limiter, *config.Config) {
+	conf := &config.Config{
+		Writers: config.Writers{GRPC: &config.GRPC{Limit: &config.Limit{Rows: 100}}},
+		Tables: config.Tables{
+			"events": {Limit: &config.Limit{Rows: 10, Bytes: 1000}},
+			"logs":   {},
+		},
+	}
+
+	return New(func() *config.Config { return conf }, monitor.NewNoop()), conf
+}
+
+func TestAdmit_Table(t *testing.T) {
+	l, _ := newTestLimiter()
+
+	_, err := l.Admit("a", map[string]Usage{"events": {Rows: 8, Bytes: 100}})
+	assert.NoError(t, err)
+
+	// The bucket of the table is shared across the clients
+	delay, err := l.Admit("b", map[string]Usage{"events": {Rows: 8, Bytes: 100}})
+	assert.Error(t, err)
+	assert.Equal(t, codes.ResourceExhausted, err.(*errors.Error).GRPC())
+	assert.True(t, delay > 0 && delay <= time.Second)
+
+	// The tables without limits are not limited
+	_, err = l.Admit("b", map[string]Usage{"logs": {Rows: 50, Bytes: 1 << 20}})
+	assert.NoError(t, err)
+}
+
+func TestAdmit_Oversized(t *testing.T) {
+	l, _ := newTestLimiter()
+
+	// A request larger than the bucket is admitted once the bucket is full
+	_, err := l.Admit("a", map[string]Usage{"events": {Rows: 30}})
+	assert.NoError(t, err)
+
+	// The following requests wait until the borrowed tokens are repaid
+	delay, err := l.Admit("a", map[string]Usage{"events": {Rows: 1}})
+	assert.Error(t, err)
+	assert.True(t, delay > 2*time.Second && delay <= 3*time.Second, delay.String())
+
+	// A request larger than the bucket also waits until the bucket is full
+	l, _ = newTestLimiter()
+	_, err = l.Admit("a", map[string]Usage{"events": {Rows: 5}})
+	assert.NoError(t, err)
+	delay, err = l.Admit("a", map[string]Usage{"events": {Rows: 30}})
+	assert.Error(t, err)
+	assert.True(t, delay > 0 && delay <= time.Second, delay.String())
+}
+
+func TestAdmit_Client(t *testing.T) {
+	l, _ := newTestLimiter()
+
+	_, err := l.Admit("a", map[string]Usage{"logs": {Rows: 100}})
+	assert.NoError(t, err)
+
+	_, err = l.Admit("a", map[string]Usage{"logs": {Rows: 50}})
+	assert.Error(t, err)
+
+	// Another client has its own bucket
+	_, err = l.Admit("b", map[string]Usage{"logs": {Rows: 50}})
+	assert.NoError(t, err)
+}
+
+func TestAdmit_Rejected(t *testing.T) {
+	l, _ := newTestLimiter()
+
+	// The client tokens are given back if a table rejects the request
+	_, err := l.Admit("a", map[string]Usage{"events": {Rows: 10}})
+	assert.NoError(t, err)
+	_, err = l.Admit("a", map[string]Usage{"events": {Rows: 10}, "logs": {Rows: 80}})
+	assert.Error(t, err)
+	_, err = l.Admit("a", map[string]Usage{"logs": {Rows: 85}})
+	assert.NoError(t, err)
+}
+
+func TestAdmit_Reload(t *testing.T) {
+	l, conf := newTestLimiter()
+
+	_, err := l.Admit("a", map[string]Usage{"events": {Rows: 10}})
+	assert.NoError(t, err)
+	_, err = l.Admit("a", map[string]Usage{"events": {Rows: 10}})
+	assert.Error(t, err)
+
+	// The limits are updated when the configuration changes
+	conf.Tables["events"] = config.Table{}
+	_, err = l.Admit("a", map[string]Usage{"events": {Rows: 10}})
+	assert.NoError(t, err)
+}
+
+func TestClientOf(t *testing.T) {
+	assert.Equal(t, "", ClientOf(context.Background()))
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}})
+	assert.Equal(t, "10.0.0.1", ClientOf(ctx))
+
+	// The client can not choose its own identity
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-client-id", "producer"))
+	assert.Equal(t, "10.0.0.1", ClientOf(ctx))
+
+	ctx = auth.WithPrincipal(ctx, auth.Internal)
+	assert.Equal(t, "internal", ClientOf(ctx))
+}
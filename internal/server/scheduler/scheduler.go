@@ -0,0 +1,87 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package scheduler
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/kelindar/talaria/internal/config"
+	"github.com/kelindar/talaria/internal/monitor"
+	"github.com/kelindar/talaria/internal/monitor/errors"
+)
+
+const (
+	ctxTag         = "scheduler"
+	defaultTimeout = 30 * time.Second
+)
+
+// Scheduler bounds the number of concurrent scans and the duration of each request, so that the
+// queries can not starve the ingestion.
+type Scheduler struct {
+	slots   chan struct{}   // The slots of the concurrent scans
+	timeout time.Duration   // The maximum duration of a request, including the time spent waiting
+	queued  int64           // The number of requests waiting for a slot
+	monitor monitor.Monitor // The monitoring layer
+}
+
+// New creates a new scheduler. By default, the number of concurrent scans is the number of CPUs
+// and each request must complete within 30 seconds.
+func New(conf *config.Scheduler, monitor monitor.Monitor) *Scheduler {
+	concurrency, timeout := runtime.NumCPU(), defaultTimeout
+	if conf != nil && conf.Concurrency > 0 {
+		concurrency = conf.Concurrency
+	}
+
+	if conf != nil && conf.Timeout > 0 {
+		timeout = time.Duration(conf.Timeout) * time.Second
+	}
+
+	return &Scheduler{
+		slots:   make(chan struct{}, concurrency),
+		timeout: timeout,
+		monitor: monitor,
+	}
+}
+
+// Run waits for a slot and runs the scan of a table. The context passed to the scan is cancelled
+// if the request is cancelled or if it exceeds its deadline.
+func (s *Scheduler) Run(ctx context.Context, table string, scan func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	// Wait for a slot to become available
+	start := time.Now()
+	s.monitor.Gauge(ctxTag, "queued", float64(atomic.AddInt64(&s.queued, 1)))
+	select {
+	case s.slots <- struct{}{}:
+		s.monitor.Gauge(ctxTag, "queued", float64(atomic.AddInt64(&s.queued, -1)))
+		s.monitor.Duration(ctxTag, "queue.wait", start, "table:"+table)
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		s.monitor.Gauge(ctxTag, "queued", float64(atomic.AddInt64(&s.queued, -1)))
+		s.monitor.Count1(ctxTag, "rejected", "table:"+table)
+		return errorOf(ctx.Err(), "query: request ended while waiting for a scan slot")
+	}
+
+	// Run the scan within the deadline
+	start = time.Now()
+	err := scan(ctx)
+	s.monitor.Duration(ctxTag, "scan", start, "table:"+table)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		s.monitor.Count1(ctxTag, "interrupted", "table:"+table)
+		return errorOf(ctxErr, "query: request ended during the scan")
+	}
+	return err
+}
+
+// errorOf converts a context error to a server error
+func errorOf(err error, msg string) error {
+	if err == context.DeadlineExceeded {
+		return errors.DeadlineExceeded(msg)
+	}
+	return errors.Canceled(msg)
+}
@@ -0,0 +1,89 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kelindar/talaria/internal/config"
+	"github.com/kelindar/talaria/internal/monitor"
+	"github.com/kelindar/talaria/internal/monitor/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+)
+
+func TestRun_Concurrency(t *testing.T) {
+	s := New(&config.Scheduler{Concurrency: 2}, monitor.NewNoop())
+
+	var lock sync.Mutex
+	var running, peak int
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, s.Run(context.Background(), "events", func(ctx context.Context) error {
+				lock.Lock()
+				if running++; running > peak {
+					peak = running
+				}
+				lock.Unlock()
+
+				time.Sleep(5 * time.Millisecond)
+				lock.Lock()
+				running--
+				lock.Unlock()
+				return nil
+			}))
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, 2, peak)
+}
+
+func TestRun_Queued(t *testing.T) {
+	s := New(&config.Scheduler{Concurrency: 1}, monitor.NewNoop())
+	s.timeout = 20 * time.Millisecond
+
+	// Hold the only slot
+	release := make(chan struct{})
+	go s.Run(context.Background(), "events", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	time.Sleep(5 * time.Millisecond)
+	defer close(release)
+
+	// Waiting for the slot exceeds the deadline
+	called := false
+	err := s.Run(context.Background(), "events", func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	assert.False(t, called)
+	assert.Equal(t, codes.DeadlineExceeded, err.(*errors.Error).GRPC())
+
+	// A cancelled request does not wait
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = s.Run(ctx, "events", func(ctx context.Context) error { return nil })
+	assert.Equal(t, codes.Canceled, err.(*errors.Error).GRPC())
+}
+
+func TestRun_Interrupted(t *testing.T) {
+	s := New(nil, monitor.NewNoop())
+	s.timeout = 10 * time.Millisecond
+
+	// The scan is interrupted by the deadline
+	err := s.Run(context.Background(), "events", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.Equal(t, codes.DeadlineExceeded, err.(*errors.Error).GRPC())
+	assert.Len(t, s.slots, 0)
+}
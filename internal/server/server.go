@@ -5,24 +5,32 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"net"
+	"net/http"
 	"runtime/debug"
 	"time"
 
+	"github.com/apache/arrow/go/arrow/flight"
 	"github.com/grab/async"
 	"github.com/kelindar/talaria/internal/column"
 	"github.com/kelindar/talaria/internal/config"
 	"github.com/kelindar/talaria/internal/ingress/s3sqs"
 	"github.com/kelindar/talaria/internal/monitor"
 	"github.com/kelindar/talaria/internal/monitor/errors"
+	"github.com/kelindar/talaria/internal/monitor/tracing"
 	"github.com/kelindar/talaria/internal/presto"
 	script "github.com/kelindar/talaria/internal/scripting"
+	"github.com/kelindar/talaria/internal/server/auth"
+	"github.com/kelindar/talaria/internal/server/limit"
+	"github.com/kelindar/talaria/internal/server/scheduler"
 	"github.com/kelindar/talaria/internal/server/thriftlog"
 	"github.com/kelindar/talaria/internal/table"
 	talaria "github.com/kelindar/talaria/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 const (
@@ -48,13 +56,36 @@ type Storage interface {
 // New creates a new talaria server.
 func New(conf config.Func, monitor monitor.Monitor, loader *script.Loader, tables ...table.Table) *Server {
 	const maxMessageSize = 32 * 1024 * 1024 // 32 MB
+
+	// Load the authentication, a misconfiguration must not result in an open server
+	authenticator, err := auth.New(conf().Auth)
+	if err != nil {
+		panic(err)
+	}
+
 	server := &Server{
-		server:  grpc.NewServer(grpc.MaxRecvMsgSize(maxMessageSize)),
 		conf:    conf,
 		monitor: monitor,
+		auth:    authenticator,
+		limit:   limit.New(conf, monitor),
+		sched:   scheduler.New(conf().Readers.Scheduler, monitor),
 		tables:  make(map[string]table.Table),
 	}
 
+	// Load the TLS of the gRPC listener
+	var grpcTLS *config.TLS
+	if c := conf().Writers.GRPC; c != nil {
+		grpcTLS = c.TLS
+	}
+
+	tlsConfig, err := server.tlsOf(grpcTLS)
+	if err != nil {
+		panic(err)
+	}
+
+	options := append(server.grpcOptions(tlsConfig), grpc.MaxRecvMsgSize(maxMessageSize))
+	server.server = grpc.NewServer(options...)
+
 	// Load computed columns
 	for _, c := range conf().Computed {
 		col, err := column.NewComputed(c.Name, c.Type, c.Func, loader)
@@ -84,10 +115,15 @@ type Server struct {
 	server   *grpc.Server           // The underlying gRPC server
 	conf     config.Func            // The presto configuration
 	monitor  monitor.Monitor        // The monitoring layer
+	auth     *auth.Authenticator    // The authentication and authorization (optional)
+	limit    *limit.Limiter         // The ingestion rate limits
+	sched    *scheduler.Scheduler   // The scheduler of the scans
 	cancel   context.CancelFunc     // The cancellation function for the server
 	tables   map[string]table.Table // The list of tables
 	computed []column.Computed      // The set of computed columns
 	s3sqs    *s3sqs.Ingress         // The S3SQS Ingress (optional)
+	http     *http.Server           // The HTTP/JSON query listener (optional)
+	flight   *grpc.Server           // The Arrow Flight query listener (optional)
 }
 
 // Listen starts listening on presto RPC & gRPC.
@@ -115,14 +151,100 @@ func (s *Server) Listen(ctx context.Context, prestoPort, grpcPort int32) error {
 		return nil, nil
 	})
 
-	// Serve presto and block
+	// Asynchronously start the HTTP listener (if configured)
+	if conf := s.conf().Readers.HTTP; conf != nil {
+		s.http = &http.Server{
+			Addr:      fmt.Sprintf(":%d", conf.Port),
+			Handler:   s.auth.Middleware(s.newHTTPHandler()),
+			TLSConfig: s.auth.TLS(),
+		}
+
+		async.Invoke(ctx, func(ctx context.Context) (interface{}, error) {
+			s.monitor.Info("server: listening for http on :%d...", conf.Port)
+			serve := s.http.ListenAndServe
+			if s.http.TLSConfig != nil {
+				serve = func() error { return s.http.ListenAndServeTLS("", "") }
+			}
+
+			if err := serve(); err != nil && err != http.ErrServerClosed {
+				s.monitor.Error(errors.Internal("unable to serve http", err))
+				return nil, err
+			}
+			return nil, nil
+		})
+	}
+
+	// Asynchronously start the Arrow Flight listener (if configured)
+	if conf := s.conf().Readers.Flight; conf != nil {
+		s.flight = grpc.NewServer(s.grpcOptions(s.auth.TLS())...)
+		flight.RegisterFlightServiceService(s.flight, s.newFlightService())
+
+		async.Invoke(ctx, func(ctx context.Context) (interface{}, error) {
+			s.monitor.Info("server: listening for arrow flight on :%d...", conf.Port)
+			lis, err := net.Listen("tcp", fmt.Sprintf(":%d", conf.Port))
+			if err != nil {
+				s.monitor.Error(errors.Internal("unable to listen for arrow flight", err))
+				return nil, err
+			}
+
+			if err := s.flight.Serve(lis); err != nil {
+				s.monitor.Error(errors.Internal("unable to serve arrow flight", err))
+				return nil, err
+			}
+			return nil, nil
+		})
+	}
+
+	// Load the TLS of the thrift listener
+	tlsConf := s.conf().Readers.Presto.TLS
+	if tlsConf == nil && s.conf().Auth != nil {
+		tlsConf = s.conf().Auth.TLS
+	}
+
+	// Thrift carries no credentials, so the principals can only be identified by their client certificate
+	if s.auth.Enabled() && (tlsConf == nil || tlsConf.ClientCA == "") {
+		return errors.New("server: thrift requires mutual TLS with a client CA when principals are configured")
+	}
+
+	tlsConfig, err := s.tlsOf(s.conf().Readers.Presto.TLS)
+	if err != nil {
+		return err
+	}
+
+	// Serve presto and block, each connection being served with the principal of its client certificate
 	s.monitor.Info("server: listening for thrift on :%d...", grpcPort)
-	return presto.Serve(ctx, int32(prestoPort), &thriftlog.Service{
-		Service: s,
-		Monitor: s.monitor,
+	return presto.Serve(ctx, int32(prestoPort), tlsConfig, func(ctx context.Context, state *tls.ConnectionState) (presto.PrestoThriftService, error) {
+		ctx, err := s.auth.AuthenticateConn(ctx, state)
+		if err != nil {
+			s.monitor.Count1(ctxTag, "thrift.unauthenticated")
+			return nil, err
+		}
+
+		return &thriftlog.Service{
+			Service: &thriftConn{Server: s, ctx: ctx},
+			Monitor: s.monitor,
+		}, nil
 	})
 }
 
+// tlsOf returns the TLS configuration of a listener, or the one of the auth section if the listener
+// does not specify its own
+func (s *Server) tlsOf(conf *config.TLS) (*tls.Config, error) {
+	if conf == nil {
+		return s.auth.TLS(), nil
+	}
+	return auth.NewTLS(conf)
+}
+
+// grpcOptions returns the options of a gRPC listener, with the transport credentials, the authentication and the tracing
+func (s *Server) grpcOptions(tlsConfig *tls.Config) []grpc.ServerOption {
+	options := append(s.auth.ServerOptions(), tracing.ServerOptions()...)
+	if tlsConfig != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	return options
+}
+
 // Optionally starts an S3 SQS ingress
 func (s *Server) pollFromSQS(conf *config.Config) (err error) {
 	if conf.Writers.S3SQS == nil {
@@ -138,7 +260,7 @@ func (s *Server) pollFromSQS(conf *config.Config) (err error) {
 	// Start ingesting
 	s.monitor.Info("server: starting ingestion from S3/SQS...")
 	s.s3sqs.Range(func(v []byte) bool {
-		if _, err := s.Ingest(context.Background(), &talaria.IngestRequest{
+		if _, err := s.Ingest(auth.WithPrincipal(context.Background(), auth.Internal), &talaria.IngestRequest{
 			Data: &talaria.IngestRequest_Orc{Orc: v},
 		}); err != nil {
 			s.monitor.Warning(err)
@@ -153,6 +275,16 @@ func (s *Server) Close() {
 	s.server.GracefulStop()
 	s.cancel()
 
+	// Stop the HTTP listener
+	if s.http != nil {
+		_ = s.http.Close()
+	}
+
+	// Stop the Arrow Flight listener
+	if s.flight != nil {
+		s.flight.GracefulStop()
+	}
+
 	// Stop S3/SQS ingress
 	if s.s3sqs != nil {
 		s.s3sqs.Close()
@@ -0,0 +1,155 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kelindar/talaria/internal/config"
+	"github.com/kelindar/talaria/internal/presto"
+	"github.com/kelindar/talaria/internal/server/auth"
+	talaria "github.com/kelindar/talaria/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestAuth_Permissions(t *testing.T) {
+	runServer(t, func(s *Server) {
+		var err error
+		s.auth, err = auth.New(&config.Auth{
+			Principals: []config.Principal{
+				{Name: "reader", Token: "reader", Read: []string{testTable}},
+				{Name: "other", Token: "other", Read: []string{"other"}, Write: []string{"other"}},
+			},
+		})
+		assert.NoError(t, err)
+
+		// Authenticate the principals the same way the gRPC server does
+		login := func(token string) context.Context {
+			ctx, err := s.auth.Authenticate(metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", token)))
+			assert.NoError(t, err)
+			return ctx
+		}
+
+		reader, other := login("reader"), login("other")
+		request := &talaria.GetSplitsRequest{
+			Table:   testTable,
+			Columns: []string{"string1"},
+			Filters: []string{"string1 == '110010100101010010101000100001'"},
+		}
+
+		// The reader can query the table but not write into it
+		splits, err := s.GetSplits(reader, request)
+		assert.NoError(t, err)
+		assert.Len(t, splits.Splits, 1)
+
+		_, err = s.GetRows(reader, &talaria.GetRowsRequest{SplitID: splits.Splits[0].SplitID, Columns: []string{"string1"}})
+		assert.NoError(t, err)
+
+		_, err = s.Ingest(reader, &talaria.IngestRequest{})
+		assert.Error(t, err)
+
+		// The other principal can not access the table at all
+		_, err = s.GetSplits(other, request)
+		assert.Error(t, err)
+
+		_, err = s.GetRows(other, &talaria.GetRowsRequest{SplitID: splits.Splits[0].SplitID, Columns: []string{"string1"}})
+		assert.Error(t, err)
+
+		// Anonymous requests are rejected
+		_, err = s.GetSplits(context.Background(), request)
+		assert.Error(t, err)
+	})
+}
+
+func TestAuth_Thrift(t *testing.T) {
+	runServer(t, func(s *Server) {
+		var err error
+		s.auth, err = auth.New(&config.Auth{
+			Principals: []config.Principal{
+				{Name: "reader", Token: "reader", Read: []string{testTable}},
+				{Name: "other", Token: "other", Read: []string{"other"}},
+			},
+		})
+		assert.NoError(t, err)
+
+		// Connections without a verified client certificate are rejected
+		_, err = s.auth.AuthenticateConn(context.Background(), nil)
+		assert.Error(t, err)
+
+		// Bind the principals to the connections the same way the thrift listener does
+		connect := func(token string) *thriftConn {
+			ctx, err := s.auth.Authenticate(metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", token)))
+			assert.NoError(t, err)
+			return &thriftConn{Server: s, ctx: ctx}
+		}
+
+		reader := connect("reader")
+		table := &presto.PrestoThriftSchemaTableName{SchemaName: "talaria", TableName: testTable}
+
+		// The reader can list, describe and query the table
+		tables, err := reader.PrestoListTables(nil)
+		assert.NoError(t, err)
+		assert.Len(t, tables, 1)
+
+		_, err = reader.PrestoGetTableMetadata(table)
+		assert.NoError(t, err)
+
+		splits, err := reader.PrestoGetSplits(table, nil, equals("string1", "110010100101010010101000100001"), 10, nil)
+		require.NoError(t, err)
+		require.NotEmpty(t, splits.Splits)
+
+		rows, err := reader.PrestoGetRows(splits.Splits[0].SplitId, []string{"string1"}, 1<<20, new(presto.PrestoThriftNullableToken))
+		require.NoError(t, err)
+		assert.NotZero(t, rows.RowCount)
+
+		// Neither the other principal nor an anonymous connection can access the table
+		for _, c := range []*thriftConn{connect("other"), s.thrift()} {
+			tables, err := c.PrestoListTables(nil)
+			assert.NoError(t, err)
+			assert.Empty(t, tables)
+
+			_, err = c.PrestoGetTableMetadata(table)
+			assert.Error(t, err)
+
+			_, err = c.PrestoGetSplits(table, nil, equals("string1", "110010100101010010101000100001"), 10, nil)
+			assert.Error(t, err)
+
+			_, err = c.PrestoGetRows(splits.Splits[0].SplitId, []string{"string1"}, 1<<20, new(presto.PrestoThriftNullableToken))
+			assert.Error(t, err)
+		}
+
+		// Describe only lists the tables which can be read
+		described, err := s.Describe(reader.ctx, &talaria.DescribeRequest{})
+		assert.NoError(t, err)
+		assert.Len(t, described.Tables, 1)
+
+		described, err = s.Describe(connect("other").ctx, &talaria.DescribeRequest{})
+		assert.NoError(t, err)
+		assert.Empty(t, described.Tables)
+	})
+}
+
+// equals returns a thrift constraint for a string column
+func equals(column, value string) *presto.PrestoThriftTupleDomain {
+	marker := &presto.PrestoThriftMarker{
+		Value: &presto.PrestoThriftBlock{VarcharData: &presto.PrestoThriftVarchar{
+			Bytes: []byte(value),
+			Sizes: []int32{int32(len(value))},
+		}},
+		Bound: presto.PrestoThriftBoundExactly,
+	}
+
+	return &presto.PrestoThriftTupleDomain{
+		Domains: map[string]*presto.PrestoThriftDomain{
+			column: {ValueSet: &presto.PrestoThriftValueSet{
+				RangeValueSet: &presto.PrestoThriftRangeValueSet{
+					Ranges: []*presto.PrestoThriftRange{{Low: marker, High: marker}},
+				},
+			}},
+		},
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	arrowschema "github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/flight"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/apache/arrow/go/arrow/memory"
+	"github.com/kelindar/binary"
+	"github.com/kelindar/talaria/internal/encoding/arrow"
+	"github.com/kelindar/talaria/internal/monitor/errors"
+	talaria "github.com/kelindar/talaria/proto"
+)
+
+// flightTicket represents a ticket which can be redeemed for the rows of a split
+type flightTicket struct {
+	SplitID []byte   // The split identifier
+	Columns []string // The set of desired columns
+}
+
+// newFlightService creates an Arrow Flight service which exposes the query API. The flight
+// descriptor command is a JSON-encoded GetSplitsRequest, each split is mapped to an endpoint
+// and its rows are streamed as arrow record batches.
+func (s *Server) newFlightService() *flight.FlightServiceService {
+	return &flight.FlightServiceService{
+		GetFlightInfo: s.getFlightInfo,
+		GetSchema:     s.getFlightSchema,
+		DoGet:         s.doGet,
+	}
+}
+
+// getFlightInfo returns the list of endpoints (one per split) for a table/filter combination
+func (s *Server) getFlightInfo(ctx context.Context, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	request, schema, err := s.decodeFlightDescriptor(desc)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := s.GetSplits(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each split needs to be retrieved from the flight listener of each host
+	port := s.conf().Readers.Flight.Port
+	info := &flight.FlightInfo{
+		Schema:           schema,
+		FlightDescriptor: desc,
+		TotalRecords:     -1,
+		TotalBytes:       -1,
+	}
+
+	for _, split := range response.Splits {
+		ticket, err := binary.Marshal(&flightTicket{
+			SplitID: split.SplitID,
+			Columns: request.Columns,
+		})
+		if err != nil {
+			return nil, errors.Internal("unable to encode the ticket", err)
+		}
+
+		endpoint := &flight.FlightEndpoint{Ticket: &flight.Ticket{Ticket: ticket}}
+		for _, host := range split.Hosts {
+			endpoint.Location = append(endpoint.Location, &flight.Location{
+				Uri: fmt.Sprintf("grpc+tcp://%s:%d", host.Host, port),
+			})
+		}
+		info.Endpoint = append(info.Endpoint, endpoint)
+	}
+
+	return info, nil
+}
+
+// getFlightSchema returns the serialized arrow schema for a flight descriptor
+func (s *Server) getFlightSchema(ctx context.Context, desc *flight.FlightDescriptor) (*flight.SchemaResult, error) {
+	_, schema, err := s.decodeFlightDescriptor(desc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &flight.SchemaResult{Schema: schema}, nil
+}
+
+// doGet streams the rows of a split as a set of arrow record batches, one per page
+func (s *Server) doGet(tkt *flight.Ticket, stream flight.FlightService_DoGetServer) error {
+	defer s.handlePanic()
+	defer s.monitor.Duration(ctxTag, funcTag, time.Now(), "func:flight_do_get")
+
+	ticket := new(flightTicket)
+	if err := binary.Unmarshal(tkt.Ticket, ticket); err != nil {
+		return errors.InvalidArgument(fmt.Sprintf("unable to decode the ticket: %v", err))
+	}
+
+	id, err := decodeID(ticket.SplitID, nil)
+	if err != nil {
+		return errors.InvalidArgument(fmt.Sprintf("unable to decode the split: %v", err))
+	}
+
+	schema, err := s.flightSchemaOf(id.Table, ticket.Columns)
+	if err != nil {
+		return err
+	}
+
+	writer := ipc.NewFlightDataWriter(stream, ipc.WithSchema(schema), ipc.WithAllocator(memory.NewGoAllocator()))
+	for token := []byte(nil); ; {
+		page, nextToken, err := s.getRows(stream.Context(), ticket.SplitID, token, ticket.Columns, defaultMaxBytes)
+		if err != nil {
+			return err
+		}
+
+		record, err := arrow.RecordOf(schema, page.Columns)
+		if err != nil {
+			return errors.Internal("unable to create arrow record", err)
+		}
+
+		err = writer.Write(record)
+		record.Release()
+		if err != nil {
+			return errors.Internal("unable to write arrow record", err)
+		}
+
+		if token = nextToken; token == nil {
+			break
+		}
+	}
+
+	return writer.Close()
+}
+
+// decodeFlightDescriptor decodes the descriptor command into a splits request and populates the
+// requested columns (all of the columns if none were requested) along with the serialized schema.
+func (s *Server) decodeFlightDescriptor(desc *flight.FlightDescriptor) (*talaria.GetSplitsRequest, []byte, error) {
+	if desc.Type != flight.FlightDescriptor_CMD {
+		return nil, nil, errors.InvalidArgument("only command flight descriptors are supported")
+	}
+
+	request := new(talaria.GetSplitsRequest)
+	if err := json.Unmarshal(desc.Cmd, request); err != nil {
+		return nil, nil, errors.InvalidArgument(fmt.Sprintf("unable to decode the command: %v", err))
+	}
+
+	// Default to all of the columns of the table
+	if len(request.Columns) == 0 {
+		table, err := s.getTable(request.Table)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		schema, _ := table.Schema()
+		request.Columns = schema.Columns()
+	}
+
+	schema, err := s.flightSchemaOf(request.Table, request.Columns)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encoded, err := arrow.SerializeSchema(schema)
+	if err != nil {
+		return nil, nil, errors.Internal("unable to serialize arrow schema", err)
+	}
+
+	return request, encoded, nil
+}
+
+// flightSchemaOf returns the arrow schema for a set of columns of a table
+func (s *Server) flightSchemaOf(tableName string, columns []string) (*arrowschema.Schema, error) {
+	table, err := s.getTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, _ := table.Schema()
+	out, err := arrow.SchemaFor(columns, schema)
+	if err != nil {
+		return nil, errors.InvalidArgument(err.Error())
+	}
+	return out, nil
+}
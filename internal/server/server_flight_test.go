@@ -0,0 +1,68 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/apache/arrow/go/arrow/flight"
+	talaria "github.com/kelindar/talaria/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+// flightStream captures the flight data sent by the server
+type flightStream struct {
+	grpc.ServerStream
+	data []*flight.FlightData
+}
+
+func (s *flightStream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *flightStream) Send(d *flight.FlightData) error {
+	s.data = append(s.data, d)
+	return nil
+}
+
+func TestFlight_Query(t *testing.T) {
+	runServer(t, func(s *Server) {
+		svc := s.newFlightService()
+		cmd, _ := json.Marshal(&talaria.GetSplitsRequest{
+			Table:   testTable,
+			Columns: []string{"string1", "int1"},
+			Filters: []string{"string1 == '110010100101010010101000100001'"},
+		})
+
+		desc := &flight.FlightDescriptor{Type: flight.FlightDescriptor_CMD, Cmd: cmd}
+		info, err := svc.GetFlightInfo(context.Background(), desc)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, info.Schema)
+		assert.Len(t, info.Endpoint, 1)
+		assert.Equal(t, "grpc+tcp://127.0.0.1:8045", info.Endpoint[0].Location[0].Uri)
+
+		schema, err := svc.GetSchema(context.Background(), desc)
+		assert.NoError(t, err)
+		assert.Equal(t, info.Schema, schema.Schema)
+
+		// Schema message followed by at least one record batch
+		stream := new(flightStream)
+		assert.NoError(t, svc.DoGet(info.Endpoint[0].Ticket, stream))
+		assert.GreaterOrEqual(t, len(stream.data), 2)
+		assert.NotEmpty(t, stream.data[1].DataBody)
+	})
+}
+
+func TestFlight_BadDescriptor(t *testing.T) {
+	runServer(t, func(s *Server) {
+		_, err := s.newFlightService().GetFlightInfo(context.Background(), &flight.FlightDescriptor{
+			Type: flight.FlightDescriptor_PATH,
+			Path: []string{testTable},
+		})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,208 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kelindar/talaria/internal/encoding/arrow"
+	"github.com/kelindar/talaria/internal/encoding/typeof"
+	"github.com/kelindar/talaria/internal/monitor/errors"
+	"github.com/kelindar/talaria/internal/presto"
+	"github.com/kelindar/talaria/internal/table"
+	talaria "github.com/kelindar/talaria/proto"
+)
+
+const (
+	defaultMaxBytes = 16 * 1024 * 1024 // 16 MB
+	nextTokenHeader = "X-Talaria-Next-Token"
+)
+
+// RowsRequest represents a request for a page of rows over HTTP
+type RowsRequest struct {
+	SplitID   []byte   `json:"splitID"`             // The split identifier, as returned by the splits endpoint
+	Columns   []string `json:"columns"`             // The set of desired columns
+	MaxBytes  int64    `json:"maxBytes,omitempty"`  // The maximum bytes that should be returned
+	NextToken []byte   `json:"nextToken,omitempty"` // The cursor representing the next token
+}
+
+// RowsResponse represents a page of rows encoded as JSON arrays
+type RowsResponse struct {
+	Columns   []string        `json:"columns"`             // The names of the columns, in order
+	Rows      [][]interface{} `json:"rows"`                // The set of rows
+	RowCount  int             `json:"rowCount"`            // The number of rows returned
+	NextToken []byte          `json:"nextToken,omitempty"` // The cursor representing the next token
+}
+
+// newHTTPHandler creates a router which exposes the query API over HTTP/JSON
+func (s *Server) newHTTPHandler() http.Handler {
+	router := mux.NewRouter()
+	router.HandleFunc("/v1/describe", s.handleDescribe).Methods(http.MethodGet)
+	router.HandleFunc("/v1/splits", s.handleSplits).Methods(http.MethodPost)
+	router.HandleFunc("/v1/rows", s.handleRows).Methods(http.MethodPost)
+	return router
+}
+
+// handleDescribe returns the list of tables along with their schema
+func (s *Server) handleDescribe(w http.ResponseWriter, r *http.Request) {
+	defer s.handlePanic()
+	response, err := s.Describe(r.Context(), &talaria.DescribeRequest{})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, response)
+}
+
+// handleSplits returns the list of splits for a table/filter combination. The request body
+// is a JSON-encoded GetSplitsRequest and the filters follow the same syntax.
+func (s *Server) handleSplits(w http.ResponseWriter, r *http.Request) {
+	defer s.handlePanic()
+	request := new(talaria.GetSplitsRequest)
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		writeError(w, errors.InvalidArgument(fmt.Sprintf("unable to decode the request: %v", err)))
+		return
+	}
+
+	response, err := s.GetSplits(r.Context(), request)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// The splits need to be retrieved from the HTTP listener of each host
+	port := s.conf().Readers.HTTP.Port
+	for _, split := range response.Splits {
+		for _, host := range split.Hosts {
+			host.Port = port
+		}
+	}
+
+	writeJSON(w, response)
+}
+
+// handleRows returns a page of rows for a particular split, either as JSON arrays or as an
+// Arrow IPC stream if the client accepts it.
+func (s *Server) handleRows(w http.ResponseWriter, r *http.Request) {
+	defer s.handlePanic()
+	defer s.monitor.Duration(ctxTag, funcTag, time.Now(), "func:http_get_rows")
+
+	request := new(RowsRequest)
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		writeError(w, errors.InvalidArgument(fmt.Sprintf("unable to decode the request: %v", err)))
+		return
+	}
+
+	if request.MaxBytes <= 0 {
+		request.MaxBytes = defaultMaxBytes
+	}
+
+	// Retrieve the rows for the table
+	page, nextToken, err := s.getRows(r.Context(), request.SplitID, request.NextToken, request.Columns, request.MaxBytes)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Respond with an arrow stream if that's what the client wants
+	if strings.Contains(r.Header.Get("Accept"), arrow.ContentType) {
+		if err := writeArrow(w, request.Columns, page, nextToken); err != nil {
+			s.monitor.Warning(errors.Internal("unable to write arrow stream", err))
+		}
+		return
+	}
+
+	writeJSON(w, &RowsResponse{
+		Columns:   request.Columns,
+		Rows:      rowsOf(page.Columns),
+		RowCount:  countOf(page.Columns),
+		NextToken: nextToken,
+	})
+}
+
+// writeArrow writes the page as an arrow IPC stream
+func writeArrow(w http.ResponseWriter, columns []string, page *table.PageResult, nextToken []byte) error {
+	schema := make(typeof.Schema, len(columns))
+	for i, c := range page.Columns {
+		schema[columns[i]] = c.Kind()
+	}
+
+	arrowSchema, err := arrow.SchemaFor(columns, schema)
+	if err != nil {
+		err = errors.Internal("unable to create arrow schema", err)
+		writeError(w, err)
+		return err
+	}
+
+	record, err := arrow.RecordOf(arrowSchema, page.Columns)
+	if err != nil {
+		err = errors.Internal("unable to create arrow record", err)
+		writeError(w, err)
+		return err
+	}
+	defer record.Release()
+
+	if nextToken != nil {
+		w.Header().Set(nextTokenHeader, base64.StdEncoding.EncodeToString(nextToken))
+	}
+
+	w.Header().Set("Content-Type", arrow.ContentType)
+	return arrow.WriteStream(w, arrowSchema, record)
+}
+
+// rowsOf transposes the set of columns into a set of rows
+func rowsOf(columns []presto.Column) [][]interface{} {
+	count := countOf(columns)
+	rows := make([][]interface{}, count)
+	for i := 0; i < count; i++ {
+		rows[i] = make([]interface{}, len(columns))
+	}
+
+	for j, c := range columns {
+		_ = c.Range(0, count, func(i int, v interface{}) error {
+			if t, ok := v.(time.Time); ok {
+				v = t.UTC().Format(time.RFC3339)
+			}
+
+			rows[i][j] = v
+			return nil
+		})
+	}
+	return rows
+}
+
+// countOf returns the number of rows in a set of columns
+func countOf(columns []presto.Column) int {
+	if len(columns) == 0 {
+		return 0
+	}
+	return columns[0].Count()
+}
+
+// writeJSON writes the response as JSON
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error as JSON, with the appropriate HTTP status
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if e, ok := err.(*errors.Error); ok {
+		status = e.HTTP()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"error": err.Error(),
+	})
+}
@@ -0,0 +1,141 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/kelindar/talaria/internal/config"
+	"github.com/kelindar/talaria/internal/encoding/arrow"
+	"github.com/kelindar/talaria/internal/monitor"
+	script "github.com/kelindar/talaria/internal/scripting"
+	"github.com/kelindar/talaria/internal/storage/disk"
+	"github.com/kelindar/talaria/internal/storage/writer"
+	"github.com/kelindar/talaria/internal/table/timeseries"
+	talaria "github.com/kelindar/talaria/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+const testTable = "eventlog"
+
+type noopMembership int
+
+func (m noopMembership) Members() []string {
+	return []string{"127.0.0.1"}
+}
+
+type testConfigurer struct{}
+
+func (m *testConfigurer) Configure(c *config.Config) error {
+	c.Readers.Presto = &config.Presto{Port: 8042, Schema: "talaria"}
+	c.Readers.HTTP = &config.HTTP{Port: 8044}
+	c.Readers.Flight = &config.Flight{Port: 8045}
+	return nil
+}
+
+// runServer creates a server with a single table and some data ingested
+func runServer(t *testing.T, test func(s *Server)) {
+	dir, _ := ioutil.TempDir(".", "testdata-")
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	tableConf := config.Table{HashBy: "string1", SortBy: "int1", TTL: 3600}
+	monitor := monitor.NewNoop()
+	store := disk.Open(dir, testTable, monitor, config.Badger{})
+	streams, _ := writer.ForStreaming(config.Streams{}, monitor, nil)
+	eventlog := timeseries.New(testTable, new(noopMembership), monitor, store, &tableConf, streams)
+	defer eventlog.Close()
+
+	conf := config.Load(context.Background(), 60*time.Second, &testConfigurer{})
+	server := New(conf, monitor, script.NewLoader(nil), eventlog)
+
+	orcfile, err := ioutil.ReadFile("../../test/test3.orc")
+	assert.NoError(t, err)
+	_, err = server.Ingest(context.Background(), &talaria.IngestRequest{
+		Data: &talaria.IngestRequest_Orc{Orc: orcfile},
+	})
+	assert.NoError(t, err)
+	test(server)
+}
+
+func TestHTTP_Query(t *testing.T) {
+	runServer(t, func(s *Server) {
+		handler := s.newHTTPHandler()
+
+		// Describe the tables
+		{
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/describe", nil))
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Contains(t, rec.Body.String(), testTable)
+		}
+
+		// Get the splits
+		var splits talaria.GetSplitsResponse
+		{
+			body, _ := json.Marshal(&talaria.GetSplitsRequest{
+				Table:   testTable,
+				Columns: []string{"string1", "int1"},
+				Filters: []string{"string1 == '110010100101010010101000100001'"},
+			})
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/splits", bytes.NewReader(body)))
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &splits))
+			assert.Len(t, splits.Splits, 1)
+			assert.Equal(t, int32(8044), splits.Splits[0].Hosts[0].Port)
+		}
+
+		// Get the rows as JSON
+		body, _ := json.Marshal(&RowsRequest{
+			SplitID: splits.Splits[0].SplitID,
+			Columns: []string{"string1", "int1"},
+		})
+		{
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/rows", bytes.NewReader(body)))
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			var rows RowsResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rows))
+			assert.Equal(t, []string{"string1", "int1"}, rows.Columns)
+			assert.Greater(t, rows.RowCount, 0)
+			assert.Len(t, rows.Rows, rows.RowCount)
+			assert.Equal(t, "110010100101010010101000100001", rows.Rows[0][0])
+		}
+
+		// Get the rows as an arrow stream
+		{
+			req := httptest.NewRequest(http.MethodPost, "/v1/rows", bytes.NewReader(body))
+			req.Header.Set("Accept", arrow.ContentType)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, arrow.ContentType, rec.Header().Get("Content-Type"))
+
+			rdr, err := ipc.NewReader(rec.Body)
+			assert.NoError(t, err)
+			assert.True(t, rdr.Next())
+			assert.Greater(t, rdr.Record().NumRows(), int64(0))
+			rdr.Release()
+		}
+	})
+}
+
+func TestHTTP_BadRequest(t *testing.T) {
+	runServer(t, func(s *Server) {
+		rec := httptest.NewRecorder()
+		s.newHTTPHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/rows", bytes.NewReader([]byte("{"))))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
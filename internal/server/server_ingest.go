@@ -6,14 +6,23 @@ package server
 import (
 	"context"
 	"fmt"
+	"math"
+	"strconv"
+	"time"
 
 	"github.com/kelindar/talaria/internal/encoding/block"
 	"github.com/kelindar/talaria/internal/encoding/typeof"
 	"github.com/kelindar/talaria/internal/monitor/errors"
+	"github.com/kelindar/talaria/internal/monitor/tracing"
+	"github.com/kelindar/talaria/internal/server/auth"
+	"github.com/kelindar/talaria/internal/server/limit"
 	"github.com/kelindar/talaria/internal/storage"
 	"github.com/kelindar/talaria/internal/storage/stream"
 	"github.com/kelindar/talaria/internal/table"
 	talaria "github.com/kelindar/talaria/proto"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 // applyFunc applies a transformation on a row and returns a new row
@@ -22,47 +31,147 @@ type applyFunc = func(block.Row) (block.Row, error)
 const ingestErrorKey = "ingest.error"
 
 // Ingest implements ingress.IngressServer
-func (s *Server) Ingest(ctx context.Context, request *talaria.IngestRequest) (*talaria.IngestResponse, error) {
+func (s *Server) Ingest(ctx context.Context, request *talaria.IngestRequest) (_ *talaria.IngestResponse, err error) {
 	defer s.handlePanic()
+	ctx, span := tracing.Start(ctx, "ingest")
+	defer func() { tracing.End(span, err) }()
 
-	// Iterate through all of the appenders and append the blocks to them
+	// Convert the request for every table which can be written
+	var denied error
+	var pending []*ingestion
 	for _, t := range s.tables {
 		appender, ok := t.(table.Appender)
 		if !ok {
 			continue
 		}
 
+		// Only ingest into the tables which can be written
+		if err := s.auth.Authorize(ctx, auth.Write, t.Name()); err != nil {
+			denied = err
+			continue
+		}
+
 		// Set the filter only if the schema is static
 		var filter *typeof.Schema
 		if schema, static := t.Schema(); static {
 			filter = &schema
 		}
 
-		// Functions to be applied
-		funcs := []applyFunc{block.Transform(filter, s.computed...)}
-
-		// If table supports streaming, add publishing function
+		// Functions to be applied, the rows are counted and are only streamed once admitted
+		next := &ingestion{table: t, appender: appender}
+		funcs := []applyFunc{next.timed(block.Transform(filter, s.computed...)), next.collect}
 		if streamer, ok := t.(storage.Streamer); ok {
-			funcs = append(funcs, stream.Publish(streamer, s.monitor))
+			next.publish = stream.Publish(streamer, s.monitor)
 		}
 
-		// Partition the request for the table
+		// Partition the request for the table, the rows are transformed while being converted
+		_, convert := tracing.Start(ctx, "ingest.convert", tracing.Table(t.Name()))
 		blocks, err := block.FromRequestBy(request, appender.HashBy(), filter, funcs...)
+		convert.SetAttributes(
+			attribute.Int("rows", next.count),
+			attribute.Int64("transform.duration_ns", int64(next.transform)),
+		)
+		tracing.End(convert, err)
 		if err != nil {
 			s.monitor.Count1(ctxTag, ingestErrorKey, "type:convert")
 			return nil, errors.Internal("unable to read the block", err)
 		}
 
-		// Append all of the blocks
-		for _, block := range blocks {
-			if err := appender.Append(block); err != nil {
-				s.monitor.Count1(ctxTag, ingestErrorKey, "type:append")
-				return nil, err
+		next.blocks = blocks
+		pending = append(pending, next)
+	}
+
+	// If none of the tables could be written, the principal is not allowed to ingest
+	if len(pending) == 0 && denied != nil {
+		s.monitor.Count1(ctxTag, ingestErrorKey, "type:auth")
+		return nil, denied
+	}
+
+	// Check the rate limits of the tables and of the client
+	if err := s.admit(ctx, pending); err != nil {
+		s.monitor.Count1(ctxTag, ingestErrorKey, "type:limit")
+		return nil, err
+	}
+
+	// Stream and append all of the blocks
+	for _, next := range pending {
+		if next.publish != nil {
+			for _, row := range next.rows {
+				_, _ = next.publish(row)
 			}
 		}
 
-		s.monitor.Count("server", fmt.Sprintf("%s.ingest.count", t.Name()), int64(len(blocks)))
+		if err := next.append(ctx); err != nil {
+			s.monitor.Count1(ctxTag, ingestErrorKey, "type:append")
+			return nil, err
+		}
+
+		s.monitor.Count("server", fmt.Sprintf("%s.ingest.count", next.table.Name()), int64(len(next.blocks)))
 	}
 
 	return nil, nil
 }
+
+// admit checks the ingestion rate limits, the requests from the server itself are not limited
+func (s *Server) admit(ctx context.Context, pending []*ingestion) error {
+	if p, ok := auth.PrincipalOf(ctx); ok && p == auth.Internal {
+		return nil
+	}
+
+	usage := make(map[string]limit.Usage, len(pending))
+	for _, next := range pending {
+		u := limit.Usage{Rows: next.count}
+		for _, b := range next.blocks {
+			u.Bytes += int(b.Size)
+		}
+		usage[next.table.Name()] = u
+	}
+
+	delay, err := s.limit.Admit(limit.ClientOf(ctx), usage)
+	if err != nil {
+		_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(int(math.Ceil(delay.Seconds())))))
+	}
+	return err
+}
+
+// ingestion represents the data of a request to be ingested into a table
+type ingestion struct {
+	table     table.Table    // The table to ingest into
+	appender  table.Appender // The appender of the table
+	blocks    []block.Block  // The blocks to append
+	publish   applyFunc      // The function which streams a row (optional)
+	rows      []block.Row    // The rows to stream
+	count     int            // The number of rows
+	transform time.Duration  // The time spent transforming the rows
+}
+
+// append appends the blocks to the table
+func (i *ingestion) append(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "ingest.append", tracing.Table(i.table.Name()), attribute.Int("blocks", len(i.blocks)))
+	defer func() { tracing.End(span, err) }()
+
+	for _, block := range i.blocks {
+		if err = i.appender.Append(ctx, block); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// timed measures the time spent in a function
+func (i *ingestion) timed(f applyFunc) applyFunc {
+	return func(r block.Row) (block.Row, error) {
+		start := time.Now()
+		defer func() { i.transform += time.Since(start) }()
+		return f(r)
+	}
+}
+
+// collect counts the rows and keeps them for streaming
+func (i *ingestion) collect(r block.Row) (block.Row, error) {
+	i.count++
+	if i.publish != nil {
+		i.rows = append(i.rows, r)
+	}
+	return r, nil
+}
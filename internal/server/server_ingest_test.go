@@ -0,0 +1,45 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/kelindar/talaria/internal/config"
+	"github.com/kelindar/talaria/internal/monitor"
+	"github.com/kelindar/talaria/internal/monitor/errors"
+	"github.com/kelindar/talaria/internal/server/auth"
+	"github.com/kelindar/talaria/internal/server/limit"
+	talaria "github.com/kelindar/talaria/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestIngest_Limit(t *testing.T) {
+	runServer(t, func(s *Server) {
+		conf := &config.Config{
+			Tables: config.Tables{testTable: {Limit: &config.Limit{Rows: 1}}},
+		}
+		s.limit = limit.New(func() *config.Config { return conf }, monitor.NewNoop())
+
+		orcfile, err := ioutil.ReadFile("../../test/test3.orc")
+		assert.NoError(t, err)
+		request := &talaria.IngestRequest{Data: &talaria.IngestRequest_Orc{Orc: orcfile}}
+
+		// The first request drains the bucket, the second one is rejected
+		_, err = s.Ingest(context.Background(), request)
+		assert.NoError(t, err)
+		_, err = s.Ingest(context.Background(), request)
+		require.Error(t, err)
+		require.IsType(t, new(errors.Error), err)
+		assert.Equal(t, codes.ResourceExhausted, err.(*errors.Error).GRPC())
+
+		// The ingestion from the server itself is not limited
+		_, err = s.Ingest(auth.WithPrincipal(context.Background(), auth.Internal), request)
+		assert.NoError(t, err)
+	})
+}
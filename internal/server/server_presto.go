@@ -4,19 +4,53 @@
 package server
 
 import (
+	"context"
 	"time"
 
 	"github.com/kelindar/talaria/internal/monitor/errors"
 	"github.com/kelindar/talaria/internal/presto"
+	"github.com/kelindar/talaria/internal/server/auth"
 )
 
+// thriftConn represents the thrift service of a connection, with a context which carries the principal
+// identified by the client certificate of the connection and which is cancelled once the connection is closed.
+type thriftConn struct {
+	*Server
+	ctx context.Context
+}
+
+// PrestoGetSplits returns a batch of splits.
+func (s *Server) PrestoGetSplits(schemaTableName *presto.PrestoThriftSchemaTableName, desiredColumns *presto.PrestoThriftNullableColumnSet, outputConstraint *presto.PrestoThriftTupleDomain, maxSplitCount int32, nextToken *presto.PrestoThriftNullableToken) (*presto.PrestoThriftSplitBatch, error) {
+	return s.thrift().PrestoGetSplits(schemaTableName, desiredColumns, outputConstraint, maxSplitCount, nextToken)
+}
+
+// PrestoGetTableMetadata returns metadata for a given table.
+func (s *Server) PrestoGetTableMetadata(schemaTableName *presto.PrestoThriftSchemaTableName) (*presto.PrestoThriftNullableTableMetadata, error) {
+	return s.thrift().PrestoGetTableMetadata(schemaTableName)
+}
+
+// PrestoListTables returns tables for the given schema name.
+func (s *Server) PrestoListTables(schemaNameOrNull *presto.PrestoThriftNullableSchemaName) ([]*presto.PrestoThriftSchemaTableName, error) {
+	return s.thrift().PrestoListTables(schemaNameOrNull)
+}
+
+// PrestoGetRows returns a batch of rows for the given split.
+func (s *Server) PrestoGetRows(splitID *presto.PrestoThriftId, columns []string, maxBytes int64, nextToken *presto.PrestoThriftNullableToken) (*presto.PrestoThriftPageResult, error) {
+	return s.thrift().PrestoGetRows(splitID, columns, maxBytes, nextToken)
+}
+
+// thrift returns the thrift service outside of a connection, which is anonymous
+func (s *Server) thrift() *thriftConn {
+	return &thriftConn{Server: s, ctx: context.Background()}
+}
+
 // PrestoGetIndexSplits returns a batch of index splits for the given batch of keys.
 func (s *Server) PrestoGetIndexSplits(schemaTableName *presto.PrestoThriftSchemaTableName, indexColumnNames []string, outputColumnNames []string, keys *presto.PrestoThriftPageResult, outputConstraint *presto.PrestoThriftTupleDomain, maxSplitCount int32, nextToken *presto.PrestoThriftNullableToken) (*presto.PrestoThriftSplitBatch, error) {
 	return nil, nil
 }
 
 // PrestoGetSplits returns a batch of splits.
-func (s *Server) PrestoGetSplits(schemaTableName *presto.PrestoThriftSchemaTableName, desiredColumns *presto.PrestoThriftNullableColumnSet, outputConstraint *presto.PrestoThriftTupleDomain, maxSplitCount int32, nextToken *presto.PrestoThriftNullableToken) (*presto.PrestoThriftSplitBatch, error) {
+func (s *thriftConn) PrestoGetSplits(schemaTableName *presto.PrestoThriftSchemaTableName, desiredColumns *presto.PrestoThriftNullableColumnSet, outputConstraint *presto.PrestoThriftTupleDomain, maxSplitCount int32, nextToken *presto.PrestoThriftNullableToken) (*presto.PrestoThriftSplitBatch, error) {
 	defer s.handlePanic()
 	defer s.monitor.Duration(ctxTag, funcTag, time.Now(), "func:get_splits")
 
@@ -26,6 +60,11 @@ func (s *Server) PrestoGetSplits(schemaTableName *presto.PrestoThriftSchemaTable
 		return nil, err
 	}
 
+	// Check whether the table can be read
+	if err := s.auth.Authorize(s.ctx, auth.Read, table.Name()); err != nil {
+		return nil, err
+	}
+
 	// Retrieve desired columns
 	var columns []string
 	if desiredColumns != nil && desiredColumns.Columns != nil {
@@ -57,7 +96,7 @@ func (s *Server) PrestoGetSplits(schemaTableName *presto.PrestoThriftSchemaTable
 }
 
 // PrestoGetTableMetadata returns metadata for a given table.
-func (s *Server) PrestoGetTableMetadata(schemaTableName *presto.PrestoThriftSchemaTableName) (*presto.PrestoThriftNullableTableMetadata, error) {
+func (s *thriftConn) PrestoGetTableMetadata(schemaTableName *presto.PrestoThriftSchemaTableName) (*presto.PrestoThriftNullableTableMetadata, error) {
 	defer s.handlePanic()
 	defer s.monitor.Duration(ctxTag, funcTag, time.Now(), "func:get_table_metadata")
 
@@ -67,6 +106,11 @@ func (s *Server) PrestoGetTableMetadata(schemaTableName *presto.PrestoThriftSche
 		return nil, err
 	}
 
+	// Check whether the table can be read
+	if err := s.auth.Authorize(s.ctx, auth.Read, table.Name()); err != nil {
+		return nil, err
+	}
+
 	// Load the schema
 	schema, _ := table.Schema()
 
@@ -97,13 +141,17 @@ func (s *Server) PrestoListSchemaNames() ([]string, error) {
 }
 
 // PrestoListTables returns tables for the given schema name.
-func (s *Server) PrestoListTables(schemaNameOrNull *presto.PrestoThriftNullableSchemaName) ([]*presto.PrestoThriftSchemaTableName, error) {
+func (s *thriftConn) PrestoListTables(schemaNameOrNull *presto.PrestoThriftNullableSchemaName) ([]*presto.PrestoThriftSchemaTableName, error) {
 	defer s.handlePanic()
 	defer s.monitor.Duration(ctxTag, funcTag, time.Now(), "func:get_tables")
 
-	// Return all of the tables configured in the server
+	// Return the tables configured in the server which can be read
 	tables := make([]*presto.PrestoThriftSchemaTableName, 0, len(s.tables))
 	for _, table := range s.tables {
+		if s.auth.Authorize(s.ctx, auth.Read, table.Name()) != nil {
+			continue
+		}
+
 		tables = append(tables, &presto.PrestoThriftSchemaTableName{
 			SchemaName: s.conf().Readers.Presto.Schema,
 			TableName:  table.Name(),
@@ -113,7 +161,7 @@ func (s *Server) PrestoListTables(schemaNameOrNull *presto.PrestoThriftNullableS
 }
 
 // PrestoGetRows returns a batch of rows for the given split.
-func (s *Server) PrestoGetRows(splitID *presto.PrestoThriftId, columns []string, maxBytes int64, nextToken *presto.PrestoThriftNullableToken) (*presto.PrestoThriftPageResult, error) {
+func (s *thriftConn) PrestoGetRows(splitID *presto.PrestoThriftId, columns []string, maxBytes int64, nextToken *presto.PrestoThriftNullableToken) (*presto.PrestoThriftPageResult, error) {
 	defer s.handlePanic()
 	defer s.monitor.Duration(ctxTag, funcTag, time.Now(), "func:get_rows")
 
@@ -129,11 +177,17 @@ func (s *Server) PrestoGetRows(splitID *presto.PrestoThriftId, columns []string,
 		return nil, errors.Internal("unable to retrieve a table", err)
 	}
 
-	// Retrieve the rows for the table
+	// Check whether the table can be read
+	if err := s.auth.Authorize(s.ctx, auth.Read, table.Name()); err != nil {
+		return nil, err
+	}
+
+	// Retrieve the rows for the table, thrift carries no cancellation so the scan is only stopped
+	// once the connection is closed or by the deadline of the scheduler
 	result := new(presto.PrestoThriftPageResult)
-	page, err := table.GetRows(id.Split, columns, maxBytes)
+	page, err := s.scan(s.ctx, table, id.Split, columns, maxBytes)
 	if err != nil {
-		return nil, errors.Internal("unable to get rows from a table", err)
+		return nil, err
 	}
 
 	// If a page has a token, we need to create a split to continue iterating
@@ -8,9 +8,12 @@ import (
 	"time"
 
 	"github.com/kelindar/talaria/internal/monitor/errors"
+	"github.com/kelindar/talaria/internal/monitor/tracing"
 	"github.com/kelindar/talaria/internal/presto"
+	"github.com/kelindar/talaria/internal/server/auth"
 	"github.com/kelindar/talaria/internal/table"
 	talaria "github.com/kelindar/talaria/proto"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Describe returns the list of schema/table combinations and the metadata
@@ -20,6 +23,10 @@ func (s *Server) Describe(ctx context.Context, _ *talaria.DescribeRequest) (*tal
 
 	tables := make([]*talaria.TableMeta, 0, len(s.tables))
 	for _, table := range s.tables {
+		if s.auth.Authorize(ctx, auth.Read, table.Name()) != nil {
+			continue // Only describe the tables which can be read
+		}
+
 		schema, _ := table.Schema()
 
 		// Populate the column metadata
@@ -54,6 +61,11 @@ func (s *Server) GetSplits(ctx context.Context, request *talaria.GetSplitsReques
 		return nil, err
 	}
 
+	// Check whether the table can be read
+	if err := s.auth.Authorize(ctx, auth.Read, table.Name()); err != nil {
+		return nil, err
+	}
+
 	// Build the domain
 	domain, err := presto.NewDomain(table.HashBy(), table.SortBy(), request.Filters...)
 	if err != nil {
@@ -61,11 +73,20 @@ func (s *Server) GetSplits(ctx context.Context, request *talaria.GetSplitsReques
 	}
 
 	// Get the splits
+	_, span := tracing.Start(ctx, "query.splits", tracing.Table(table.Name()))
 	splits, err := table.GetSplits(request.Columns, domain, int(request.MaxSplits))
+	span.SetAttributes(attribute.Int("splits", len(splits)))
+	tracing.End(span, err)
 	if err != nil {
 		return nil, err
 	}
 
+	// The rows need to be retrieved from the gRPC listener of each host
+	port := s.conf().Readers.Presto.Port
+	if grpc := s.conf().Writers.GRPC; grpc != nil {
+		port = grpc.Port
+	}
+
 	// Prepare the response
 	response := new(talaria.GetSplitsResponse)
 	for _, split := range splits {
@@ -75,7 +96,7 @@ func (s *Server) GetSplits(ctx context.Context, request *talaria.GetSplitsReques
 		}
 
 		for _, addr := range split.Addrs {
-			tsplit.Hosts = append(tsplit.Hosts, &talaria.Endpoint{Host: addr, Port: s.conf().Readers.Presto.Port})
+			tsplit.Hosts = append(tsplit.Hosts, &talaria.Endpoint{Host: addr, Port: port})
 		}
 		response.Splits = append(response.Splits, &tsplit)
 	}
@@ -88,36 +109,54 @@ func (s *Server) GetRows(ctx context.Context, request *talaria.GetRowsRequest) (
 	defer s.handlePanic()
 	defer s.monitor.Duration(ctxTag, funcTag, time.Now(), "func:get_rows")
 
-	// Parse the incoming thriftID
-	id, err := decodeID(request.SplitID, request.NextToken)
+	// Retrieve the rows for the table
+	page, nextToken, err := s.getRows(ctx, request.SplitID, request.NextToken, request.Columns, request.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return the result set
+	result := &talaria.GetRowsResponse{NextToken: nextToken}
+	for _, b := range page.Columns {
+		result.Columns = append(result.Columns, b.AsProto())
+		result.RowCount = int32(b.Count())
+	}
+	return result, nil
+}
+
+// getRows decodes the split, retrieves a page of rows and returns it along with an encoded next token
+func (s *Server) getRows(ctx context.Context, splitID, token []byte, columns []string, maxBytes int64) (*table.PageResult, []byte, error) {
+
+	// Parse the incoming split ID
+	id, err := decodeID(splitID, token)
 	if err != nil {
-		return nil, errors.Internal("decoding query failed", err)
+		return nil, nil, errors.Internal("decoding query failed", err)
 	}
 
 	// Retrieve the table
 	table, err := s.getTable(id.Table)
 	if err != nil {
-		return nil, errors.Internal("unable to retrieve a table", err)
+		return nil, nil, errors.Internal("unable to retrieve a table", err)
+	}
+
+	// Check whether the table can be read
+	if err := s.auth.Authorize(ctx, auth.Read, table.Name()); err != nil {
+		return nil, nil, err
 	}
 
 	// Retrieve the rows for the table
-	result := new(talaria.GetRowsResponse)
-	page, err := table.GetRows(id.Split, request.Columns, request.MaxBytes)
+	page, err := s.scan(ctx, table, id.Split, columns, maxBytes)
 	if err != nil {
-		return nil, errors.Internal("unable to get rows from a table", err)
+		return nil, nil, err
 	}
 
 	// If a page has a token, we need to create a split to continue iterating
+	var nextToken []byte
 	if page.NextToken != nil {
-		result.NextToken = encodeID(table.Name(), page.NextToken)
+		nextToken = encodeID(table.Name(), page.NextToken)
 	}
 
-	// Return the result set
-	for _, b := range page.Columns {
-		result.Columns = append(result.Columns, b.AsProto())
-		result.RowCount = int32(b.Count())
-	}
-	return result, nil
+	return page, nextToken, nil
 }
 
 // getTable returns the table or errors out
@@ -128,3 +167,21 @@ func (s *Server) getTable(name string) (table.Table, error) {
 	}
 	return table, nil
 }
+
+// scan retrieves a page of rows of a table, once the scheduler allows it
+func (s *Server) scan(ctx context.Context, t table.Table, split []byte, columns []string, maxBytes int64) (page *table.PageResult, err error) {
+	ctx, span := tracing.Start(ctx, "query.scan", tracing.Table(t.Name()))
+	defer func() { tracing.End(span, err) }()
+
+	err = s.sched.Run(ctx, t.Name(), func(ctx context.Context) (err error) {
+		page, err = t.GetRows(ctx, split, columns, maxBytes)
+		return
+	})
+
+	switch err.(type) {
+	case nil, *errors.Error:
+		return page, err
+	default:
+		return nil, errors.Internal("unable to get rows from a table", err)
+	}
+}
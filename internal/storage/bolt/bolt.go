@@ -0,0 +1,220 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"path"
+	"sync/atomic"
+	"time"
+
+	"github.com/grab/async"
+	"github.com/kelindar/talaria/internal/config"
+	"github.com/kelindar/talaria/internal/encoding/key"
+	"github.com/kelindar/talaria/internal/monitor"
+	"github.com/kelindar/talaria/internal/monitor/errors"
+	"github.com/kelindar/talaria/internal/storage"
+	"go.etcd.io/bbolt"
+)
+
+const (
+	ctxTag    = "bolt"
+	errClosed = "unable to run commands on a closed database"
+)
+
+// The bucket which contains the data, the values are prefixed with their expiration time
+var bucket = []byte("data")
+
+// Assert contract compliance
+var _ storage.Storage = new(Storage)
+var _ storage.ContextIterator = new(Storage)
+
+// Storage represents a disk storage which internally uses a bbolt B+tree.
+type Storage struct {
+	closed  int32           // The closed flag
+	gc      async.Task      // Closing channel
+	db      *bbolt.DB       // The underlying key-value store
+	monitor monitor.Monitor // The stats client
+}
+
+// Open opens or creates a bbolt database in the directory and starts the garbage collection.
+func Open(dir string, options config.Bolt, monitor monitor.Monitor) (*Storage, error) {
+	if dir == "" {
+		dir = "/data"
+	}
+
+	// Make sure we have a directory
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+
+	db, err := bbolt.Open(path.Join(dir, "data.bolt"), 0666, &bbolt.Options{
+		Timeout:      time.Second,
+		NoSync:       options.NoSync,
+		FreelistType: bbolt.FreelistMapType,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	}); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	s := &Storage{db: db, monitor: monitor}
+	s.gc = async.Repeat(context.Background(), 1*time.Minute, s.GC)
+	return s, nil
+}
+
+// Append adds an event into the storage.
+func (s *Storage) Append(key key.Key, value []byte, ttl time.Duration) error {
+	if s.isClosed() {
+		return errors.New(errClosed)
+	}
+
+	// Prefix the value with its expiration time
+	entry := make([]byte, 8+len(value))
+	binary.BigEndian.PutUint64(entry, uint64(time.Now().Add(ttl).Unix()))
+	copy(entry[8:], value)
+
+	// Batch the concurrent appends into a single transaction
+	if err := s.db.Batch(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucket).Put(key, entry)
+	}); err != nil {
+		return errors.Internal("unable to append", err)
+	}
+	return nil
+}
+
+// Range performs a range query against the storage. It calls f sequentially for each key and value present in
+// the store. If f returns true, range stops the iteration. The keys are lexigraphically sorted and the expired
+// items are skipped.
+func (s *Storage) Range(seek, until key.Key, f func(key, value []byte) bool) error {
+	return s.RangeContext(context.Background(), seek, until, f)
+}
+
+// RangeContext performs a range query against the storage, which stops as soon as the context is cancelled
+// and returns the error of the context.
+func (s *Storage) RangeContext(ctx context.Context, seek, until key.Key, f func(key, value []byte) bool) error {
+	keys, values, err := s.find(seek, until)
+	if err != nil {
+		return err
+	}
+
+	for i := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if f(keys[i], values[i]) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// find returns the keys and values which have not expired within the range, so the callback is
+// invoked once the read transaction is released.
+func (s *Storage) find(seek, until key.Key) (keys, values [][]byte, err error) {
+	if s.isClosed() {
+		return nil, nil, errors.New(errClosed)
+	}
+
+	now := uint64(time.Now().Unix())
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		cursor := tx.Bucket(bucket).Cursor()
+		for k, v := cursor.Seek(seek); k != nil; k, v = cursor.Next() {
+			if bytes.Compare(k, until) > 0 {
+				return nil // Stop if we're reached the end
+			}
+
+			// The memory is only valid within the transaction, so copy it
+			if len(v) >= 8 && binary.BigEndian.Uint64(v) > now {
+				keys = append(keys, append([]byte{}, k...))
+				values = append(values, append([]byte{}, v[8:]...))
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// Delete deletes one or multiple keys from the storage.
+func (s *Storage) Delete(keys ...key.Key) error {
+	if s.isClosed() {
+		return errors.New(errClosed)
+	}
+
+	if err := s.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(bucket)
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return errors.Internal("unable to delete", err)
+	}
+	return nil
+}
+
+// GC runs the garbage collection on the storage, removing the expired items
+func (s *Storage) GC(ctx context.Context) (interface{}, error) {
+	if s.gc != nil && s.gc.State() == async.IsCancelled {
+		return nil, nil
+	}
+
+	deleted, total := s.purge()
+	s.monitor.Gauge(ctxTag, "GC.purge", float64(deleted), "type:deleted")
+	s.monitor.Gauge(ctxTag, "GC.purge", float64(total), "type:total")
+	return nil, nil
+}
+
+// purge removes the expired items from the storage
+func (s *Storage) purge() (deleted, total int) {
+	if s.isClosed() {
+		return
+	}
+
+	var expired []key.Key
+	now := uint64(time.Now().Unix())
+	_ = s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucket).ForEach(func(k, v []byte) error {
+			total++
+			if len(v) < 8 || binary.BigEndian.Uint64(v) <= now {
+				expired = append(expired, append([]byte{}, k...))
+			}
+			return nil
+		})
+	})
+
+	if err := s.Delete(expired...); err != nil {
+		s.monitor.Error(err)
+		return 0, total
+	}
+	return len(expired), total
+}
+
+// Close is used to gracefully close the database.
+func (s *Storage) Close() error {
+	if s.gc != nil {
+		s.gc.Cancel()
+	}
+
+	atomic.StoreInt32(&s.closed, 1)
+	return s.db.Close()
+}
+
+// isClosed checks if the DB is closed or not.
+func (s *Storage) isClosed() bool {
+	return atomic.LoadInt32(&s.closed) == int32(1)
+}
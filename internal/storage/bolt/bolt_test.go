@@ -0,0 +1,72 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package bolt
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kelindar/talaria/internal/config"
+	"github.com/kelindar/talaria/internal/encoding/key"
+	"github.com/kelindar/talaria/internal/monitor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGC(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bolt")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	store, err := Open(dir, config.Bolt{}, monitor.NewNoop())
+	assert.NoError(t, err)
+
+	assert.NoError(t, store.Append(key.Key("1"), []byte("A"), -time.Minute))
+	assert.NoError(t, store.Append(key.Key("2"), []byte("B"), time.Minute))
+
+	_, err = store.GC(context.Background())
+	assert.NoError(t, err)
+
+	// Only the item which has not expired is left
+	deleted, total := store.purge()
+	assert.Equal(t, 0, deleted)
+	assert.Equal(t, 1, total)
+
+	// The data survives a restart
+	assert.NoError(t, store.Close())
+	store, err = Open(dir, config.Bolt{}, monitor.NewNoop())
+	assert.NoError(t, err)
+	defer store.Close()
+
+	var values []string
+	assert.NoError(t, store.Range(key.First(), key.Last(), func(k, v []byte) bool {
+		values = append(values, string(v))
+		return false
+	}))
+	assert.Equal(t, []string{"B"}, values)
+}
+
+func TestRange_Delete(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bolt")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	store, err := Open(dir, config.Bolt{}, monitor.NewNoop())
+	assert.NoError(t, err)
+	defer store.Close()
+
+	assert.NoError(t, store.Append(key.Key("1"), []byte("A"), time.Minute))
+	assert.NoError(t, store.Append(key.Key("2"), []byte("B"), time.Minute))
+
+	// The callback can write to the store, since the transaction is released
+	assert.NoError(t, store.Range(key.First(), key.Last(), func(k, v []byte) bool {
+		assert.NoError(t, store.Delete(k))
+		return false
+	}))
+
+	_, total := store.purge()
+	assert.Equal(t, 0, total)
+}
@@ -0,0 +1,383 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package cold
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kelindar/talaria/internal/encoding/block"
+	"github.com/kelindar/talaria/internal/encoding/key"
+	"github.com/kelindar/talaria/internal/monitor"
+	"github.com/kelindar/talaria/internal/monitor/errors"
+	"github.com/kelindar/talaria/internal/storage"
+)
+
+// Assert contract compliance
+var _ storage.Iterator = new(Storage)
+var _ storage.ContextIterator = new(Storage)
+
+const (
+	ctxTag    = "cold"
+	dayLayout = "year=2006/month=1/day=2"
+	maxDays   = 31       // The maximum number of date partitions listed, otherwise every file is listed
+	cacheSize = 64 << 20 // The maximum size of the cached contents of the files
+)
+
+// Source represents a sink whose files can be listed and read back.
+type Source interface {
+	List(prefix string) ([]string, error)
+	Read(name string) ([]byte, error)
+}
+
+// Storage represents a read-only cold tier which reads the compacted files back from a sink. The files are
+// listed and pruned by their date partition, the date at which they were written, and once read, by the
+// hashes and the time range they contain. The contents of the files which were read are cached.
+type Storage struct {
+	source  Source             // The sink containing the compacted files
+	format  string             // The format of the files (e.g. "orc" or "parquet")
+	dated   bool               // Whether the files are written under the date partition of the time they are written
+	hashBy  string             // The column used to compute the hash of the keys
+	sortBy  string             // The column used to compute the time of the keys
+	monitor monitor.Monitor    // The monitor client
+	lock    sync.Mutex         // The lock for the summaries and the cache
+	summary map[string]summary // The summaries of the files which were read
+	cache   *cache             // The contents of the files which were read
+}
+
+// summary represents the hashes and the time range of the blocks of a file
+type summary struct {
+	hashes   map[uint32]bool
+	min, max int64
+}
+
+// item represents a key-value pair
+type item struct {
+	key   key.Key
+	value []byte
+}
+
+// New creates a new cold tier for a sink containing files of a format. If the files are dated, they are
+// named after the date at which they were written (e.g. "year=2020/month=1/day=2/..."), which allows to
+// only list the dates which may contain the keys of a query.
+func New(source Source, format string, dated bool, hashBy, sortBy string, monitor monitor.Monitor) *Storage {
+	return &Storage{
+		source:  source,
+		format:  format,
+		dated:   dated,
+		hashBy:  hashBy,
+		sortBy:  sortBy,
+		monitor: monitor,
+		summary: make(map[string]summary),
+		cache:   newCache(cacheSize),
+	}
+}
+
+// Range performs a range query against the compacted files. It calls f sequentially for each key and value
+// present in the files, in lexicographic order of the keys. If f returns true, range stops the iteration.
+func (s *Storage) Range(seek, until key.Key, f func(key, value []byte) bool) error {
+	return s.RangeContext(context.Background(), seek, until, f)
+}
+
+// RangeContext performs a range query against the compacted files, which stops as soon as the context is
+// cancelled, checked before reading each of the files, and returns the error of the context.
+func (s *Storage) RangeContext(ctx context.Context, seek, until key.Key, f func(key, value []byte) bool) error {
+	names, err := s.list(key.TimeOf(seek).Unix())
+	if err != nil {
+		return err
+	}
+
+	var items []item
+	for _, name := range names {
+		if !s.mayContain(name, seek, until) {
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		found, err := s.read(name)
+		if err != nil {
+			s.monitor.Count1(ctxTag, "error", "type:read")
+			s.monitor.Warning(err)
+			continue
+		}
+
+		for _, it := range found {
+			if bytes.Compare(it.key, seek) >= 0 && bytes.Compare(it.key, until) <= 0 {
+				items = append(items, it)
+			}
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return bytes.Compare(items[i].key, items[j].key) < 0
+	})
+
+	for _, it := range items {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if f(it.key, it.value) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// list lists the files which may contain keys from a time onwards. Since a file is written after the data
+// it contains, only the date partitions from the day before up to today are listed, unless there are too
+// many of them (e.g. if the time of the keys is not in nanoseconds).
+func (s *Storage) list(from int64) ([]string, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	first := time.Unix(from, 0).UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+	if !s.dated || today.Sub(first) > maxDays*24*time.Hour {
+		names, err := s.source.List("")
+		if err == nil {
+			s.retain("", names)
+		}
+		return names, err
+	}
+
+	var names []string
+	for day := first; !day.After(today); day = day.AddDate(0, 0, 1) {
+		prefix := day.Format(dayLayout)
+		found, err := s.source.List(prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		s.retain(prefix+"/", found)
+		names = append(names, found...)
+	}
+	return names, nil
+}
+
+// retain forgets the files with a prefix which are no longer present, for example once aged into the next tier
+func (s *Storage) retain(prefix string, names []string) {
+	present := make(map[string]bool, len(names))
+	for _, name := range names {
+		present[name] = true
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for name := range s.summary {
+		if strings.HasPrefix(name, prefix) && !present[name] {
+			delete(s.summary, name)
+			s.cache.remove(name)
+		}
+	}
+}
+
+// mayContain returns whether a file may contain keys within the range
+func (s *Storage) mayContain(name string, seek, until key.Key) bool {
+	from, to := key.TimeOf(seek).Unix(), key.TimeOf(until).Unix()
+	if to < 0 {
+		to = math.MaxInt64 // The time of the last key overflows
+	}
+
+	// A file is written after the data it contains, so the files written before the range can be skipped
+	if day, ok := dateOf(name); ok && s.dated && day.AddDate(0, 0, 2).Unix() <= from {
+		return false
+	}
+
+	s.lock.Lock()
+	sum, ok := s.summary[name]
+	s.lock.Unlock()
+	if !ok {
+		return true
+	}
+
+	if sum.max < from || sum.min > to {
+		return false
+	}
+
+	// Queries are usually for a single hash
+	if bytes.Equal(seek[:4], until[:4]) {
+		return sum.hashes[key.HashOf(seek)]
+	}
+	return true
+}
+
+// read reads the blocks of a file, from the cache if it was recently read
+func (s *Storage) read(name string) ([]item, error) {
+	s.lock.Lock()
+	items, ok := s.cache.get(name)
+	s.lock.Unlock()
+	if ok {
+		s.monitor.Count1(ctxTag, "cache", "type:hit")
+		return items, nil
+	}
+
+	s.monitor.Count1(ctxTag, "cache", "type:miss")
+	items, sum, err := s.decode(name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	s.summary[name] = sum
+	s.cache.put(name, items)
+	s.lock.Unlock()
+	return items, nil
+}
+
+// decode decodes the blocks of a file and summarizes it
+func (s *Storage) decode(name string) (_ []item, _ summary, err error) {
+	payload, err := s.source.Read(name)
+	if err != nil {
+		return nil, summary{}, err
+	}
+
+	// Decoders may panic on a corrupted file
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Newf("cold: unable to decode %s: %v", name, r)
+		}
+	}()
+
+	blocks, err := block.FromFormatBy(s.format, payload, s.hashBy, nil, func(r block.Row) (block.Row, error) {
+		return r, nil
+	})
+	if err != nil {
+		return nil, summary{}, errors.Internal("cold: unable to decode "+name, err)
+	}
+
+	// Keys of the blocks must be deterministic, so that a query can be resumed from a key
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	seq := h.Sum32()
+
+	items := make([]item, 0, len(blocks))
+	sum := summary{hashes: make(map[uint32]bool, len(blocks)), min: -1}
+	for i, b := range blocks {
+		ts, ok := b.Min(s.sortBy)
+		if !ok || ts < 0 {
+			ts = 0
+		}
+
+		value, err := b.Encode()
+		if err != nil {
+			return nil, summary{}, err
+		}
+
+		k := key.WithSequence(string(b.Key), time.Unix(0, ts), seq+uint32(i))
+		items = append(items, item{key: k, value: value})
+
+		unix := key.TimeOf(k).Unix()
+		sum.hashes[key.HashOf(k)] = true
+		if sum.min < 0 || unix < sum.min {
+			sum.min = unix
+		}
+		if unix > sum.max {
+			sum.max = unix
+		}
+	}
+
+	return items, sum, nil
+}
+
+// dateOf returns the date of the hive-style date partition of a file, if present
+func dateOf(name string) (time.Time, bool) {
+	var year, month, day int
+	for _, part := range strings.Split(name, "/") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		v, err := strconv.Atoi(kv[1])
+		if err != nil {
+			continue
+		}
+
+		switch kv[0] {
+		case "year":
+			year = v
+		case "month":
+			month = v
+		case "day":
+			day = v
+		}
+	}
+
+	if year == 0 || month == 0 || day == 0 {
+		return time.Time{}, false
+	}
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC), true
+}
+
+// ------------------------------------------------------------------------------------------------------------
+
+// cache represents the least recently used contents of the files, up to a maximum size
+type cache struct {
+	size, capacity int                      // The current and maximum size of the contents
+	order          *list.List               // The names of the files, most recently used first
+	entries        map[string]*list.Element // The elements of the files, by name
+}
+
+// entry represents the contents of a file in the cache
+type entry struct {
+	name  string
+	items []item
+	size  int
+}
+
+// newCache creates a new cache of a maximum size
+func newCache(capacity int) *cache {
+	return &cache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// get returns the contents of a file
+func (c *cache) get(name string) ([]item, bool) {
+	e, ok := c.entries[name]
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToFront(e)
+	return e.Value.(*entry).items, true
+}
+
+// put adds the contents of a file, evicting the least recently used files
+func (c *cache) put(name string, items []item) {
+	c.remove(name)
+	size := 0
+	for _, it := range items {
+		size += len(it.key) + len(it.value)
+	}
+
+	if size > c.capacity {
+		return
+	}
+
+	c.entries[name] = c.order.PushFront(&entry{name: name, items: items, size: size})
+	for c.size += size; c.size > c.capacity; {
+		c.remove(c.order.Back().Value.(*entry).name)
+	}
+}
+
+// remove removes the contents of a file
+func (c *cache) remove(name string) {
+	if e, ok := c.entries[name]; ok {
+		c.order.Remove(e)
+		c.size -= e.Value.(*entry).size
+		delete(c.entries, name)
+	}
+}
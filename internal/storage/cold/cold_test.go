@@ -0,0 +1,226 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package cold
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kelindar/talaria/internal/column"
+	"github.com/kelindar/talaria/internal/config"
+	"github.com/kelindar/talaria/internal/encoding/block"
+	"github.com/kelindar/talaria/internal/encoding/key"
+	"github.com/kelindar/talaria/internal/encoding/merge"
+	"github.com/kelindar/talaria/internal/encoding/typeof"
+	"github.com/kelindar/talaria/internal/monitor"
+	"github.com/kelindar/talaria/internal/storage/disk"
+	"github.com/stretchr/testify/assert"
+)
+
+// source represents a source of files, in memory
+type source struct {
+	files map[string][]byte
+	lists []string // The prefixes which were listed
+	reads int      // The number of files which were read
+}
+
+func newSource(files map[string][]byte) *source {
+	return &source{files: files}
+}
+
+func (s *source) List(prefix string) (out []string, err error) {
+	s.lists = append(s.lists, prefix)
+	for name := range s.files {
+		if strings.HasPrefix(name, prefix) {
+			out = append(out, name)
+		}
+	}
+	return
+}
+
+func (s *source) Read(name string) ([]byte, error) {
+	s.reads++
+	if b, ok := s.files[name]; ok {
+		return b, nil
+	}
+	return nil, fmt.Errorf("not found")
+}
+
+var schema = typeof.Schema{
+	"event": typeof.String,
+	"tsi":   typeof.Int64,
+	"value": typeof.Int64,
+}
+
+// newBlock creates a block with a row per value
+func newBlock(event string, tsi int64, values ...int64) block.Block {
+	cols := column.MakeColumns(&schema)
+	for _, v := range values {
+		cols.Append("event", event, typeof.String)
+		cols.Append("tsi", tsi, typeof.Int64)
+		cols.Append("value", v, typeof.Int64)
+	}
+
+	b, _ := block.FromColumns(event, cols)
+	return b
+}
+
+// newFile creates an orc file containing the blocks
+func newFile(blocks ...block.Block) []byte {
+	b, _ := merge.ToOrc(blocks, schema)
+	return b
+}
+
+// dayOf returns the date partition of a time
+func dayOf(t time.Time) string {
+	return t.UTC().Format(dayLayout)
+}
+
+// count returns the number of blocks of an event from a time onwards
+func count(t *testing.T, store *Storage, event string, from time.Time) (n int) {
+	seek := key.WithSequence(event, from, 0)
+	until := key.WithSequence(event, time.Now().Add(time.Hour), math.MaxUint32)
+	assert.NoError(t, store.Range(seek, until, func(k, v []byte) bool {
+		b, err := block.FromBuffer(v)
+		assert.NoError(t, err)
+		assert.Equal(t, event, string(b.Key))
+		n++
+		return false
+	}))
+	return
+}
+
+func TestRange(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-10 * 24 * time.Hour)
+	older := now.Add(-60 * 24 * time.Hour)
+	src := newSource(map[string][]byte{
+		dayOf(older) + "/00-00-00-a.orc": newFile(newBlock("click", older.UnixNano(), 1, 2), newBlock("view", older.UnixNano(), 3)),
+		dayOf(old) + "/00-00-00-b.orc":   newFile(newBlock("click", old.UnixNano(), 4)),
+		dayOf(now) + "/00-00-00-c.orc":   newFile(newBlock("click", now.UnixNano(), 5)),
+		dayOf(now) + "/00-00-00-d.orc":   []byte("invalid"),
+	})
+
+	// Only the date partitions from the day before the range are listed and read
+	store := New(src, "orc", true, "event", "tsi", monitor.NewNoop())
+	assert.Equal(t, 1, count(t, store, "click", now.UTC().Truncate(24*time.Hour)))
+	assert.Len(t, src.lists, 2)
+	assert.Equal(t, dayOf(now.Add(-24*time.Hour)), src.lists[0])
+	assert.Len(t, store.summary, 1)
+
+	// Files written before the range are skipped
+	assert.Equal(t, 2, count(t, store, "click", old.Add(-time.Hour)))
+	assert.Len(t, store.summary, 2)
+
+	// A range over too many days lists every file, the contents of the files are cached
+	reads := src.reads
+	assert.Equal(t, 3, count(t, store, "click", time.Unix(0, 0)))
+	assert.Equal(t, "", src.lists[len(src.lists)-1])
+	assert.Equal(t, reads+2, src.reads) // The new file and the invalid one
+	assert.Equal(t, 3, count(t, store, "click", time.Unix(0, 0)))
+	assert.Equal(t, reads+3, src.reads) // Only the invalid one
+	assert.True(t, store.summary[dayOf(older)+"/00-00-00-a.orc"].hashes[key.HashOf(key.New("click", now))])
+
+	// The files which are no longer present are forgotten
+	delete(src.files, dayOf(older)+"/00-00-00-a.orc")
+	assert.Equal(t, 2, count(t, store, "click", time.Unix(0, 0)))
+	assert.Len(t, store.summary, 2)
+
+	// Keys are deterministic, so a query can be resumed
+	seek, until := key.WithSequence("click", time.Unix(0, 0), 0), key.Last()
+	var first, second []key.Key
+	_ = store.Range(seek, until, func(k, v []byte) bool {
+		first = append(first, key.Clone(k))
+		return false
+	})
+	_ = store.Range(seek, until, func(k, v []byte) bool {
+		second = append(second, key.Clone(k))
+		return true
+	})
+	assert.Equal(t, first[:1], second)
+}
+
+func TestRange_Format(t *testing.T) {
+	now := time.Now()
+	parquet, err := merge.ToParquet([]block.Block{newBlock("click", now.UnixNano(), 1, 2)}, schema)
+	assert.NoError(t, err)
+
+	// The files are decoded with the configured format, whatever their extension
+	src := newSource(map[string][]byte{
+		dayOf(now) + "/00-00-00-a.orc": parquet,
+	})
+
+	assert.Equal(t, 1, count(t, New(src, "parquet", true, "event", "tsi", monitor.NewNoop()), "click", now.Add(-time.Hour)))
+	assert.Equal(t, 0, count(t, New(src, "orc", true, "event", "tsi", monitor.NewNoop()), "click", now.Add(-time.Hour)))
+}
+
+func TestRangeContext(t *testing.T) {
+	now := time.Now()
+	src := newSource(map[string][]byte{
+		dayOf(now) + "/00-00-00-a.orc": newFile(newBlock("click", now.UnixNano(), 1)),
+		dayOf(now) + "/00-00-00-b.orc": newFile(newBlock("click", now.UnixNano(), 2)),
+	})
+
+	// No file is read once the context is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	store := New(src, "orc", true, "event", "tsi", monitor.NewNoop())
+	assert.Equal(t, context.Canceled, store.RangeContext(ctx, key.First(), key.Last(), func(k, v []byte) bool {
+		return false
+	}))
+	assert.Equal(t, 0, src.reads)
+}
+
+func TestCache(t *testing.T) {
+	c := newCache(100)
+	c.put("a", []item{{key: make([]byte, 10), value: make([]byte, 40)}})
+	c.put("b", []item{{key: make([]byte, 10), value: make([]byte, 40)}})
+	_, ok := c.get("a")
+	assert.True(t, ok)
+
+	// The least recently used file is evicted
+	c.put("c", []item{{key: make([]byte, 10), value: make([]byte, 20)}})
+	_, ok = c.get("b")
+	assert.False(t, ok)
+	_, ok = c.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 80, c.size)
+
+	// Files larger than the cache are not cached
+	c.put("d", []item{{key: make([]byte, 10), value: make([]byte, 200)}})
+	_, ok = c.get("d")
+	assert.False(t, ok)
+}
+
+func TestReadThrough(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "test")
+	defer func() { _ = os.RemoveAll(dir) }()
+	buffer := disk.Open(dir, "buffer", monitor.NewNoop(), config.Badger{})
+	defer buffer.Close()
+
+	src := newSource(map[string][]byte{
+		"year=2020/month=1/day=1/00-00-00-a.orc": newFile(newBlock("click", 100, 1, 2)),
+	})
+
+	// The blocks in the buffer are merged with the compacted ones
+	blk := newBlock("click", 200, 3)
+	hot, _ := blk.Encode()
+	assert.NoError(t, buffer.Append(key.New("click", time.Unix(0, 200)), hot, time.Hour))
+
+	store := ReadThrough(buffer, New(src, "orc", true, "event", "tsi", monitor.NewNoop()))
+	var rows int
+	assert.NoError(t, store.Range(key.WithSequence("click", time.Unix(0, 0), 0), key.Last(), func(k, v []byte) bool {
+		cols, err := block.Read(v, schema)
+		assert.NoError(t, err)
+		rows += cols.Max()
+		return false
+	}))
+	assert.Equal(t, 3, rows)
+}
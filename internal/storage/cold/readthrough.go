@@ -0,0 +1,80 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package cold
+
+import (
+	"bytes"
+	"context"
+	"sort"
+
+	"github.com/kelindar/talaria/internal/encoding/key"
+	"github.com/kelindar/talaria/internal/storage"
+)
+
+// readThrough represents a storage which reads from both the buffer and the cold tiers
+type readThrough struct {
+	storage.Storage
+	tiers []storage.Iterator
+}
+
+// ReadThrough creates a storage which appends to the buffer, while range queries merge the blocks of the
+// buffer with the ones of the cold tiers, so that the compacted data remains queryable.
+func ReadThrough(buffer storage.Storage, tiers ...storage.Iterator) storage.Storage {
+	return &readThrough{
+		Storage: buffer,
+		tiers:   tiers,
+	}
+}
+
+// Range performs a range query against both the buffer and the cold tiers, in lexicographic order of the keys.
+func (s *readThrough) Range(seek, until key.Key, f func(key, value []byte) bool) error {
+	return s.RangeContext(context.Background(), seek, until, f)
+}
+
+// RangeContext performs a range query against both the buffer and the cold tiers, which stops as soon as
+// the context is cancelled and returns the error of the context.
+func (s *readThrough) RangeContext(ctx context.Context, seek, until key.Key, f func(key, value []byte) bool) error {
+	var items []item
+	for _, tier := range s.tiers {
+		if err := storage.RangeContext(ctx, tier, seek, until, func(k, v []byte) bool {
+			items = append(items, item{key: key.Clone(k), value: v})
+			return false
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Each tier is sorted, but not across the tiers
+	if len(s.tiers) > 1 {
+		sort.SliceStable(items, func(i, j int) bool {
+			return bytes.Compare(items[i].key, items[j].key) < 0
+		})
+	}
+
+	stopped := false
+	if err := storage.RangeContext(ctx, s.Storage, seek, until, func(k, v []byte) bool {
+		for len(items) > 0 && bytes.Compare(items[0].key, k) < 0 {
+			if stopped = f(items[0].key, items[0].value); stopped {
+				return true
+			}
+			items = items[1:]
+		}
+
+		stopped = f(k, v)
+		return stopped
+	}); err != nil || stopped {
+		return err
+	}
+
+	for _, it := range items {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if f(it.key, it.value) {
+			return nil
+		}
+	}
+	return nil
+}
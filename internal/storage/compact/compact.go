@@ -5,7 +5,10 @@ package compact
 
 import (
 	"context"
+	"fmt"
+	"hash/fnv"
 	"runtime"
+	"sync"
 	"time"
 
 	"github.com/grab/async"
@@ -14,7 +17,9 @@ import (
 	"github.com/kelindar/talaria/internal/encoding/typeof"
 	"github.com/kelindar/talaria/internal/monitor"
 	"github.com/kelindar/talaria/internal/monitor/errors"
+	"github.com/kelindar/talaria/internal/monitor/tracing"
 	"github.com/kelindar/talaria/internal/storage"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Assert contract compliance
@@ -24,23 +29,59 @@ const ctxTag = "compaction"
 
 // BlockWriter represents a block writer that can be used to encode and write blocks
 type BlockWriter interface {
-	WriteBlock([]block.Block, typeof.Schema) error
+	WriteBlock(context.Context, Job, []block.Block, typeof.Schema) error
+}
+
+// Job represents a compaction job. Its identifier is derived from the keys of the blocks, so that
+// a retried job writes the same files.
+type Job struct {
+	ID   string    `json:"id"`   // The identifier of the job, derived from its keys
+	Time time.Time `json:"time"` // The time at which the job was created, kept in the commit log so a retried job writes the same files
+}
+
+// jobOf creates a job for a set of keys. The time of the keys is not used since its unit depends on
+// the sortBy column of the table.
+func jobOf(keys []key.Key) Job {
+	h := fnv.New64a()
+	for _, k := range keys {
+		_, _ = h.Write(k)
+	}
+
+	return Job{
+		ID:   fmt.Sprintf("%016x", h.Sum64()),
+		Time: time.Now().UTC(),
+	}
+}
+
+// Trigger represents the conditions upon which a group of blocks sharing the same hash is compacted.
+// If neither is specified, every group is compacted on each interval.
+type Trigger struct {
+	TargetSize int64         // The accumulated size of the blocks of a group after which it is compacted
+	MaxAge     time.Duration // The maximum duration for which a group is buffered before it is compacted
 }
 
 // Storage represents compactor storage.
 type Storage struct {
-	compact async.Task      // The compaction worker
-	monitor monitor.Monitor // The monitor client
-	buffer  storage.Storage // The storage to use for buffering
-	dest    BlockWriter     // The compaction destination
+	compact async.Task           // The compaction worker
+	monitor monitor.Monitor      // The monitor client
+	buffer  storage.Storage      // The storage to use for buffering
+	dest    BlockWriter          // The compaction destination
+	trigger Trigger              // The conditions upon which a group is compacted
+	journal *Journal             // The optional commit log of the jobs in flight
+	lock    sync.Mutex           // The lock for the first seen times
+	seen    map[uint32]time.Time // The time each group was first buffered
 }
 
-// New creates a new storage implementation.
-func New(buffer storage.Storage, dest BlockWriter, monitor monitor.Monitor, interval time.Duration) *Storage {
+// New creates a new storage implementation. If a journal is specified, the jobs which were
+// interrupted are retried under the same name before any new job is started.
+func New(buffer storage.Storage, dest BlockWriter, monitor monitor.Monitor, interval time.Duration, trigger Trigger, journal *Journal) *Storage {
 	s := &Storage{
 		monitor: monitor,
 		buffer:  buffer,
 		dest:    dest,
+		trigger: trigger,
+		journal: journal,
+		seen:    make(map[uint32]time.Time),
 	}
 	s.compact = compactEvery(interval, s.Compact)
 	return s
@@ -62,20 +103,38 @@ func compactEvery(interval time.Duration, compact async.Work) async.Task {
 }
 
 // Append adds an event into the buffer.
-func (s *Storage) Append(key key.Key, value []byte, ttl time.Duration) error {
-	return s.buffer.Append(key, value, ttl)
+func (s *Storage) Append(k key.Key, value []byte, ttl time.Duration) error {
+	return s.AppendContext(context.Background(), k, value, ttl)
+}
+
+// AppendContext adds an event into the buffer, unless the context is cancelled.
+func (s *Storage) AppendContext(ctx context.Context, k key.Key, value []byte, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if s.trigger.MaxAge > 0 {
+		s.markSeen(key.HashOf(k), time.Now())
+	}
+
+	return storage.AppendContext(ctx, s.buffer, k, value, ttl)
 }
 
 // Range performs a range query against the storage. It calls f sequentially for each key and value present in
 // the store. If f returns false, range stops the iteration. The API is designed to be very similar to the concurrent
 // map. The implementation must guarantee that the keys are lexigraphically sorted.
 func (s *Storage) Range(seek, until key.Key, f func(key, value []byte) bool) error {
+	return s.RangeContext(context.Background(), seek, until, f)
+}
+
+// RangeContext performs a range query against the storage, which stops as soon as the context is cancelled.
+func (s *Storage) RangeContext(ctx context.Context, seek, until key.Key, f func(key, value []byte) bool) error {
 	if iter, ok := s.dest.(storage.Iterator); ok {
-		return iter.Range(seek, until, f)
+		return storage.RangeContext(ctx, iter, seek, until, f)
 	}
 
 	// If the destination cannot be iterated over, read from the buffer instead
-	return s.buffer.Range(seek, until, f)
+	return storage.RangeContext(ctx, s.buffer, seek, until, f)
 }
 
 // Delete deletes a key from the buffer.
@@ -83,58 +142,53 @@ func (s *Storage) Delete(keys ...key.Key) error {
 	return s.buffer.Delete(keys...)
 }
 
-// Compact runs the compaction on the storage
+// Compact runs the compaction on the storage, only the groups which are ready are compacted
 func (s *Storage) Compact(ctx context.Context) (interface{}, error) {
+	return s.compactGroups(ctx, false)
+}
+
+// compactGroups compacts either the groups which are ready, or every group if forced. Since the keys
+// are sorted by their hash, the blocks of a group are contiguous and whether a group is ready is
+// decided while iterating through the buffer.
+func (s *Storage) compactGroups(ctx context.Context, force bool) (_ interface{}, err error) {
 	st := time.Now()
-	var hash uint32
-	var blocks []block.Block
-	var merged []key.Key
+	ctx, span := tracing.Start(ctx, "compact")
+	defer func() { tracing.End(span, err) }()
 
 	concurrency := runtime.NumCPU()
 	queue := make(chan async.Task, concurrency)
 	wpool := async.Consume(context.Background(), concurrency, queue)
 
+	// Retry the jobs which were interrupted first, their keys must not be part of another job
+	inflight, err := s.retry(ctx, queue)
+	if err != nil {
+		close(queue)
+		return nil, err
+	}
+
 	// Iterate through all of the blocks in the storage
-	schema := make(typeof.Schema, 4)
+	var bufferedBlocks, bufferedBytes int64
+	pass := s.newPass(ctx, queue, force, st)
 	if err := s.buffer.Range(key.First(), key.Last(), func(k, v []byte) bool {
-		input, err := block.FromBuffer(v)
-		if err != nil {
-			s.monitor.Error(errors.Internal("compact: unable to read a buffer", err))
-			return true
-		}
-
-		// Update the current hash
-		previous := hash
-		hash = key.HashOf(k)
-
-		// If the hash is unchanged and schemas merge cleanly, accumulate...
-		if mergedSchema, ok := schema.Union(input.Schema()); previous == 0 || (ok && hash == previous) {
-			blocks = append(blocks, input)
-			merged = append(merged, key.Clone(k))
-			schema = mergedSchema
+		bufferedBlocks++
+		bufferedBytes += int64(len(v))
+		if inflight[string(k)] {
 			return false
 		}
 
-		// Merge asynchronously and delete the keys on a successful merge
-		queue <- s.merge(merged, blocks, schema)
-
-		// Reset both the schema and the set of blocks
-		blocks = make([]block.Block, 0, 16)
-		merged = make([]key.Key, 0, 16)
-
-		// append the last value that didn't merge in previous merge
-		schema = input.Schema()
-		blocks = append(blocks, input)
-		merged = append(merged, key.Clone(k))
-		return false
+		return pass.add(k, v)
 	}); err != nil {
+		close(queue)
 		return nil, err
 	}
 
-	// Merge one last time if we still have block
-	if len(blocks) > 0 {
-		queue <- s.merge(merged, blocks, schema)
-	}
+	// Complete the last group and forget the groups which are no longer buffered
+	pass.done()
+
+	// Report the occupancy of the buffer, before the compaction
+	s.monitor.Gauge(ctxTag, "buffer", float64(bufferedBlocks), "type:blocks")
+	s.monitor.Gauge(ctxTag, "buffer", float64(bufferedBytes), "type:bytes")
+	span.SetAttributes(attribute.Int64("buffer.blocks", bufferedBlocks), attribute.Int64("buffer.bytes", bufferedBytes))
 
 	// Wait for the pool to be close
 	close(queue)
@@ -143,13 +197,74 @@ func (s *Storage) Compact(ctx context.Context) (interface{}, error) {
 	return out, err
 }
 
-// merge adds an key-value pair to the underlying database
-func (s *Storage) merge(keys []key.Key, blocks []block.Block, schema typeof.Schema) async.Task {
-	return async.NewTask(func(ctx context.Context) (_ interface{}, err error) {
+// retry queues the jobs of the journal which were not committed and returns their keys. Each job
+// is retried with the blocks which are still in the buffer, or committed if there are none left.
+func (s *Storage) retry(ctx context.Context, queue chan<- async.Task) (map[string]bool, error) {
+	if s.journal == nil {
+		return nil, nil
+	}
+
+	inflight := make(map[string]bool)
+	jobs, keys := s.journal.Pending()
+	for i, job := range jobs {
+		if len(keys[i]) == 0 {
+			continue
+		}
+
+		wanted := make(map[string]bool, len(keys[i]))
+		for _, k := range keys[i] {
+			wanted[string(k)] = true
+			inflight[string(k)] = true
+		}
+
+		// Keys of a job are sorted, so only the range between the first and the last one is read
+		var found []key.Key
+		var blocks []block.Block
+		schema := make(typeof.Schema, 4)
+		if err := s.buffer.Range(keys[i][0], keys[i][len(keys[i])-1], func(k, v []byte) bool {
+			if !wanted[string(k)] {
+				return false
+			}
+
+			input, err := block.FromBuffer(v)
+			if err != nil {
+				s.monitor.Error(errors.Internal("compact: unable to read a buffer", err))
+				return false
+			}
+
+			schema, _ = schema.Union(input.Schema())
+			blocks = append(blocks, input)
+			found = append(found, key.Clone(k))
+			return false
+		}); err != nil {
+			return nil, err
+		}
+
+		// If the blocks were already deleted, the job has completed
+		if len(blocks) == 0 {
+			if err := s.journal.Commit(job); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		s.monitor.Count1(ctxTag, "retry")
+		queue <- s.merge(ctx, job, found, blocks, schema)
+	}
+
+	return inflight, nil
+}
+
+// merge adds an key-value pair to the underlying database, the job is traced as part of the compaction
+func (s *Storage) merge(parent context.Context, job Job, keys []key.Key, blocks []block.Block, schema typeof.Schema) async.Task {
+	return async.NewTask(func(context.Context) (_ interface{}, err error) {
 		if len(blocks) == 0 {
 			return
 		}
 
+		ctx, span := tracing.Start(parent, "compact.job", attribute.String("job", job.ID), attribute.Int("blocks", len(blocks)))
+		defer func() { tracing.End(span, err) }()
+
 		// Get the max expiration time for merging
 		max := int64(0)
 		for _, b := range blocks {
@@ -158,9 +273,18 @@ func (s *Storage) merge(keys []key.Key, blocks []block.Block, schema typeof.Sche
 			}
 		}
 
+		// Record the job before writing, so it can be retried if interrupted
+		if s.journal != nil {
+			if err = s.journal.Begin(job, keys); err != nil {
+				s.monitor.Count1(ctxTag, "error", "type:journal")
+				s.monitor.Error(errors.Internal("compact: unable to record a job", err))
+				return
+			}
+		}
+
 		// Merge all blocks together and write it through
 		// TODO: add ttl := time.Duration(max-now) * time.Second
-		if err = s.dest.WriteBlock(blocks, schema); err != nil {
+		if err = s.dest.WriteBlock(ctx, job, blocks, schema); err != nil {
 			s.monitor.Count1(ctxTag, "error", "type:append")
 			s.monitor.Error(err)
 			return
@@ -171,16 +295,52 @@ func (s *Storage) merge(keys []key.Key, blocks []block.Block, schema typeof.Sche
 		if err = s.buffer.Delete(keys...); err != nil {
 			s.monitor.Count1(ctxTag, "error", "type:delete")
 			s.monitor.Error(errors.Internal("merge error %s", err))
+			return
 		}
 		s.monitor.Histogram(ctxTag, "deletelatency", float64(time.Since(start)))
 		s.monitor.Count(ctxTag, "deleteCount", int64(len(keys)))
+
+		// The job has completed, remove it from the journal
+		if s.journal != nil {
+			if err = s.journal.Commit(job); err != nil {
+				s.monitor.Count1(ctxTag, "error", "type:journal")
+				s.monitor.Error(errors.Internal("compact: unable to commit a job", err))
+			}
+		}
 		return
 	})
 }
 
+// markSeen records the time a group was first seen, if it was not already, and returns it.
+func (s *Storage) markSeen(hash uint32, now time.Time) time.Time {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if first, ok := s.seen[hash]; ok {
+		return first
+	}
+
+	s.seen[hash] = now
+	return now
+}
+
+// resetSeen resets the time a group was first seen, or forgets it if the time is zero.
+func (s *Storage) resetSeen(hash uint32, now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if now.IsZero() {
+		delete(s.seen, hash)
+		return
+	}
+
+	s.seen[hash] = now
+}
+
 // Close is used to gracefully close storage.
 func (s *Storage) Close() error {
 	s.compact.Cancel()
-	s.Compact(context.Background())
+	s.compactGroups(context.Background(), true)
+	if s.journal != nil {
+		return storage.Close(s.buffer, s.dest, s.journal)
+	}
 	return storage.Close(s.buffer, s.dest)
 }
@@ -5,6 +5,7 @@ package compact
 
 import (
 	"context"
+	"errors"
 	"io/ioutil"
 	"os"
 	"sync/atomic"
@@ -38,10 +39,10 @@ var input = []byte{
 	0x0, 0x0, 0x0, 0x2, 0x0, 0x0, 0x0, 0x2, 0x68, 0x69, 0x0}
 
 // blockWriter mock
-type blockWriter func([]block.Block, typeof.Schema) error
+type blockWriter func(Job, []block.Block, typeof.Schema) error
 
-func (w blockWriter) WriteBlock(blocks []block.Block, schema typeof.Schema) error {
-	return w(blocks, schema)
+func (w blockWriter) WriteBlock(_ context.Context, job Job, blocks []block.Block, schema typeof.Schema) error {
+	return w(job, blocks, schema)
 }
 
 // Opens a new disk storage and runs a a test on it.
@@ -70,12 +71,12 @@ func run(f func(store *disk.Storage)) {
 func TestRange(t *testing.T) {
 	runTest(t, func(buffer *disk.Storage) {
 		var count int64
-		var dest blockWriter = func(blocks []block.Block, schema typeof.Schema) error {
+		var dest blockWriter = func(job Job, blocks []block.Block, schema typeof.Schema) error {
 			atomic.AddInt64(&count, 1)
 			return nil
 		}
 
-		store := New(buffer, dest, monitor.NewNoop(), 100*time.Millisecond)
+		store := New(buffer, dest, monitor.NewNoop(), 100*time.Millisecond, Trigger{}, nil)
 
 		// Insert out of order
 		_ = store.Append(key.New("A", time.Unix(0, 0)), input, 60*time.Second)
@@ -105,3 +106,113 @@ func TestRange(t *testing.T) {
 		assert.Equal(t, int64(4), count)
 	})
 }
+
+func TestCompact_Triggers(t *testing.T) {
+	runTest(t, func(buffer *disk.Storage) {
+		var count int64
+		var dest blockWriter = func(job Job, blocks []block.Block, schema typeof.Schema) error {
+			atomic.AddInt64(&count, 1)
+			return nil
+		}
+
+		store := New(buffer, dest, monitor.NewNoop(), time.Hour, Trigger{
+			TargetSize: int64(len(input) * 3),
+			MaxAge:     100 * time.Millisecond,
+		}, nil)
+
+		_ = store.Append(key.New("A", time.Unix(0, 0)), input, 60*time.Second)
+		_ = store.Append(key.New("A", time.Unix(1, 0)), input, 60*time.Second)
+		_ = store.Append(key.New("B", time.Unix(0, 0)), input, 60*time.Second)
+		_ = store.Append(key.New("B", time.Unix(1, 0)), input, 60*time.Second)
+		_ = store.Append(key.New("B", time.Unix(2, 0)), input, 60*time.Second)
+		_ = store.Append(key.New("B", time.Unix(3, 0)), input, 60*time.Second)
+		_ = store.Append(key.New("C", time.Unix(1, 0)), input, 60*time.Second)
+
+		// Only the group which reached the target size is compacted, split at the target size, and
+		// the blocks which did not reach it remain in the buffer
+		store.Compact(context.Background())
+		assert.Equal(t, int64(1), count)
+
+		var remaining int
+		assert.NoError(t, buffer.Range(key.First(), key.Last(), func(k, v []byte) bool {
+			remaining++
+			return false
+		}))
+		assert.Equal(t, 4, remaining)
+
+		// Once the groups are old enough, they are compacted as well
+		time.Sleep(150 * time.Millisecond)
+		store.Compact(context.Background())
+		assert.Equal(t, int64(4), count)
+		assert.Empty(t, store.seen)
+	})
+}
+
+func TestCompact_Expired(t *testing.T) {
+	runTest(t, func(buffer *disk.Storage) {
+		var dest blockWriter = func(job Job, blocks []block.Block, schema typeof.Schema) error {
+			return nil
+		}
+
+		store := New(buffer, dest, monitor.NewNoop(), time.Hour, Trigger{
+			MaxAge: time.Hour,
+		}, nil)
+
+		k := key.New("A", time.Unix(0, 0))
+		_ = store.Append(k, input, 60*time.Second)
+		store.Compact(context.Background())
+		assert.Len(t, store.seen, 1)
+
+		// The age of the groups which are no longer buffered is forgotten
+		assert.NoError(t, buffer.Delete(k))
+		store.Compact(context.Background())
+		assert.Empty(t, store.seen)
+	})
+}
+
+func TestCompact_Retry(t *testing.T) {
+	runTest(t, func(buffer *disk.Storage) {
+		dir, _ := ioutil.TempDir("", "journal")
+		defer func() { _ = os.RemoveAll(dir) }()
+
+		journal, err := OpenJournal(dir + "/compaction.log")
+		assert.NoError(t, err)
+		defer journal.Close()
+
+		var jobs []Job
+		var dest blockWriter = func(job Job, blocks []block.Block, schema typeof.Schema) error {
+			jobs = append(jobs, job)
+			if len(jobs) == 1 {
+				return errors.New("unable to write")
+			}
+			return nil
+		}
+
+		store := New(buffer, dest, monitor.NewNoop(), time.Hour, Trigger{}, journal)
+		_ = store.Append(key.New("A", time.Unix(0, 0)), input, 60*time.Second)
+		_ = store.Append(key.New("A", time.Unix(1, 0)), input, 60*time.Second)
+
+		// The first write fails, so the job remains pending and the blocks stay in the buffer
+		store.Compact(context.Background())
+		pending, _ := journal.Pending()
+		assert.Len(t, pending, 1)
+
+		// A block appended in the meantime is not part of the retried job
+		_ = store.Append(key.New("A", time.Unix(2, 0)), input, 60*time.Second)
+		store.Compact(context.Background())
+		assert.Len(t, jobs, 3)
+		assert.Equal(t, jobs[0], jobs[1])
+		assert.NotEqual(t, jobs[0].ID, jobs[2].ID)
+		assert.WithinDuration(t, time.Now(), jobs[0].Time, time.Minute)
+
+		pending, _ = journal.Pending()
+		assert.Empty(t, pending)
+
+		var remaining int
+		assert.NoError(t, buffer.Range(key.First(), key.Last(), func(k, v []byte) bool {
+			remaining++
+			return false
+		}))
+		assert.Equal(t, 0, remaining)
+	})
+}
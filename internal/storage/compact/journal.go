@@ -0,0 +1,168 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package compact
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/kelindar/talaria/internal/encoding/key"
+)
+
+// entry represents a single record of the commit log
+type entry struct {
+	Job    Job       `json:"job"`            // The compaction job
+	Keys   []key.Key `json:"keys,omitempty"` // The keys of the blocks, if the job has begun
+	Commit bool      `json:"commit"`         // Whether the job was committed
+}
+
+// Journal represents a commit log of the compaction jobs which are in flight. A job is recorded
+// before its blocks are written to the sink and committed once they are deleted from the buffer,
+// so the jobs which were interrupted can be retried after a restart.
+type Journal struct {
+	lock    sync.Mutex
+	file    *os.File
+	pending map[string]entry // The jobs which have begun but were not committed
+}
+
+// OpenJournal opens or creates a commit log and loads the jobs which were not committed
+func OpenJournal(path string) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replay the log, a partially written record at the end is truncated so that the records
+	// appended after it start on their own line
+	j := &Journal{file: file, pending: make(map[string]entry)}
+	reader := bufio.NewReader(file)
+	offset := int64(0)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				err = j.truncateTo(offset)
+			} else {
+				err = nil
+			}
+		}
+
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+
+		if len(line) == 0 || line[len(line)-1] != '\n' {
+			break
+		}
+
+		offset += int64(len(line))
+		var e entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			continue
+		}
+
+		if e.Commit {
+			delete(j.pending, e.Job.ID)
+			continue
+		}
+		j.pending[e.Job.ID] = e
+	}
+
+	return j, nil
+}
+
+// Begin records a job along with the keys of its blocks, before they are written to the sink
+func (j *Journal) Begin(job Job, keys []key.Key) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	e := entry{Job: job, Keys: keys}
+	if err := j.write(e); err != nil {
+		return err
+	}
+
+	j.pending[job.ID] = e
+	return nil
+}
+
+// Commit marks the job as completed, once its blocks were deleted from the buffer
+func (j *Journal) Commit(job Job) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if _, ok := j.pending[job.ID]; !ok {
+		return nil
+	}
+
+	delete(j.pending, job.ID)
+	if len(j.pending) == 0 {
+		return j.truncate()
+	}
+
+	return j.write(entry{Job: job, Commit: true})
+}
+
+// Pending returns the jobs which have begun but were not committed, along with their keys
+func (j *Journal) Pending() ([]Job, [][]key.Key) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	jobs := make([]Job, 0, len(j.pending))
+	for _, e := range j.pending {
+		jobs = append(jobs, e.Job)
+	}
+
+	sort.Slice(jobs, func(i, k int) bool {
+		return jobs[i].ID < jobs[k].ID
+	})
+
+	keys := make([][]key.Key, 0, len(jobs))
+	for _, job := range jobs {
+		keys = append(keys, j.pending[job.ID].Keys)
+	}
+	return jobs, keys
+}
+
+// Close closes the commit log
+func (j *Journal) Close() error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	return j.file.Close()
+}
+
+// write appends a record to the log and syncs it to disk
+func (j *Journal) write(e entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return j.file.Sync()
+}
+
+// truncate empties the log once there are no more jobs in flight
+func (j *Journal) truncate() error {
+	return j.truncateTo(0)
+}
+
+// truncateTo cuts the log at the offset and syncs it to disk
+func (j *Journal) truncateTo(offset int64) error {
+	if err := j.file.Truncate(offset); err != nil {
+		return err
+	}
+	return j.file.Sync()
+}
@@ -0,0 +1,90 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package compact
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kelindar/talaria/internal/encoding/key"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournal(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "journal")
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	path := filepath.Join(dir, "compaction.log")
+	journal, err := OpenJournal(path)
+	assert.NoError(t, err)
+
+	k1 := []key.Key{key.New("A", time.Unix(0, 0)), key.New("A", time.Unix(1, 0))}
+	k2 := []key.Key{key.New("B", time.Unix(0, 0))}
+	j1, j2 := jobOf(k1), jobOf(k2)
+	assert.NotEqual(t, j1.ID, j2.ID)
+	assert.Equal(t, j1.ID, jobOf(k1).ID)
+
+	assert.NoError(t, journal.Begin(j1, k1))
+	assert.NoError(t, journal.Begin(j2, k2))
+	assert.NoError(t, journal.Commit(j2))
+	assert.NoError(t, journal.Close())
+
+	// Only the job which was not committed is pending after a restart
+	journal, err = OpenJournal(path)
+	assert.NoError(t, err)
+	jobs, keys := journal.Pending()
+	assert.Len(t, jobs, 1)
+	assert.Equal(t, j1.ID, jobs[0].ID)
+	assert.True(t, j1.Time.Equal(jobs[0].Time))
+	assert.Equal(t, k1, keys[0])
+
+	// Once every job is committed, the log is emptied
+	assert.NoError(t, journal.Commit(j1))
+	jobs, _ = journal.Pending()
+	assert.Empty(t, jobs)
+	assert.NoError(t, journal.Close())
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), info.Size())
+}
+
+func TestJournal_TornTail(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "journal")
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	path := filepath.Join(dir, "compaction.log")
+	journal, err := OpenJournal(path)
+	assert.NoError(t, err)
+
+	k1 := []key.Key{key.New("A", time.Unix(0, 0))}
+	k2 := []key.Key{key.New("B", time.Unix(0, 0))}
+	j1, j2 := jobOf(k1), jobOf(k2)
+	assert.NoError(t, journal.Begin(j1, k1))
+	assert.NoError(t, journal.Close())
+
+	// Simulate a crash in the middle of writing a record
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0666)
+	assert.NoError(t, err)
+	_, err = file.WriteString(`{"job":{"id":"torn`)
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+
+	// The torn record is dropped and the next one is appended on its own line
+	journal, err = OpenJournal(path)
+	assert.NoError(t, err)
+	assert.NoError(t, journal.Begin(j2, k2))
+	assert.NoError(t, journal.Close())
+
+	journal, err = OpenJournal(path)
+	assert.NoError(t, err)
+	jobs, keys := journal.Pending()
+	assert.Len(t, jobs, 2)
+	assert.Contains(t, keys, k1)
+	assert.Contains(t, keys, k2)
+	assert.NoError(t, journal.Close())
+}
@@ -0,0 +1,151 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package compact
+
+import (
+	"context"
+	"time"
+
+	"github.com/grab/async"
+	"github.com/kelindar/talaria/internal/encoding/block"
+	"github.com/kelindar/talaria/internal/encoding/key"
+	"github.com/kelindar/talaria/internal/encoding/typeof"
+	"github.com/kelindar/talaria/internal/monitor/errors"
+)
+
+// pass represents a single iteration through the buffer, which splits each group which is ready
+// into jobs of the target size.
+type pass struct {
+	*Storage
+	ctx       context.Context
+	queue     chan<- async.Task
+	force     bool            // Whether every group is compacted
+	started   time.Time       // The time at which the pass has started
+	groups    map[uint32]bool // The groups which were seen during the pass
+	hash      uint32          // The hash of the current group
+	aged      bool            // Whether the current group is compacted entirely
+	skip      bool            // Whether the current group is not compacted
+	compacted bool            // Whether a job was queued for the current group
+	keys      []key.Key       // The keys of the current job
+	blocks    []block.Block   // The blocks of the current job
+	schema    typeof.Schema   // The schema of the current job
+	size      int64           // The size of the current job
+}
+
+// newPass creates a new pass through the buffer
+func (s *Storage) newPass(ctx context.Context, queue chan<- async.Task, force bool, now time.Time) *pass {
+	return &pass{
+		Storage: s,
+		ctx:     ctx,
+		queue:   queue,
+		force:   force,
+		started: now,
+		groups:  make(map[uint32]bool, 16),
+		schema:  make(typeof.Schema, 4),
+	}
+}
+
+// add adds a block to the current group, and returns whether the iteration should stop
+func (p *pass) add(k, v []byte) bool {
+	if hash := key.HashOf(k); len(p.groups) == 0 || hash != p.hash {
+		p.complete()
+		p.begin(hash)
+	}
+
+	if p.skip {
+		return false
+	}
+
+	input, err := block.FromBuffer(v)
+	if err != nil {
+		p.monitor.Error(errors.Internal("compact: unable to read a buffer", err))
+		return true
+	}
+
+	// Blocks whose schema does not merge cleanly start a new job
+	mergedSchema, ok := p.schema.Union(input.Schema())
+	if !ok && len(p.blocks) > 0 {
+		p.flush()
+		mergedSchema = input.Schema()
+	}
+
+	p.blocks = append(p.blocks, input)
+	p.keys = append(p.keys, key.Clone(k))
+	p.schema = mergedSchema
+	p.size += int64(len(v))
+
+	// Once the job reaches the target size, merge it
+	if p.trigger.TargetSize > 0 && p.size >= p.trigger.TargetSize {
+		p.flush()
+	}
+	return false
+}
+
+// begin starts a new group, which is compacted entirely if forced, without any trigger or once it has
+// reached its maximum age. Otherwise, only the jobs which reach the target size are compacted.
+func (p *pass) begin(hash uint32) {
+	p.hash = hash
+	p.groups[hash] = true
+	p.compacted = false
+
+	first := p.markSeen(hash, p.started) // Groups buffered before a restart are seen for the first time
+	p.aged = p.force ||
+		(p.trigger.TargetSize <= 0 && p.trigger.MaxAge <= 0) ||
+		(p.trigger.MaxAge > 0 && p.started.Sub(first) >= p.trigger.MaxAge)
+	p.skip = !p.aged && p.trigger.TargetSize <= 0
+}
+
+// complete completes the current group. The remaining blocks of a group which is not compacted
+// entirely stay in the buffer, as they have not reached the target size.
+func (p *pass) complete() {
+	if len(p.groups) == 0 {
+		return
+	}
+
+	if p.aged {
+		p.flush()
+	}
+
+	remaining := len(p.blocks) > 0
+	p.reset()
+
+	// Reset the age of the group, if it was compacted
+	switch {
+	case !p.compacted:
+	case remaining:
+		p.resetSeen(p.hash, p.started)
+	default:
+		p.resetSeen(p.hash, time.Time{})
+	}
+}
+
+// done completes the last group and forgets the age of the groups which are no longer buffered
+func (p *pass) done() {
+	p.complete()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for hash, first := range p.seen {
+		if !p.groups[hash] && first.Before(p.started) {
+			delete(p.seen, hash)
+		}
+	}
+}
+
+// flush merges the current job asynchronously, the keys are deleted on a successful merge
+func (p *pass) flush() {
+	if len(p.blocks) > 0 {
+		p.queue <- p.merge(p.ctx, jobOf(p.keys), p.keys, p.blocks, p.schema)
+		p.compacted = true
+	}
+	p.reset()
+}
+
+// reset resets the current job
+func (p *pass) reset() {
+	p.blocks = make([]block.Block, 0, 16)
+	p.keys = make([]key.Key, 0, 16)
+	p.schema = make(typeof.Schema, 4)
+	p.size = 0
+}
@@ -146,10 +146,19 @@ func (s *Storage) Open(dir string, options config.Badger) error {
 
 // Append adds an event into the storage.
 func (s *Storage) Append(key key.Key, value []byte, ttl time.Duration) error {
+	return s.AppendContext(context.Background(), key, value, ttl)
+}
+
+// AppendContext adds an event into the storage, unless the context is cancelled.
+func (s *Storage) AppendContext(ctx context.Context, key key.Key, value []byte, ttl time.Duration) error {
 	if s.isClosed() {
 		return errors.New(errClosed)
 	}
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	if err := s.db.Update(func(tx *badger.Txn) error {
 		return tx.SetEntry(&badger.Entry{
 			Key:       key,
@@ -166,6 +175,12 @@ func (s *Storage) Append(key key.Key, value []byte, ttl time.Duration) error {
 // the store. If f returns false, range stops the iteration. The API is designed to be very similar to the concurrent
 // map. The implementation must guarantee that the keys are lexigraphically sorted.
 func (s *Storage) Range(seek, until key.Key, f func(key, value []byte) bool) error {
+	return s.RangeContext(context.Background(), seek, until, f)
+}
+
+// RangeContext performs a range query against the storage, which stops as soon as the context is cancelled
+// and returns the error of the context.
+func (s *Storage) RangeContext(ctx context.Context, seek, until key.Key, f func(key, value []byte) bool) error {
 	if s.isClosed() {
 		return errors.New(errClosed)
 	}
@@ -185,6 +200,11 @@ func (s *Storage) Range(seek, until key.Key, f func(key, value []byte) bool) err
 				return nil // Stop if we're reached the end
 			}
 
+			// Stop if the request was cancelled, before fetching the value
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
 			// Fetch the value
 			if value, ok := s.fetch(key, item); ok && f(key, value) {
 				return nil
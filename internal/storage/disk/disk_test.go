@@ -106,6 +106,28 @@ func TestRange_NoPrefix(t *testing.T) {
 	})
 }
 
+func TestRangeContext(t *testing.T) {
+	runTest(t, func(store *Storage) {
+		populate(store)
+
+		// Cancel the context half-way through the iteration
+		count := 0
+		ctx, cancel := context.WithCancel(context.Background())
+		err := store.RangeContext(ctx, asBytes("3000"), asBytes("5999"), func(k, v []byte) bool {
+			if count++; count == 100 {
+				cancel()
+			}
+			return false
+		})
+
+		assert.Equal(t, context.Canceled, err)
+		assert.Equal(t, 100, count)
+
+		// Nothing is appended once cancelled
+		assert.Equal(t, context.Canceled, store.AppendContext(ctx, asBytes("9"), asBytes("Z"), time.Minute))
+	})
+}
+
 func TestDelete(t *testing.T) {
 	const count = 10000
 	runTest(t, func(store *Storage) {
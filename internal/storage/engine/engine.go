@@ -0,0 +1,86 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package engine
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kelindar/talaria/internal/config"
+	"github.com/kelindar/talaria/internal/monitor"
+	"github.com/kelindar/talaria/internal/monitor/errors"
+	"github.com/kelindar/talaria/internal/storage"
+	"github.com/kelindar/talaria/internal/storage/bolt"
+	"github.com/kelindar/talaria/internal/storage/disk"
+)
+
+// The names of the built-in storage engines
+const (
+	Badger = "badger"
+	Bolt   = "bolt"
+)
+
+// Opener opens a storage engine in a directory
+type Opener func(dir string, options config.Storage, monitor monitor.Monitor) (storage.Storage, error)
+
+var (
+	lock    sync.RWMutex
+	engines = map[string]Opener{
+		Badger: openBadger,
+		Bolt:   openBolt,
+	}
+)
+
+// Register registers a storage engine by its name, replacing an existing one with the same name
+func Register(name string, open Opener) {
+	lock.Lock()
+	defer lock.Unlock()
+	engines[strings.ToLower(name)] = open
+}
+
+// Names returns the sorted names of the registered storage engines
+func Names() []string {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Open opens the storage of a table with the engine registered under a name, defaults to badger
+func Open(engine, table string, options config.Storage, monitor monitor.Monitor) (storage.Storage, error) {
+	if engine == "" {
+		engine = Badger
+	}
+
+	lock.RLock()
+	open, ok := engines[strings.ToLower(engine)]
+	lock.RUnlock()
+	if !ok {
+		return nil, errors.Newf("engine: unknown storage engine '%s', must be one of %v", engine, Names())
+	}
+
+	return open(path.Join(options.Directory, table), options, monitor)
+}
+
+// openBadger opens a badger storage in a directory
+func openBadger(dir string, options config.Storage, monitor monitor.Monitor) (storage.Storage, error) {
+	store := disk.New(monitor)
+	if err := store.Open(dir, options.Badger); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// openBolt opens a bbolt storage in a directory
+func openBolt(dir string, options config.Storage, monitor monitor.Monitor) (storage.Storage, error) {
+	return bolt.Open(dir, options.Bolt, monitor)
+}
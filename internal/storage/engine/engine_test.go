@@ -0,0 +1,117 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/kelindar/talaria/internal/config"
+	"github.com/kelindar/talaria/internal/encoding/key"
+	"github.com/kelindar/talaria/internal/monitor"
+	"github.com/kelindar/talaria/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// runSuite runs the same test against every registered storage engine
+func runSuite(t *testing.T, test func(t *testing.T, store storage.Storage)) {
+	for _, name := range Names() {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "engine")
+			assert.NoError(t, err)
+			defer func() { _ = os.RemoveAll(dir) }()
+
+			store, err := Open(name, "test", config.Storage{Directory: dir}, monitor.NewNoop())
+			assert.NoError(t, err)
+			defer func() { _ = store.Close() }()
+			test(t, store)
+		})
+	}
+}
+
+func TestOpen_Unknown(t *testing.T) {
+	_, err := Open("xyz", "test", config.Storage{}, monitor.NewNoop())
+	assert.Error(t, err)
+	assert.Equal(t, []string{Badger, Bolt}, Names())
+}
+
+func TestRange(t *testing.T) {
+	runSuite(t, func(t *testing.T, store storage.Storage) {
+
+		// Insert out of order
+		assert.NoError(t, store.Append(key.Key("1"), []byte("A"), time.Minute))
+		assert.NoError(t, store.Append(key.Key("3"), []byte("C"), time.Minute))
+		assert.NoError(t, store.Append(key.Key("2"), []byte("B"), time.Minute))
+		assert.NoError(t, store.Append(key.Key("6"), []byte("F"), time.Minute))
+
+		// Iterate in order, the end is inclusive
+		var values []string
+		assert.NoError(t, store.Range(key.Key("1"), key.Key("3"), func(k, v []byte) bool {
+			values = append(values, string(v))
+			return false
+		}))
+		assert.Equal(t, []string{"A", "B", "C"}, values)
+
+		// Stop the iteration early
+		values = values[:0]
+		assert.NoError(t, store.Range(key.Key("2"), key.Key("6"), func(k, v []byte) bool {
+			values = append(values, string(v))
+			return true
+		}))
+		assert.Equal(t, []string{"B"}, values)
+	})
+}
+
+func TestExpire(t *testing.T) {
+	runSuite(t, func(t *testing.T, store storage.Storage) {
+		assert.NoError(t, store.Append(key.Key("1"), []byte("A"), -time.Minute))
+		assert.NoError(t, store.Append(key.Key("2"), []byte("B"), time.Minute))
+
+		var values []string
+		assert.NoError(t, store.Range(key.First(), key.Last(), func(k, v []byte) bool {
+			values = append(values, string(v))
+			return false
+		}))
+		assert.Equal(t, []string{"B"}, values)
+	})
+}
+
+func TestDelete(t *testing.T) {
+	const count = 1000
+	runSuite(t, func(t *testing.T, store storage.Storage) {
+		var keys []key.Key
+		for i := 0; i < count; i++ {
+			k := key.Key(strconv.Itoa(i))
+			keys = append(keys, k)
+			assert.NoError(t, store.Append(k, k, time.Minute))
+		}
+
+		assert.Equal(t, count, countKeys(store))
+		assert.NoError(t, store.Delete(keys[:500]...))
+		assert.Equal(t, count-500, countKeys(store))
+	})
+}
+
+func TestClosed(t *testing.T) {
+	runSuite(t, func(t *testing.T, store storage.Storage) {
+		assert.NoError(t, store.Close())
+		assert.Error(t, store.Append(key.Key("1"), []byte("A"), time.Minute))
+		assert.Error(t, store.Delete(key.Key("1")))
+		assert.Error(t, store.Range(key.First(), key.Last(), func(k, v []byte) bool {
+			return false
+		}))
+	})
+}
+
+// countKeys counts the number of keys in the store
+func countKeys(store storage.Storage) (count int) {
+	_ = store.Range(key.First(), key.Last(), func(k, v []byte) bool {
+		count++
+		return false
+	})
+	return
+}
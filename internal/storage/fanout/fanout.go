@@ -0,0 +1,187 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package fanout
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/kelindar/talaria/internal/encoding/key"
+	"github.com/kelindar/talaria/internal/storage"
+)
+
+// Assert contract compliance
+var _ storage.Storage = new(Storage)
+var _ storage.ContextIterator = new(Storage)
+
+// The size of the keys of the blocks, the markers of the namespaces are prefixed
+const keySize = 16
+
+// PipelineFunc creates a pipeline for a namespace of the shared buffer
+type PipelineFunc func(name string, buffer storage.Storage) (storage.Storage, error)
+
+// Storage represents a storage which appends every block once to a shared buffer, while multiple
+// independent pipelines consume it through their own namespace. Each namespace remembers which
+// blocks its pipeline has deleted, so one pipeline never holds back another and a block is only
+// removed from the buffer once every pipeline has deleted it.
+type Storage struct {
+	lock      sync.Mutex        // The lock for the deleted blocks of the namespaces
+	buffer    storage.Storage   // The shared buffer
+	ttl       time.Duration     // The duration for which the markers of the deleted blocks are kept
+	spaces    []*Namespace      // The namespaces of the pipelines
+	pipelines []storage.Storage // The pipelines, the first one is used for reading
+}
+
+// New creates a new storage which fans out to a pipeline per name. The markers of the deleted blocks
+// are kept for the ttl of the blocks, so that they never outlive the blocks they mark. The blocks
+// which were buffered before the pipelines were split are consumed by every pipeline.
+func New(buffer storage.Storage, ttl time.Duration, names []string, newPipeline PipelineFunc) (*Storage, error) {
+	s := &Storage{
+		buffer:    buffer,
+		ttl:       ttl,
+		spaces:    make([]*Namespace, 0, len(names)),
+		pipelines: make([]storage.Storage, 0, len(names)),
+	}
+
+	for _, name := range names {
+		ns := newNamespace(s, name)
+		if err := ns.load(); err != nil {
+			return nil, err
+		}
+
+		pipeline, err := newPipeline(name, ns)
+		if err != nil {
+			return nil, err
+		}
+
+		s.spaces = append(s.spaces, ns)
+		s.pipelines = append(s.pipelines, pipeline)
+	}
+	return s, nil
+}
+
+// Append adds an event once into the shared buffer, where every pipeline sees it, and then lets
+// every pipeline account for it.
+func (s *Storage) Append(k key.Key, value []byte, ttl time.Duration) error {
+	if err := s.buffer.Append(k, value, ttl); err != nil {
+		return err
+	}
+
+	for _, p := range s.pipelines {
+		if err := p.Append(k, value, ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Range performs a range query against the first pipeline.
+func (s *Storage) Range(seek, until key.Key, f func(key, value []byte) bool) error {
+	return s.RangeContext(context.Background(), seek, until, f)
+}
+
+// RangeContext performs a range query against the first pipeline, which stops as soon as the context
+// is cancelled.
+func (s *Storage) RangeContext(ctx context.Context, seek, until key.Key, f func(key, value []byte) bool) error {
+	if len(s.pipelines) == 0 {
+		return nil
+	}
+
+	return storage.RangeContext(ctx, s.pipelines[0], seek, until, f)
+}
+
+// Delete deletes the keys from every pipeline.
+func (s *Storage) Delete(keys ...key.Key) error {
+	for _, p := range s.pipelines {
+		if err := p.Delete(keys...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes every pipeline and then the shared buffer.
+func (s *Storage) Close() error {
+	objs := make([]interface{}, 0, len(s.pipelines)+1)
+	for _, p := range s.pipelines {
+		objs = append(objs, p)
+	}
+	return storage.Close(append(objs, s.buffer)...)
+}
+
+// delete marks the keys as deleted by a namespace, and removes the blocks which every namespace
+// has deleted from the buffer along with their markers.
+func (s *Storage) delete(ns *Namespace, keys []key.Key) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	for _, space := range s.spaces {
+		space.expire(now)
+	}
+
+	var marked, removed []key.Key
+	for _, k := range keys {
+		if ns.isDeleted(k, now) {
+			continue
+		}
+
+		if !s.deletedByOthers(ns, k, now) {
+			marked = append(marked, k)
+			continue
+		}
+
+		removed = append(removed, k)
+		for _, other := range s.spaces {
+			if other != ns {
+				removed = append(removed, other.markerOf(k))
+			}
+		}
+	}
+
+	// Persist the markers along with their expiry before the blocks are hidden from the namespace
+	expires := now.Add(s.ttl)
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, uint64(expires.UnixNano()))
+	for _, k := range marked {
+		if err := s.buffer.Append(ns.markerOf(k), value, s.ttl); err != nil {
+			return err
+		}
+		ns.deleted[string(k)] = expires
+	}
+
+	if len(removed) == 0 {
+		return nil
+	}
+
+	if err := s.buffer.Delete(removed...); err != nil {
+		return err
+	}
+
+	for _, other := range s.spaces {
+		for _, k := range removed {
+			delete(other.deleted, string(k))
+		}
+	}
+	return nil
+}
+
+// deletedByOthers returns whether every other namespace has deleted the key
+func (s *Storage) deletedByOthers(ns *Namespace, k key.Key, now time.Time) bool {
+	for _, other := range s.spaces {
+		if other != ns && !other.isDeleted(k, now) {
+			return false
+		}
+	}
+	return true
+}
+
+// isDeleted returns whether the namespace has deleted the key
+func (s *Storage) isDeleted(ns *Namespace, k []byte) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return ns.isDeleted(k, time.Now())
+}
@@ -0,0 +1,124 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package fanout
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kelindar/talaria/internal/config"
+	"github.com/kelindar/talaria/internal/encoding/block"
+	"github.com/kelindar/talaria/internal/encoding/key"
+	"github.com/kelindar/talaria/internal/monitor"
+	"github.com/kelindar/talaria/internal/storage"
+	"github.com/kelindar/talaria/internal/storage/disk"
+	"github.com/stretchr/testify/assert"
+)
+
+// Run runs a function on a temp store
+func run(f func(store *disk.Storage)) {
+	dir, _ := ioutil.TempDir("", "test")
+	store := disk.New(monitor.NewNoop())
+	_ = store.Open(dir, config.Badger{})
+
+	// Close once we're done and delete data
+	defer func() { _ = os.RemoveAll(dir) }()
+	defer func() { _ = store.Close() }()
+
+	f(store)
+}
+
+// count returns the number of keys in a storage
+func count(t *testing.T, store storage.Iterator) (n int) {
+	assert.NoError(t, store.Range(key.First(), key.Last(), func(k, v []byte) bool {
+		assert.Len(t, k, 16)
+		n++
+		return false
+	}))
+	return
+}
+
+// pipelines creates a pipeline per name which is simply the namespace
+func pipelines(spaces map[string]storage.Storage) PipelineFunc {
+	return func(name string, buffer storage.Storage) (storage.Storage, error) {
+		spaces[name] = buffer
+		return buffer, nil
+	}
+}
+
+func TestFanout(t *testing.T) {
+	run(func(buffer *disk.Storage) {
+		encoded, err := (&block.Block{
+			Expires: time.Now().Add(time.Hour).Unix(),
+		}).Encode()
+		assert.NoError(t, err)
+
+		// Blocks which were buffered before the split are seen by every pipeline
+		k1, k2, k3 := key.New("A", time.Unix(0, 0)), key.New("B", time.Unix(0, 0)), key.New("C", time.Unix(0, 0))
+		assert.NoError(t, buffer.Append(k1, encoded, time.Hour))
+		assert.NoError(t, buffer.Append(k2, encoded, time.Hour))
+
+		spaces := make(map[string]storage.Storage)
+		store, err := New(buffer, time.Hour, []string{"s3", "gcs"}, pipelines(spaces))
+		assert.NoError(t, err)
+		a, b := spaces["s3"], spaces["gcs"]
+		assert.Equal(t, 2, count(t, a))
+		assert.Equal(t, 2, count(t, b))
+		assert.Equal(t, 2, count(t, store))
+
+		// Every block is appended once, and each pipeline deletes it independently
+		assert.NoError(t, store.Append(k3, encoded, time.Hour))
+		assert.Equal(t, 3, count(t, buffer))
+		assert.NoError(t, a.Delete(k1, k3))
+		assert.Equal(t, 1, count(t, a))
+		assert.Equal(t, 3, count(t, b))
+
+		assert.NoError(t, store.Delete(k2))
+		assert.Equal(t, 0, count(t, a))
+		assert.Equal(t, 2, count(t, b))
+
+		// The pipelines which were ahead keep their position once reopened
+		store, err = New(buffer, time.Hour, []string{"s3", "gcs"}, pipelines(spaces))
+		assert.NoError(t, err)
+		a, b = spaces["s3"], spaces["gcs"]
+		assert.Equal(t, 0, count(t, a))
+		assert.Equal(t, 2, count(t, b))
+
+		// Once every pipeline deleted a block, the block and its markers are removed
+		assert.NoError(t, b.Delete(k1, k3))
+		var total int
+		assert.NoError(t, buffer.Range(key.First(), key.Last(), func(k, v []byte) bool {
+			total++
+			return false
+		}))
+		assert.Equal(t, 0, total)
+	})
+}
+
+func TestFanout_Expire(t *testing.T) {
+	run(func(buffer *disk.Storage) {
+		spaces := make(map[string]storage.Storage)
+		store, err := New(buffer, 50*time.Millisecond, []string{"s3", "gcs"}, pipelines(spaces))
+		assert.NoError(t, err)
+
+		// A pipeline which never deletes does not keep the keys of the other one forever
+		k1, k2 := key.New("A", time.Unix(0, 0)), key.New("B", time.Unix(0, 0))
+		assert.NoError(t, spaces["s3"].Delete(k1))
+		assert.Len(t, store.spaces[0].deleted, 1)
+
+		time.Sleep(100 * time.Millisecond)
+		assert.NoError(t, spaces["s3"].Delete(k2))
+		assert.Len(t, store.spaces[0].deleted, 1)
+		assert.Contains(t, store.spaces[0].deleted, string(k2))
+
+		// The expiry of the markers is kept once reopened
+		store, err = New(buffer, time.Hour, []string{"s3", "gcs"}, pipelines(spaces))
+		assert.NoError(t, err)
+		for _, expires := range store.spaces[0].deleted {
+			assert.True(t, expires.Before(time.Now().Add(time.Minute)))
+		}
+	})
+}
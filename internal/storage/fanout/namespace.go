@@ -0,0 +1,115 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package fanout
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"time"
+
+	"github.com/kelindar/talaria/internal/encoding/key"
+	"github.com/kelindar/talaria/internal/storage"
+)
+
+// Assert contract compliance
+var _ storage.Storage = new(Namespace)
+var _ storage.ContextIterator = new(Namespace)
+
+// Namespace represents the view of a pipeline over the shared buffer, which contains every block
+// the pipeline has not deleted yet. The deleted blocks are marked by keys prefixed with the hash
+// of the name of the namespace. The shared buffer is not closed by the view.
+type Namespace struct {
+	owner   *Storage             // The storage which owns the shared buffer
+	prefix  []byte               // The prefix of the markers
+	deleted map[string]time.Time // The keys which were deleted from the namespace, along with the expiry of their markers
+	swept   time.Time            // The time the expired keys were last removed
+}
+
+// newNamespace creates a new view of the buffer for a namespace.
+func newNamespace(owner *Storage, name string) *Namespace {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	prefix := make([]byte, 4)
+	binary.BigEndian.PutUint32(prefix, h.Sum32())
+	return &Namespace{
+		owner:   owner,
+		prefix:  prefix,
+		deleted: make(map[string]time.Time),
+		swept:   time.Now(),
+	}
+}
+
+// Append does not write anything, since the block is appended once to the shared buffer by the
+// fanout storage and the namespace sees it until it is deleted.
+func (n *Namespace) Append(k key.Key, value []byte, ttl time.Duration) error {
+	return nil
+}
+
+// Range performs a range query on the blocks of the shared buffer which were not deleted from the namespace.
+func (n *Namespace) Range(seek, until key.Key, f func(key, value []byte) bool) error {
+	return n.RangeContext(context.Background(), seek, until, f)
+}
+
+// RangeContext performs a range query on the blocks which were not deleted from the namespace, which
+// stops as soon as the context is cancelled.
+func (n *Namespace) RangeContext(ctx context.Context, seek, until key.Key, f func(key, value []byte) bool) error {
+	return storage.RangeContext(ctx, n.owner.buffer, seek, until, func(k, v []byte) bool {
+		if len(k) != keySize || n.owner.isDeleted(n, k) {
+			return false
+		}
+		return f(k, v)
+	})
+}
+
+// Delete deletes the keys from the namespace.
+func (n *Namespace) Delete(keys ...key.Key) error {
+	return n.owner.delete(n, keys)
+}
+
+// Close is a no-op, since the buffer is shared.
+func (n *Namespace) Close() error {
+	return nil
+}
+
+// load loads the markers of the keys which were deleted from the namespace. A marker without
+// its expiry is kept for another ttl.
+func (n *Namespace) load() error {
+	return n.owner.buffer.Range(n.markerOf(key.First()), n.markerOf(key.Last()), func(k, v []byte) bool {
+		expires := time.Now().Add(n.owner.ttl)
+		if len(v) == 8 {
+			expires = time.Unix(0, int64(binary.BigEndian.Uint64(v)))
+		}
+
+		n.deleted[string(k[len(n.prefix):])] = expires
+		return false
+	})
+}
+
+// isDeleted returns whether the key was deleted from the namespace and its marker has not expired
+func (n *Namespace) isDeleted(k []byte, now time.Time) bool {
+	expires, ok := n.deleted[string(k)]
+	return ok && now.Before(expires)
+}
+
+// expire removes the keys whose markers have expired, since the blocks they mark have expired
+// from the buffer as well. This is done at most once per ttl.
+func (n *Namespace) expire(now time.Time) {
+	if now.Sub(n.swept) < n.owner.ttl {
+		return
+	}
+
+	n.swept = now
+	for k, expires := range n.deleted {
+		if !now.Before(expires) {
+			delete(n.deleted, k)
+		}
+	}
+}
+
+// markerOf returns the key which marks a deleted key of the namespace
+func (n *Namespace) markerOf(k key.Key) key.Key {
+	out := make(key.Key, 0, len(n.prefix)+len(k))
+	return append(append(out, n.prefix...), k...)
+}
@@ -4,12 +4,15 @@
 package flush
 
 import (
+	"context"
+
 	"github.com/kelindar/talaria/internal/encoding/block"
 	"github.com/kelindar/talaria/internal/encoding/key"
 	"github.com/kelindar/talaria/internal/encoding/merge"
 	"github.com/kelindar/talaria/internal/encoding/typeof"
 	"github.com/kelindar/talaria/internal/monitor"
 	"github.com/kelindar/talaria/internal/storage"
+	"github.com/kelindar/talaria/internal/storage/compact"
 )
 
 // Writer represents a sink for the flusher.
@@ -17,38 +20,68 @@ type Writer interface {
 	Write(key key.Key, value []byte) error
 }
 
+// contextWriter represents a sink which writes along with the context of the compaction job
+type contextWriter interface {
+	WriteContext(ctx context.Context, key key.Key, value []byte) error
+}
+
+// NameFunc represents a function which names a file, given the compaction job and the last row of the file.
+type NameFunc func(compact.Job, map[string]interface{}) (string, error)
+
 // Flusher represents a flusher/merger.
 type Flusher struct {
-	monitor      monitor.Monitor // The monitor client
-	writer       Writer          // The underlying block writer
-	merge        merge.Func      // The function used to merge blocks
-	fileNameFunc func(map[string]interface{}) (string, error)
+	monitor      monitor.Monitor  // The monitor client
+	writer       Writer           // The underlying block writer
+	merge        merge.Func       // The function used to merge blocks
+	fileNameFunc NameFunc         // The function used to name the files
 	streamer     storage.Streamer // The underlying row writer
+	sortBy       string           // The column the rows are sorted by, partitioned by its date
+	partitionBy  []string         // The columns to partition the files by
 }
 
-// ForCompaction creates a new storage implementation.
-func ForCompaction(monitor monitor.Monitor, writer Writer, encoder string, fileNameFunc func(map[string]interface{}) (string, error)) (*Flusher, error) {
-	mergeFn, err := merge.New(encoder)
-	if err != nil {
-		return nil, err
-	}
-
+// ForCompaction creates a new storage implementation. If partition columns are specified, each
+// file contains the rows of exactly one partition and is written under the hive-style path of
+// the partition (e.g. "time=2020-01-02/event=click/<file name>").
+func ForCompaction(monitor monitor.Monitor, writer Writer, mergeFn merge.Func, fileNameFunc NameFunc, sortBy string, partitionBy ...string) *Flusher {
 	return &Flusher{
 		monitor:      monitor,
 		writer:       writer,
 		merge:        mergeFn,
 		fileNameFunc: fileNameFunc,
-	}, nil
+		sortBy:       sortBy,
+		partitionBy:  partitionBy,
+	}
 }
 
 // TODO: ForStreaming
 
 // WriteBlock writes a one or multiple blocks to the underlying writer.
-func (s *Flusher) WriteBlock(blocks []block.Block, schema typeof.Schema) error {
+func (s *Flusher) WriteBlock(ctx context.Context, job compact.Job, blocks []block.Block, schema typeof.Schema) error {
 	if s.writer == nil || len(blocks) == 0 {
 		return nil
 	}
 
+	if len(s.partitionBy) == 0 {
+		return s.writeBlock(ctx, job, blocks, schema, "")
+	}
+
+	// Split the blocks so that each file contains a single partition
+	partitions, err := partition(blocks, schema, s.sortBy, s.partitionBy)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range partitions {
+		if err := s.writeBlock(ctx, job, []block.Block{p.block}, schema, p.path+"/"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBlock merges the blocks and writes them to the underlying writer as a single file.
+func (s *Flusher) writeBlock(ctx context.Context, job compact.Job, blocks []block.Block, schema typeof.Schema, prefix string) error {
+
 	// Merge the blocks based on the specified merging function
 	buffer, err := s.merge(blocks, schema)
 	if err != nil {
@@ -56,7 +89,11 @@ func (s *Flusher) WriteBlock(blocks []block.Block, schema typeof.Schema) error {
 	}
 
 	// Generate the file name and write the data to the underlying writer
-	return s.writer.Write(s.generateFileName(blocks[0]), buffer)
+	name := append([]byte(prefix), s.generateFileName(job, blocks[0])...)
+	if w, ok := s.writer.(contextWriter); ok {
+		return w.WriteContext(ctx, name, buffer)
+	}
+	return s.writer.Write(name, buffer)
 }
 
 // WriteRow writes a single row to the underlying writer (i.e. streamer).
@@ -69,12 +106,12 @@ func (s *Flusher) WriteRow(r block.Row) error {
 	return s.streamer.Stream(r)
 }
 
-func (s *Flusher) generateFileName(b block.Block) []byte {
+func (s *Flusher) generateFileName(job compact.Job, b block.Block) []byte {
 	row, err := b.LastRow()
 	if err != nil {
 		return []byte{}
 	}
-	output, err := s.fileNameFunc(row)
+	output, err := s.fileNameFunc(job, row)
 	if err != nil {
 		s.monitor.Error(err)
 		return []byte{}
@@ -10,16 +10,18 @@ import (
 	eorc "github.com/crphang/orc"
 	"github.com/kelindar/talaria/internal/column"
 	"github.com/kelindar/talaria/internal/encoding/block"
+	"github.com/kelindar/talaria/internal/encoding/merge"
 	"github.com/kelindar/talaria/internal/encoding/orc"
 	"github.com/kelindar/talaria/internal/encoding/typeof"
 	"github.com/kelindar/talaria/internal/monitor"
 	script "github.com/kelindar/talaria/internal/scripting"
+	"github.com/kelindar/talaria/internal/storage/compact"
 	"github.com/kelindar/talaria/internal/storage/writer/noop"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestNameFunc(t *testing.T) {
-	fileNameFunc := func(row map[string]interface{}) (string, error) {
+	fileNameFunc := func(_ compact.Job, row map[string]interface{}) (string, error) {
 		lua, _ := column.NewComputed("fileName", typeof.String, `
 	function main(row)
 
@@ -52,7 +54,7 @@ func TestNameFunc(t *testing.T) {
 		return output.(string), err
 	}
 
-	flusher, _ := ForCompaction(monitor.NewNoop(), noop.New(), "orc", fileNameFunc)
+	flusher := ForCompaction(monitor.NewNoop(), noop.New(), merge.ToOrc, fileNameFunc, "")
 	schema := typeof.Schema{
 		"col0": typeof.String,
 		"col1": typeof.Timestamp,
@@ -73,7 +75,7 @@ func TestNameFunc(t *testing.T) {
 	apply := block.Transform(nil)
 
 	blocks, err := block.FromOrcBy(orcBuffer.Bytes(), "col0", nil, apply)
-	fileName := flusher.generateFileName(blocks[0])
+	fileName := flusher.generateFileName(compact.Job{}, blocks[0])
 
 	assert.Equal(t, "year=46970/month=3/day=29/ns=eventName/0-0-0-127.0.0.1.orc", string(fileName))
 
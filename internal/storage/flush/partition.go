@@ -0,0 +1,103 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package flush
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kelindar/talaria/internal/column"
+	"github.com/kelindar/talaria/internal/encoding/block"
+	"github.com/kelindar/talaria/internal/encoding/encoder"
+	"github.com/kelindar/talaria/internal/encoding/typeof"
+	"github.com/kelindar/talaria/internal/monitor/errors"
+	"github.com/kelindar/talaria/internal/presto"
+)
+
+// The partition value used for null values, same as Hive
+const nullPartition = "__HIVE_DEFAULT_PARTITION__"
+
+// partitioned represents a block containing the rows of a single partition
+type partitioned struct {
+	path  string      // The hive-style path of the partition
+	block block.Block // The rows of the partition
+}
+
+// partitionPath returns the hive-style path of the partition of a row, for example
+// "time=2020-01-02/event=click". Timestamp columns and the integer time of the sortBy
+// column are partitioned by their date.
+func partitionPath(row map[string]interface{}, sortBy string, partitionBy []string) string {
+	parts := make([]string, 0, len(partitionBy))
+	for _, name := range partitionBy {
+		parts = append(parts, partitionOf(name, row[name], name == sortBy))
+	}
+	return strings.Join(parts, "/")
+}
+
+// partitionOf returns the path element of a partition column
+func partitionOf(name string, value interface{}, isTime bool) string {
+	if v, ok := value.(int64); ok && isTime {
+		value = presto.TimeOf(v)
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return name + "=" + nullPartition
+	case time.Time:
+		return name + "=" + v.UTC().Format("2006-01-02")
+	default:
+		return name + "=" + url.PathEscape(fmt.Sprintf("%v", v))
+	}
+}
+
+// partition splits the rows of the blocks into a single block per partition
+func partition(blocks []block.Block, schema typeof.Schema, sortBy string, partitionBy []string) ([]partitioned, error) {
+	var order []string
+	names := schema.Columns()
+	partitions := make(map[string]column.Columns, 4)
+	row := make(map[string]interface{}, len(names))
+	if err := encoder.RangeRows(blocks, schema, func(values []interface{}) error {
+		for i, name := range names {
+			row[name] = values[i]
+		}
+
+		// Find the partition of the row, create it if it does not exist yet
+		path := partitionPath(row, sortBy, partitionBy)
+		cols, ok := partitions[path]
+		if !ok {
+			cols = column.MakeColumns(&schema)
+			partitions[path] = cols
+			order = append(order, path)
+		}
+
+		for i, name := range names {
+			cols.Append(name, values[i], schema[name])
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	// Get the max expiration time of the blocks
+	expires := int64(0)
+	for _, b := range blocks {
+		if b.Expires > expires {
+			expires = b.Expires
+		}
+	}
+
+	output := make([]partitioned, 0, len(order))
+	for _, path := range order {
+		blk, err := block.FromColumns(string(blocks[0].Key), partitions[path])
+		if err != nil {
+			return nil, errors.Internal("flush: unable to partition blocks", err)
+		}
+
+		blk.Expires = expires
+		output = append(output, partitioned{path: path, block: blk})
+	}
+	return output, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package flush
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kelindar/talaria/internal/column"
+	"github.com/kelindar/talaria/internal/encoding/block"
+	"github.com/kelindar/talaria/internal/encoding/key"
+	"github.com/kelindar/talaria/internal/encoding/merge"
+	"github.com/kelindar/talaria/internal/encoding/typeof"
+	"github.com/kelindar/talaria/internal/monitor"
+	"github.com/kelindar/talaria/internal/storage/compact"
+	"github.com/stretchr/testify/assert"
+)
+
+type fileWriter map[string][]byte
+
+func (w fileWriter) Write(key key.Key, value []byte) error {
+	w[string(key)] = value
+	return nil
+}
+
+func TestPartitionPath(t *testing.T) {
+	path := partitionPath(map[string]interface{}{
+		"time":  time.Date(2020, 1, 2, 23, 0, 0, 0, time.UTC),
+		"event": "a/b",
+	}, "", []string{"time", "event", "missing"})
+	assert.Equal(t, "time=2020-01-02/event=a%2Fb/missing=__HIVE_DEFAULT_PARTITION__", path)
+}
+
+func TestPartitionPath_SortBy(t *testing.T) {
+	ts := time.Date(2020, 1, 2, 23, 0, 0, 0, time.UTC)
+	for _, v := range []int64{ts.Unix(), ts.UnixNano() / 1e6, ts.UnixNano()} {
+		path := partitionPath(map[string]interface{}{
+			"ingested": v,
+			"count":    v,
+		}, "ingested", []string{"ingested", "count"})
+		assert.Equal(t, fmt.Sprintf("ingested=2020-01-02/count=%d", v), path)
+	}
+}
+
+func TestPartition(t *testing.T) {
+	schema := typeof.Schema{
+		"event": typeof.String,
+		"time":  typeof.Timestamp,
+		"value": typeof.Int64,
+	}
+
+	day := int64(24 * 60 * 60)
+	cols := column.MakeColumns(&schema)
+	for i, event := range []string{"a", "b", "a", "a", "b"} {
+		cols.Append("event", event, typeof.String)
+		cols.Append("time", int64(i%2)*day, typeof.Timestamp)
+		cols.Append("value", int64(i), typeof.Int64)
+	}
+
+	blk, err := block.FromColumns("test", cols)
+	assert.NoError(t, err)
+
+	// Name each file by its partition and the number of rows
+	output := make(fileWriter)
+	flusher := ForCompaction(monitor.NewNoop(), output, merge.ToOrc, func(_ compact.Job, row map[string]interface{}) (string, error) {
+		return fmt.Sprintf("%v.orc", row["value"]), nil
+	}, "", "time", "event")
+
+	assert.NoError(t, flusher.WriteBlock(context.Background(), compact.Job{}, []block.Block{blk}, schema))
+	var names []string
+	for name := range output {
+		names = append(names, name)
+	}
+
+	assert.ElementsMatch(t, []string{
+		"time=1970-01-01/event=a/2.orc",
+		"time=1970-01-01/event=b/4.orc",
+		"time=1970-01-02/event=a/3.orc",
+		"time=1970-01-02/event=b/1.orc",
+	}, names)
+}
@@ -0,0 +1,256 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package memory
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/btree"
+	"github.com/grab/async"
+	"github.com/kelindar/talaria/internal/encoding/key"
+	"github.com/kelindar/talaria/internal/monitor"
+	"github.com/kelindar/talaria/internal/monitor/errors"
+	"github.com/kelindar/talaria/internal/storage"
+)
+
+const (
+	ctxTag    = "memory"
+	errClosed = "unable to run commands on a closed database"
+	errTooBig = "unable to append an item larger than the maximum size"
+)
+
+// Assert contract compliance
+var _ storage.Storage = new(Storage)
+var _ storage.ContextIterator = new(Storage)
+
+// item represents a single key/value pair kept in memory
+type item struct {
+	key     key.Key       // The key of the item
+	value   []byte        // The value of the item
+	expires int64         // The expiration time, in unix nanoseconds
+	element *list.Element // The element in the insertion order, used for eviction
+}
+
+// Less compares the keys of two items
+func (i *item) Less(than btree.Item) bool {
+	return bytes.Compare(i.key, than.(*item).key) < 0
+}
+
+// size returns the number of bytes the item accounts for
+func (i *item) size() int64 {
+	return int64(len(i.key) + len(i.value))
+}
+
+// Storage represents an in-memory storage, sorted by key. The items expire after their TTL and
+// once the storage grows beyond its maximum size, the oldest items are evicted.
+type Storage struct {
+	lock    sync.RWMutex
+	closed  bool            // The closed flag
+	gc      async.Task      // Closing channel
+	tree    *btree.BTree    // The sorted items
+	order   *list.List      // The items in insertion order
+	size    int64           // The current size, in bytes
+	maxSize int64           // The maximum size, in bytes, 0 means unlimited
+	monitor monitor.Monitor // The stats client
+}
+
+// New creates a new in-memory storage with an optional maximum size, in bytes.
+func New(maxSize int64, m monitor.Monitor) *Storage {
+	s := &Storage{
+		tree:    btree.New(32),
+		order:   list.New(),
+		maxSize: maxSize,
+		monitor: m,
+	}
+
+	s.gc = async.Repeat(context.Background(), 1*time.Minute, s.GC)
+	return s
+}
+
+// Append adds an event into the storage.
+func (s *Storage) Append(key key.Key, value []byte, ttl time.Duration) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return errors.New(errClosed)
+	}
+
+	// An item which does not fit would evict every other item, including itself
+	if s.maxSize > 0 && int64(len(key)+len(value)) > s.maxSize {
+		return errors.InvalidArgument(errTooBig)
+	}
+
+	// Copy the key and the value, since the caller might reuse the buffers
+	v := &item{
+		key:     append([]byte{}, key...),
+		value:   append([]byte{}, value...),
+		expires: time.Now().Add(ttl).UnixNano(),
+	}
+
+	if old := s.tree.ReplaceOrInsert(v); old != nil {
+		s.forget(old.(*item))
+	}
+
+	v.element = s.order.PushBack(v)
+	s.size += v.size()
+	s.evict()
+	return nil
+}
+
+// Range performs a range query against the storage. It calls f sequentially for each key and value present in
+// the store. If f returns true, range stops the iteration. The keys are lexigraphically sorted and the expired
+// items are skipped.
+func (s *Storage) Range(seek, until key.Key, f func(key, value []byte) bool) error {
+	return s.RangeContext(context.Background(), seek, until, f)
+}
+
+// RangeContext performs a range query against the storage, which stops as soon as the context is cancelled
+// and returns the error of the context.
+func (s *Storage) RangeContext(ctx context.Context, seek, until key.Key, f func(key, value []byte) bool) error {
+	items, err := s.find(seek, until)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range items {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if f(v.key, v.value) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// find returns the items which have not expired within the range, so the callback is invoked
+// without holding the lock.
+func (s *Storage) find(seek, until key.Key) ([]*item, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if s.closed {
+		return nil, errors.New(errClosed)
+	}
+
+	var items []*item
+	now := time.Now().UnixNano()
+	s.tree.AscendGreaterOrEqual(&item{key: seek}, func(i btree.Item) bool {
+		v := i.(*item)
+		if bytes.Compare(v.key, until) > 0 {
+			return false // Stop if we're reached the end
+		}
+
+		if v.expires > now {
+			items = append(items, v)
+		}
+		return true
+	})
+	return items, nil
+}
+
+// Delete deletes one or multiple keys from the storage.
+func (s *Storage) Delete(keys ...key.Key) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return errors.New(errClosed)
+	}
+
+	for _, k := range keys {
+		if old := s.tree.Delete(&item{key: k}); old != nil {
+			s.forget(old.(*item))
+		}
+	}
+	return nil
+}
+
+// GC runs the garbage collection on the storage, removing the expired items
+func (s *Storage) GC(ctx context.Context) (interface{}, error) {
+	if s.gc != nil && s.gc.State() == async.IsCancelled {
+		return nil, nil
+	}
+
+	deleted, total := s.purge()
+	s.monitor.Gauge(ctxTag, "GC.purge", float64(deleted), "type:deleted")
+	s.monitor.Gauge(ctxTag, "GC.purge", float64(total), "type:total")
+	s.monitor.Gauge(ctxTag, "size", float64(s.Size()))
+	return nil, nil
+}
+
+// Size returns the current size of the storage, in bytes
+func (s *Storage) Size() int64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.size
+}
+
+// Close is used to gracefully close the storage and release the memory.
+func (s *Storage) Close() error {
+	if s.gc != nil {
+		s.gc.Cancel()
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.closed = true
+	s.tree = btree.New(32)
+	s.order.Init()
+	s.size = 0
+	return nil
+}
+
+// purge removes the expired items from the storage
+func (s *Storage) purge() (deleted, total int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return
+	}
+
+	var expired []btree.Item
+	now := time.Now().UnixNano()
+	total = s.tree.Len()
+	s.tree.Ascend(func(i btree.Item) bool {
+		if i.(*item).expires <= now {
+			expired = append(expired, i)
+		}
+		return true
+	})
+
+	for _, v := range expired {
+		s.tree.Delete(v)
+		s.forget(v.(*item))
+	}
+	return len(expired), total
+}
+
+// evict removes the oldest items until the storage fits within its maximum size
+func (s *Storage) evict() {
+	if s.maxSize <= 0 {
+		return
+	}
+
+	evicted := 0
+	for s.size > s.maxSize && s.order.Len() > 0 {
+		v := s.order.Front().Value.(*item)
+		s.tree.Delete(v)
+		s.forget(v)
+		evicted++
+	}
+
+	if evicted > 0 {
+		s.monitor.Count(ctxTag, "evict", int64(evicted))
+	}
+}
+
+// forget removes an item, which is no longer in the tree, from the insertion order and the size
+func (s *Storage) forget(v *item) {
+	s.order.Remove(v.element)
+	s.size -= v.size()
+}
@@ -0,0 +1,137 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kelindar/talaria/internal/encoding/key"
+	"github.com/kelindar/talaria/internal/monitor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRange(t *testing.T) {
+	store := New(0, monitor.NewNoop())
+	defer store.Close()
+
+	// Insert out of order
+	assert.NoError(t, store.Append(key.Key("1"), []byte("A"), time.Minute))
+	assert.NoError(t, store.Append(key.Key("3"), []byte("C"), time.Minute))
+	assert.NoError(t, store.Append(key.Key("2"), []byte("B"), time.Minute))
+	assert.NoError(t, store.Append(key.Key("6"), []byte("F"), time.Minute))
+
+	// Iterate in order
+	var values []string
+	assert.NoError(t, store.Range(key.Key("1"), key.Key("5"), func(k, v []byte) bool {
+		values = append(values, string(v))
+		return false
+	}))
+	assert.Equal(t, []string{"A", "B", "C"}, values)
+
+	// Stop the iteration early
+	values = values[:0]
+	assert.NoError(t, store.Range(key.Key("2"), key.Key("6"), func(k, v []byte) bool {
+		values = append(values, string(v))
+		return true
+	}))
+	assert.Equal(t, []string{"B"}, values)
+}
+
+func TestRangeContext(t *testing.T) {
+	store := New(0, monitor.NewNoop())
+	defer store.Close()
+
+	assert.NoError(t, store.Append(key.Key("1"), []byte("A"), time.Minute))
+	assert.NoError(t, store.Append(key.Key("2"), []byte("B"), time.Minute))
+
+	// The iteration stops once the context is cancelled
+	var values []string
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.Equal(t, context.Canceled, store.RangeContext(ctx, key.Key("1"), key.Key("5"), func(k, v []byte) bool {
+		values = append(values, string(v))
+		cancel()
+		return false
+	}))
+	assert.Equal(t, []string{"A"}, values)
+}
+
+func TestReplace(t *testing.T) {
+	store := New(0, monitor.NewNoop())
+	defer store.Close()
+
+	assert.NoError(t, store.Append(key.Key("1"), []byte("A"), time.Minute))
+	assert.NoError(t, store.Append(key.Key("1"), []byte("BB"), time.Minute))
+	assert.Equal(t, int64(3), store.Size())
+
+	count := 0
+	assert.NoError(t, store.Range(key.Key("0"), key.Key("9"), func(k, v []byte) bool {
+		assert.Equal(t, "BB", string(v))
+		count++
+		return false
+	}))
+	assert.Equal(t, 1, count)
+}
+
+func TestExpire(t *testing.T) {
+	store := New(0, monitor.NewNoop())
+	defer store.Close()
+
+	assert.NoError(t, store.Append(key.Key("1"), []byte("A"), -time.Second))
+	assert.NoError(t, store.Append(key.Key("2"), []byte("B"), time.Minute))
+
+	// Expired items are skipped
+	var values []string
+	assert.NoError(t, store.Range(key.Key("0"), key.Key("9"), func(k, v []byte) bool {
+		values = append(values, string(v))
+		return false
+	}))
+	assert.Equal(t, []string{"B"}, values)
+	assert.Equal(t, int64(4), store.Size())
+
+	// And purged by the garbage collection
+	_, err := store.GC(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), store.Size())
+}
+
+func TestEvict(t *testing.T) {
+	store := New(4, monitor.NewNoop())
+	defer store.Close()
+
+	assert.NoError(t, store.Append(key.Key("3"), []byte("A"), time.Minute))
+	assert.NoError(t, store.Append(key.Key("1"), []byte("B"), time.Minute))
+	assert.NoError(t, store.Append(key.Key("2"), []byte("C"), time.Minute))
+
+	// The oldest item was evicted
+	var keys []string
+	assert.NoError(t, store.Range(key.Key("0"), key.Key("9"), func(k, v []byte) bool {
+		keys = append(keys, string(k))
+		return false
+	}))
+	assert.Equal(t, []string{"1", "2"}, keys)
+	assert.Equal(t, int64(4), store.Size())
+
+	// An item larger than the maximum size is rejected and nothing is evicted
+	assert.Error(t, store.Append(key.Key("4"), []byte("DDDD"), time.Minute))
+	assert.Equal(t, int64(4), store.Size())
+}
+
+func TestDelete(t *testing.T) {
+	store := New(0, monitor.NewNoop())
+
+	assert.NoError(t, store.Append(key.Key("1"), []byte("A"), time.Minute))
+	assert.NoError(t, store.Append(key.Key("2"), []byte("B"), time.Minute))
+	assert.NoError(t, store.Delete(key.Key("1"), key.Key("5")))
+	assert.Equal(t, int64(2), store.Size())
+
+	// Once closed, every command fails
+	assert.NoError(t, store.Close())
+	assert.Error(t, store.Append(key.Key("1"), []byte("A"), time.Minute))
+	assert.Error(t, store.Delete(key.Key("1")))
+	assert.Error(t, store.Range(key.Key("0"), key.Key("9"), func(k, v []byte) bool {
+		return false
+	}))
+}
@@ -4,6 +4,7 @@
 package storage
 
 import (
+	"context"
 	"io"
 	"time"
 
@@ -31,6 +32,16 @@ type Appender interface {
 	Append(key key.Key, value []byte, ttl time.Duration) error
 }
 
+// ContextIterator represents an iterator which stops as soon as its context is cancelled.
+type ContextIterator interface {
+	RangeContext(ctx context.Context, seek, until key.Key, f func(key, value []byte) bool) error
+}
+
+// ContextAppender represents an appender which does not append once its context is cancelled.
+type ContextAppender interface {
+	AppendContext(ctx context.Context, key key.Key, value []byte, ttl time.Duration) error
+}
+
 // Merger represents a contract that merges two or more blocks together.
 type Merger interface {
 	Merge([]block.Block, typeof.Schema) ([]byte, []byte)
@@ -53,3 +64,34 @@ func Close(objs ...interface{}) error {
 	}
 	return result
 }
+
+// RangeContext performs a range query against an iterator, which stops as soon as the context is
+// cancelled. If the iteration was interrupted, the error of the context is returned.
+func RangeContext(ctx context.Context, iter Iterator, seek, until key.Key, f func(key, value []byte) bool) error {
+	if it, ok := iter.(ContextIterator); ok {
+		return it.RangeContext(ctx, seek, until, f)
+	}
+
+	var cancelled error
+	if err := iter.Range(seek, until, func(key, value []byte) bool {
+		if cancelled = ctx.Err(); cancelled != nil {
+			return true
+		}
+		return f(key, value)
+	}); err != nil {
+		return err
+	}
+	return cancelled
+}
+
+// AppendContext appends a value to an appender, unless the context is already cancelled.
+func AppendContext(ctx context.Context, appender Appender, key key.Key, value []byte, ttl time.Duration) error {
+	if a, ok := appender.(ContextAppender); ok {
+		return a.AppendContext(ctx, key, value, ttl)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return appender.Append(key, value, ttl)
+}
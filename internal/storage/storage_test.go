@@ -4,8 +4,12 @@
 package storage
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
+
+	"github.com/kelindar/talaria/internal/encoding/key"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -39,3 +43,45 @@ func TestClose(t *testing.T) {
 		assert.Error(t, err)
 	}
 }
+
+// slice represents a storage of a set of ordered values
+type slice []string
+
+func (s *slice) Range(seek, until key.Key, f func(key, value []byte) bool) error {
+	for _, v := range *s {
+		if f(nil, []byte(v)) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *slice) Append(key key.Key, value []byte, ttl time.Duration) error {
+	*s = append(*s, string(value))
+	return nil
+}
+
+func TestRangeContext(t *testing.T) {
+	values := slice{"a", "b", "c"}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var out []string
+	err := RangeContext(ctx, &values, nil, nil, func(_, v []byte) bool {
+		out = append(out, string(v))
+		cancel()
+		return false
+	})
+
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, []string{"a"}, out)
+}
+
+func TestAppendContext(t *testing.T) {
+	var values slice
+	assert.NoError(t, AppendContext(context.Background(), &values, nil, []byte("a"), time.Minute))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, AppendContext(ctx, &values, nil, []byte("b"), time.Minute))
+	assert.Equal(t, slice{"a"}, values)
+}
@@ -0,0 +1,295 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package tiered
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kelindar/talaria/internal/encoding/block"
+	"github.com/kelindar/talaria/internal/encoding/key"
+	"github.com/kelindar/talaria/internal/encoding/merge"
+	"github.com/kelindar/talaria/internal/encoding/typeof"
+	"github.com/kelindar/talaria/internal/monitor"
+	"github.com/kelindar/talaria/internal/monitor/errors"
+	"github.com/kelindar/talaria/internal/storage"
+	"github.com/kelindar/talaria/internal/storage/cold"
+)
+
+// Assert contract compliance
+var _ storage.Storage = new(Storage)
+var _ storage.ContextIterator = new(Storage)
+
+const (
+	ctxTag     = "tiered"
+	nameLayout = "year=2006/month=1/day=2/15-04-05"
+	maxSize    = 64 << 20 // The maximum size of the blocks merged into a single file
+)
+
+// Tier represents a tier of columnar files, such as a local directory or an object store.
+type Tier interface {
+	cold.Source
+	Write(key.Key, []byte) error
+	Delete(name string) error
+}
+
+// Level represents a tier of files along with the duration for which the files are kept in it.
+type Level struct {
+	Tier Tier          // The tier containing the files
+	TTL  time.Duration // The duration for which files are kept, based on the time they were written. Zero keeps them forever
+}
+
+// Storage represents a tiered storage. Blocks are kept in the hot storage and aged, based on the time they
+// were inserted, into parquet files of the next levels (e.g. a local directory and then an object store).
+// The time of the keys is not used, since its unit depends on the sortBy column of the table.
+type Storage struct {
+	storage.Storage                    // The hot storage, reading through the levels
+	hot             storage.Storage    // The hot storage
+	ttl             time.Duration      // The TTL of the table, used to derive the insertion time from the expiration of the blocks
+	hotTTL          time.Duration      // The duration for which blocks are kept in the hot storage
+	interval        time.Duration      // How often the blocks and files are aged
+	levels          []Level            // The levels of files
+	monitor         monitor.Monitor    // The monitor client
+	cancel          context.CancelFunc // Cancels the aging worker
+	done            chan struct{}      // Closed once the aging worker has stopped
+}
+
+// New creates a new tiered storage for a table with the specified TTL.
+func New(hot storage.Storage, ttl, hotTTL, interval time.Duration, hashBy, sortBy string, monitor monitor.Monitor, levels ...Level) *Storage {
+	readers := make([]storage.Iterator, 0, len(levels))
+	for _, level := range levels {
+		readers = append(readers, cold.New(level.Tier, "parquet", true, hashBy, sortBy, monitor))
+	}
+
+	s := &Storage{
+		Storage:  cold.ReadThrough(hot, readers...),
+		hot:      hot,
+		ttl:      ttl,
+		hotTTL:   hotTTL,
+		interval: interval,
+		levels:   levels,
+		monitor:  monitor,
+		done:     make(chan struct{}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	go s.ageEvery(ctx)
+	return s
+}
+
+// ageEvery ages the tiers on a regular interval, until the context is cancelled.
+func (s *Storage) ageEvery(ctx context.Context) {
+	defer close(s.done)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.interval):
+			_, _ = s.Age(ctx)
+		}
+	}
+}
+
+// Append adds an event into the hot storage. The blocks are kept at least until they are aged.
+func (s *Storage) Append(k key.Key, value []byte, ttl time.Duration) error {
+	if min := s.hotTTL + 2*s.interval; len(s.levels) > 0 && ttl < min {
+		ttl = min
+	}
+
+	return s.hot.Append(k, value, ttl)
+}
+
+// RangeContext performs a range query against the hot storage and the levels, which stops as soon as the
+// context is cancelled, in between the files read from the levels.
+func (s *Storage) RangeContext(ctx context.Context, seek, until key.Key, f func(key, value []byte) bool) error {
+	return storage.RangeContext(ctx, s.Storage, seek, until, f)
+}
+
+// Age moves the blocks and files which are old enough into their next tier.
+func (s *Storage) Age(ctx context.Context) (interface{}, error) {
+	if len(s.levels) == 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	if err := s.ageBlocks(ctx, now); err != nil {
+		s.monitor.Count1(ctxTag, "error", "type:hot")
+		s.monitor.Error(err)
+	}
+
+	for i := range s.levels {
+		if err := s.ageFiles(ctx, i, now); err != nil {
+			s.monitor.Count1(ctxTag, "error", "type:files")
+			s.monitor.Error(err)
+		}
+	}
+	return nil, nil
+}
+
+// ageBlocks merges the blocks of the hot storage which are old enough into parquet files of the first level,
+// one or multiple files for each hash, and deletes them from the hot storage once written.
+func (s *Storage) ageBlocks(ctx context.Context, now time.Time) error {
+	until := now.Add(-s.hotTTL).Unix()
+	var hash uint32
+	var day, inserted int64
+	var size int
+	var keys []key.Key
+	var blocks []block.Block
+	schema := make(typeof.Schema, 4)
+
+	var result error
+	flush := func() {
+		if len(keys) > 0 {
+			if err := s.writeBlocks(keys, blocks, schema, time.Unix(inserted, 0)); err != nil {
+				result = err
+			}
+		}
+
+		keys, blocks, size = nil, nil, 0
+		schema = make(typeof.Schema, 4)
+	}
+
+	if err := s.hot.Range(key.First(), key.Last(), func(k, v []byte) bool {
+		if ctx.Err() != nil {
+			return true
+		}
+
+		input, err := block.FromBuffer(v)
+		if err != nil {
+			s.monitor.Error(errors.Internal("tiered: unable to read a buffer", err))
+			return false
+		}
+
+		// The blocks expire after the TTL of the table, which gives the time they were inserted
+		insertedAt := s.insertedAt(input, until)
+		if insertedAt >= until {
+			return false
+		}
+
+		// Each file contains a single hash, day and schema, up to a maximum size
+		merged, ok := schema.Union(input.Schema())
+		today := insertedAt / 86400
+		if len(keys) > 0 && (!ok || key.HashOf(k) != hash || today != day || size >= maxSize) {
+			flush()
+			merged = input.Schema()
+		}
+
+		if len(keys) == 0 || insertedAt < inserted {
+			inserted = insertedAt
+		}
+
+		hash = key.HashOf(k)
+		day = today
+		schema = merged
+		size += len(v)
+		keys = append(keys, key.Clone(k))
+		blocks = append(blocks, input)
+		return false
+	}); err != nil {
+		return err
+	}
+
+	flush()
+	return result
+}
+
+// insertedAt returns the time at which a block was inserted, in unix seconds. Blocks without an expiration
+// were not inserted through a table, they are considered old enough to be aged right away.
+func (s *Storage) insertedAt(b block.Block, until int64) int64 {
+	if b.Expires <= 0 {
+		return until - 1
+	}
+	return b.Expires - int64(s.ttl/time.Second)
+}
+
+// writeBlocks writes the blocks into a parquet file of the first level and deletes them from the hot storage.
+// The file is named after the time the blocks were inserted, along with a hash of the keys, so a retried
+// write overwrites the same file.
+func (s *Storage) writeBlocks(keys []key.Key, blocks []block.Block, schema typeof.Schema, inserted time.Time) error {
+	buffer, err := merge.NewParquet(nil)(blocks, schema)
+	if err != nil {
+		return err
+	}
+
+	h := fnv.New64a()
+	for _, k := range keys {
+		_, _ = h.Write(k)
+	}
+
+	name := fmt.Sprintf("%s-%016x.parquet", inserted.UTC().Format(nameLayout), h.Sum64())
+	if err := s.levels[0].Tier.Write(key.Key(name), buffer); err != nil {
+		return err
+	}
+
+	s.monitor.Count(ctxTag, "aged", int64(len(keys)), "tier:hot")
+	return s.hot.Delete(keys...)
+}
+
+// ageFiles moves the files of a level which are old enough into the next level, or deletes them if
+// this is the last level.
+func (s *Storage) ageFiles(ctx context.Context, i int, now time.Time) error {
+	level := s.levels[i]
+	if level.TTL <= 0 {
+		return nil
+	}
+
+	names, err := level.Tier.List("")
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if t, ok := timeOf(name); !ok || now.Sub(t) < level.TTL {
+			continue
+		}
+
+		if i+1 < len(s.levels) {
+			b, err := level.Tier.Read(name)
+			if err != nil {
+				return err
+			}
+
+			if err := s.levels[i+1].Tier.Write(key.Key(name), b); err != nil {
+				return err
+			}
+		}
+
+		if err := level.Tier.Delete(name); err != nil {
+			return err
+		}
+		s.monitor.Count1(ctxTag, "aged", fmt.Sprintf("tier:%d", i))
+	}
+	return nil
+}
+
+// Close is used to gracefully close storage, waiting for the aging in progress to stop.
+func (s *Storage) Close() error {
+	s.cancel()
+	<-s.done
+	return storage.Close(s.hot)
+}
+
+// timeOf returns the time of a file written by the tiered storage
+func timeOf(name string) (time.Time, bool) {
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	idx := strings.LastIndex(base, "-")
+	if idx < 0 {
+		return time.Time{}, false
+	}
+
+	t, err := time.Parse(nameLayout, base[:idx])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,165 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package tiered
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kelindar/talaria/internal/column"
+	"github.com/kelindar/talaria/internal/config"
+	"github.com/kelindar/talaria/internal/encoding/block"
+	"github.com/kelindar/talaria/internal/encoding/key"
+	"github.com/kelindar/talaria/internal/encoding/typeof"
+	"github.com/kelindar/talaria/internal/monitor"
+	"github.com/kelindar/talaria/internal/storage/disk"
+	"github.com/kelindar/talaria/internal/storage/writer/file"
+	"github.com/stretchr/testify/assert"
+)
+
+var schema = typeof.Schema{
+	"event": typeof.String,
+	"tsi":   typeof.Int64,
+	"value": typeof.Int64,
+}
+
+// newBlock creates an encoded block with a row per value, inserted at a specific time into a table with a TTL of an hour
+func newBlock(event string, tsi int64, inserted time.Time, values ...int64) []byte {
+	cols := column.MakeColumns(&schema)
+	for _, v := range values {
+		cols.Append("event", event, typeof.String)
+		cols.Append("tsi", tsi, typeof.Int64)
+		cols.Append("value", v, typeof.Int64)
+	}
+
+	b, _ := block.FromColumns(event, cols)
+	b.Expires = inserted.Add(time.Hour).Unix()
+	out, _ := b.Encode()
+	return out
+}
+
+// count returns the number of rows of an event in the storage
+func count(t *testing.T, s *Storage, event string) (rows int) {
+	seek := key.WithSequence(event, time.Unix(0, 0), 0)
+	until := key.WithSequence(event, time.Now().Add(time.Hour), math.MaxUint32)
+	assert.NoError(t, s.Range(seek, until, func(k, v []byte) bool {
+		cols, err := block.Read(v, schema)
+		assert.NoError(t, err)
+		rows += cols.Max()
+		return false
+	}))
+	return
+}
+
+func TestTiered(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "tiered")
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	hot := disk.Open(dir, "hot", monitor.NewNoop(), config.Badger{})
+	warm, _ := file.New(filepath.Join(dir, "warm"))
+	cold, _ := file.New(filepath.Join(dir, "cold"))
+	store := New(hot, time.Hour, time.Hour, time.Hour, "event", "tsi", monitor.NewNoop(),
+		Level{Tier: warm, TTL: 24 * time.Hour},
+		Level{Tier: cold},
+	)
+	defer store.Close()
+
+	// Recent blocks stay in the hot storage, old ones are aged into the warm tier
+	now := time.Now()
+	before, older := now.Add(-2*time.Hour), now.Add(-48*time.Hour)
+	assert.NoError(t, store.Append(key.New("click", now), newBlock("click", now.UnixNano(), now, 1), time.Hour))
+	assert.NoError(t, store.Append(key.New("click", before), newBlock("click", before.UnixNano(), before, 2, 3), time.Hour))
+	assert.NoError(t, store.Append(key.New("view", before), newBlock("view", before.UnixNano(), before, 4), time.Hour))
+	assert.NoError(t, store.Append(key.New("click", older), newBlock("click", older.UnixNano(), older, 5), time.Hour))
+	assert.Equal(t, 4, count(t, store, "click"))
+
+	_, err := store.Age(context.Background())
+	assert.NoError(t, err)
+
+	// A file is written for each hash and day, files older than the TTL of the warm tier are moved into the cold tier
+	warmFiles, _ := warm.List("")
+	coldFiles, _ := cold.List("")
+	assert.Len(t, warmFiles, 2)
+	assert.Len(t, coldFiles, 1)
+
+	var remaining int
+	assert.NoError(t, hot.Range(key.First(), key.Last(), func(k, v []byte) bool {
+		remaining++
+		return false
+	}))
+	assert.Equal(t, 1, remaining)
+
+	// Range continues to work across the tiers
+	assert.Equal(t, 4, count(t, store, "click"))
+	assert.Equal(t, 1, count(t, store, "view"))
+}
+
+// The blocks are aged based on the time they were inserted, whatever the unit of the sortBy column
+func TestTiered_Seconds(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "tiered")
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	hot := disk.Open(dir, "hot", monitor.NewNoop(), config.Badger{})
+	warm, _ := file.New(filepath.Join(dir, "warm"))
+	cold, _ := file.New(filepath.Join(dir, "cold"))
+	store := New(hot, time.Hour, time.Hour, time.Hour, "event", "tsi", monitor.NewNoop(),
+		Level{Tier: warm, TTL: 24 * time.Hour},
+		Level{Tier: cold, TTL: 7 * 24 * time.Hour},
+	)
+	defer store.Close()
+
+	// The keys of a table sorted by a column in seconds are in 1970
+	now := time.Now()
+	before := now.Add(-2 * time.Hour)
+	assert.NoError(t, store.Append(key.New("click", time.Unix(0, now.Unix())), newBlock("click", now.Unix(), now, 1), time.Hour))
+	assert.NoError(t, store.Append(key.New("click", time.Unix(0, before.Unix())), newBlock("click", before.Unix(), before, 2, 3), time.Hour))
+
+	_, err := store.Age(context.Background())
+	assert.NoError(t, err)
+
+	// Only the block inserted before the hot duration is aged, into a file named after its insertion
+	warmFiles, _ := warm.List("")
+	coldFiles, _ := cold.List("")
+	assert.Len(t, warmFiles, 1)
+	assert.Len(t, coldFiles, 0)
+	if len(warmFiles) == 1 {
+		written, ok := timeOf(warmFiles[0])
+		assert.True(t, ok)
+		assert.Equal(t, before.UTC().Truncate(time.Second), written)
+	}
+
+	// Nothing was lost
+	assert.Equal(t, 3, count(t, store, "click"))
+}
+
+func TestClose(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "tiered")
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	hot := disk.Open(dir, "hot", monitor.NewNoop(), config.Badger{})
+	store := New(hot, time.Hour, time.Hour, time.Millisecond, "event", "tsi", monitor.NewNoop())
+
+	// Closing waits for the aging worker to stop
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, store.Close())
+	select {
+	case <-store.done:
+	default:
+		assert.Fail(t, "aging worker is still running")
+	}
+}
+
+func TestTimeOf(t *testing.T) {
+	v, ok := timeOf("year=2020/month=1/day=2/03-04-05-0123456789abcdef.parquet")
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), v)
+
+	_, ok = timeOf("event=click/03-04-05-0123456789abcdef.parquet")
+	assert.False(t, ok)
+}
@@ -2,27 +2,24 @@ package base
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/grab/async"
 	"github.com/kelindar/lua"
 	"github.com/kelindar/talaria/internal/encoding/block"
+	"github.com/kelindar/talaria/internal/encoding/encoder"
 	"github.com/kelindar/talaria/internal/monitor/errors"
 	script "github.com/kelindar/talaria/internal/scripting"
 )
 
-// Func encodes the payload
-type Func func(interface{}) ([]byte, error)
-
 // Writer is to filter and encode row of events
 type Writer struct {
 	task    async.Task
 	Process func(context.Context) error
 	filter  *lua.Script
 	name    string
-	encode  Func
+	encode  encoder.RowFunc
 }
 
 // New creates a new encoder
@@ -31,18 +28,15 @@ func New(filter, encoderFunc string, loader *script.Loader) (*Writer, error) {
 		encoderFunc = "json"
 	}
 
-	// Extendable encoder functions
-	var encoder Func
-	switch encoderFunc {
-	case "json":
-		encoder = Func(json.Marshal)
-	default:
-		return nil, errors.Newf("encoder: unsupported encoder '%s'", encoderFunc)
+	// Extendable encoder functions, see the encoder registry
+	encode, err := encoder.ForRows(encoderFunc)
+	if err != nil {
+		return nil, err
 	}
 
 	// If no filter was specified, create a base writer without a filter
 	if filter == "" {
-		return newWithEncoder(encoderFunc, nil, encoder)
+		return newWithEncoder(encoderFunc, nil, encode)
 	}
 
 	// Load the filter script if required
@@ -51,19 +45,15 @@ func New(filter, encoderFunc string, loader *script.Loader) (*Writer, error) {
 		return nil, err
 	}
 
-	return newWithEncoder(encoderFunc, script, encoder)
+	return newWithEncoder(encoderFunc, script, encode)
 }
 
 // newWithEncoder will generate a new encoder for a writer
-func newWithEncoder(name string, filter *lua.Script, encoder Func) (*Writer, error) {
-	if encoder == nil {
-		encoder = Func(json.Marshal)
-	}
-
+func newWithEncoder(name string, filter *lua.Script, encode encoder.RowFunc) (*Writer, error) {
 	return &Writer{
 		name:   name,
 		filter: filter,
-		encode: encoder,
+		encode: encode,
 	}, nil
 }
 
@@ -88,18 +78,19 @@ func (w *Writer) Close() error {
 
 // Encode will encode a row to the format the user specifies
 func (w *Writer) Encode(input interface{}) ([]byte, error) {
-	// TODO: make this work for block.Block and block.Row
-
-	// If it's a row, take the value map
-	if r, ok := input.(block.Row); ok {
-		if w.applyFilter(&r) {
-			input = r.Values
-		} else {
-			return nil, nil
-		}
+	// TODO: make this work for block.Block
+
+	// Only rows can be encoded, filter them out if needed
+	row, ok := input.(block.Row)
+	if !ok {
+		return nil, errors.Newf("encoder: unsupported input %T", input)
+	}
+
+	if !w.applyFilter(&row) {
+		return nil, nil
 	}
 
-	encodedData, err := w.encode(input)
+	encodedData, err := w.encode(row)
 	if err != nil {
 		return nil, errors.Internal(fmt.Sprintf("encoder: could not marshal to %s", w.name), err)
 	}
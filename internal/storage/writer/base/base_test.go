@@ -71,3 +71,21 @@ func TestEmptyFilter(t *testing.T) {
 	assert.NotNil(t, enc1)
 	assert.NoError(t, err)
 }
+
+func TestEncoders(t *testing.T) {
+	row := block.Row{
+		Values: map[string]interface{}{"test": "Hello Talaria"},
+	}
+
+	for _, name := range []string{"json", "ndjson", "csv", "avro"} {
+		enc, err := New("", name, script.NewLoader(nil))
+		assert.NoError(t, err)
+
+		data, err := enc.Encode(row)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, data)
+	}
+
+	_, err := New("", "xxx", script.NewLoader(nil))
+	assert.Error(t, err)
+}
@@ -47,3 +47,47 @@ func (w *Writer) Write(key key.Key, val []byte) error {
 	}
 	return nil
 }
+
+// List returns the names of the files which were written under a prefix.
+func (w *Writer) List(prefix string) ([]string, error) {
+	var names []string
+	root := path.Join(w.directory, prefix)
+	if err := filepath.Walk(root, func(name string, info os.FileInfo, err error) error {
+		switch {
+		case os.IsNotExist(err):
+			return nil
+		case err != nil:
+			return err
+		case info.IsDir():
+			return nil
+		}
+
+		rel, err := filepath.Rel(w.directory, name)
+		if err != nil {
+			return err
+		}
+
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	}); err != nil {
+		return nil, errors.Internal("file: unable to list", err)
+	}
+	return names, nil
+}
+
+// Read reads the contents of a file which was written.
+func (w *Writer) Read(name string) ([]byte, error) {
+	b, err := ioutil.ReadFile(path.Join(w.directory, name))
+	if err != nil {
+		return nil, errors.Internal("file: unable to read", err)
+	}
+	return b, nil
+}
+
+// Delete deletes a file which was written.
+func (w *Writer) Delete(name string) error {
+	if err := os.Remove(path.Join(w.directory, name)); err != nil && !os.IsNotExist(err) {
+		return errors.Internal("file: unable to delete", err)
+	}
+	return nil
+}
@@ -19,3 +19,30 @@ func TestWriter(t *testing.T) {
 		c.Write([]byte("abc"), []byte("hello"))
 	})
 }
+
+func TestList(t *testing.T) {
+	c, err := New("testdata")
+	defer func() { _ = os.RemoveAll("testdata") }()
+	assert.NoError(t, err)
+
+	assert.NoError(t, c.Write([]byte("year=2020/month=1/day=2/a.orc"), []byte("hello")))
+	assert.NoError(t, c.Write([]byte("year=2020/month=1/day=3/b.orc"), []byte("world")))
+
+	names, err := c.List("")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"year=2020/month=1/day=2/a.orc", "year=2020/month=1/day=3/b.orc"}, names)
+
+	names, err = c.List("year=2020/month=1/day=3")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"year=2020/month=1/day=3/b.orc"}, names)
+
+	names, err = c.List("year=2021")
+	assert.NoError(t, err)
+	assert.Empty(t, names)
+
+	_, err = c.Read("missing.orc")
+	assert.Error(t, err)
+	b, err := c.Read("year=2020/month=1/day=2/a.orc")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+}
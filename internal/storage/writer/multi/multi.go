@@ -2,10 +2,14 @@ package multi
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/grab/async"
 	"github.com/kelindar/talaria/internal/encoding/block"
 	"github.com/kelindar/talaria/internal/encoding/key"
+	"github.com/kelindar/talaria/internal/monitor/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -41,12 +45,28 @@ func New(writers ...SubWriter) *Writer {
 	}
 }
 
+// Writers returns a copy of the sub-writers.
+func (w *Writer) Writers() []SubWriter {
+	return append([]SubWriter(nil), w.writers...)
+}
+
 // Write writes the data to the sink.
 func (w *Writer) Write(key key.Key, val []byte) error {
+	return w.WriteContext(context.Background(), key, val)
+}
+
+// WriteContext writes the data to the sink, tracing each write as a child of the span in the context.
+func (w *Writer) WriteContext(ctx context.Context, key key.Key, val []byte) error {
 	eg := new(errgroup.Group)
 	for _, w := range w.writers {
 		w := w
-		eg.Go(func() error {
+		eg.Go(func() (err error) {
+			_, span := tracing.Start(ctx, "sink.write",
+				attribute.String("sink", NameOf(w)),
+				attribute.String("key", string(key)),
+				attribute.Int("bytes", len(val)),
+			)
+			defer func() { tracing.End(span, err) }()
 			return w.Write(key, val)
 		})
 	}
@@ -76,3 +96,16 @@ func (w *Writer) Stream(row block.Row) error {
 
 	return nil
 }
+
+// NameOf returns the name of a sub-writer, such as "s3", which is the name of its package unless it has one.
+func NameOf(w SubWriter) string {
+	if named, ok := w.(interface{ Name() string }); ok {
+		return named.Name()
+	}
+
+	name := strings.TrimPrefix(fmt.Sprintf("%T", w), "*")
+	if i := strings.Index(name, "."); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
@@ -9,6 +9,10 @@ import (
 	"github.com/kelindar/talaria/internal/encoding/block"
 	"github.com/kelindar/talaria/internal/encoding/key"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type MockWriter func(key key.Key, val []byte) error
@@ -57,3 +61,23 @@ func TestMulti(t *testing.T) {
 	assert.Equal(t, 1, mock1.Count)
 	assert.Equal(t, 6, mock2.Count)
 }
+
+func TestWriteContext(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	sub := MockWriter(func(key key.Key, val []byte) error {
+		return nil
+	})
+
+	// Each sink write is traced
+	multiWriter := New(sub, &MockWriterFull{})
+	assert.NoError(t, multiWriter.WriteContext(context.Background(), key.Key("a"), []byte("b")))
+	assert.Len(t, recorder.Ended(), 2)
+	for _, span := range recorder.Ended() {
+		assert.Equal(t, "sink.write", span.Name())
+	}
+
+	assert.Equal(t, "multi", NameOf(sub))
+}
@@ -0,0 +1,115 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package retry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kelindar/talaria/internal/encoding/key"
+	"github.com/kelindar/talaria/internal/monitor"
+	"github.com/kelindar/talaria/internal/monitor/errors"
+	"github.com/kelindar/talaria/internal/storage/writer/multi"
+)
+
+const ctxTag = "retry"
+
+// Policy represents the retry policy of a sink
+type Policy struct {
+	Backoff     time.Duration // The initial backoff after a failure, doubled on every consecutive failure
+	MaxBackoff  time.Duration // The maximum backoff
+	MaxFailures int           // The number of consecutive failures after which the data is dead-lettered
+}
+
+// Writer represents a writer which backs off exponentially while its sink is failing, and diverts
+// the data to a dead-letter sink once the sink has failed too many times in a row.
+type Writer struct {
+	name       string          // The name of the sink, used for tagging
+	writer     multi.SubWriter // The underlying sink
+	deadLetter multi.SubWriter // The optional dead-letter sink
+	monitor    monitor.Monitor // The monitor client
+	policy     Policy          // The retry policy
+	lock       sync.Mutex      // The lock for the state
+	failures   int             // The number of consecutive failures
+	next       time.Time       // The time of the next attempt
+}
+
+// New creates a new writer.
+func New(name string, writer multi.SubWriter, policy Policy, deadLetter multi.SubWriter, monitor monitor.Monitor) *Writer {
+	if policy.Backoff <= 0 {
+		policy.Backoff = time.Second
+	}
+	if policy.MaxBackoff < policy.Backoff {
+		policy.MaxBackoff = 5 * time.Minute
+	}
+
+	return &Writer{
+		name:       name,
+		writer:     writer,
+		deadLetter: deadLetter,
+		monitor:    monitor,
+		policy:     policy,
+	}
+}
+
+// Name returns the name of the sink.
+func (w *Writer) Name() string {
+	return w.name
+}
+
+// Write writes the data to the sink, unless the sink is backing off.
+func (w *Writer) Write(key key.Key, val []byte) error {
+	if !w.ready() {
+		return w.divert(key, val, errors.New("retry: sink "+w.name+" is backing off"))
+	}
+
+	err := w.writer.Write(key, val)
+	w.onAttempt(err)
+	if err != nil {
+		w.monitor.Count1(ctxTag, "error", "sink:"+w.name)
+		return w.divert(key, val, err)
+	}
+	return nil
+}
+
+// ready returns whether the backoff of the sink has expired
+func (w *Writer) ready() bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return !time.Now().Before(w.next)
+}
+
+// onAttempt updates the backoff of the sink after an attempt
+func (w *Writer) onAttempt(err error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if err == nil {
+		w.failures = 0
+		w.next = time.Time{}
+		return
+	}
+
+	backoff := w.policy.MaxBackoff
+	if w.failures < 32 && w.policy.Backoff<<uint(w.failures) < w.policy.MaxBackoff {
+		backoff = w.policy.Backoff << uint(w.failures)
+	}
+
+	w.failures++
+	w.next = time.Now().Add(backoff)
+}
+
+// divert writes the data to the dead-letter sink if the sink has failed too many times in a row,
+// otherwise the error is returned so the data is retried later.
+func (w *Writer) divert(key key.Key, val []byte, err error) error {
+	w.lock.Lock()
+	exhausted := w.policy.MaxFailures > 0 && w.failures >= w.policy.MaxFailures
+	w.lock.Unlock()
+	if !exhausted || w.deadLetter == nil {
+		return err
+	}
+
+	w.monitor.Count1(ctxTag, "deadletter", "sink:"+w.name)
+	w.monitor.Warning(errors.Internal("retry: writing "+string(key)+" to the dead-letter sink", err))
+	return w.deadLetter.Write(key, val)
+}
@@ -0,0 +1,78 @@
+// Copyright 2019-2020 Grabtaxi Holdings PTE LTE (GRAB), All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be found in the LICENSE file
+
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kelindar/talaria/internal/encoding/key"
+	"github.com/kelindar/talaria/internal/monitor"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockWriter func(key key.Key, val []byte) error
+
+func (w mockWriter) Write(key key.Key, val []byte) error {
+	return w(key, val)
+}
+
+func TestRetry(t *testing.T) {
+	var attempts, dead int
+	failing := true
+	sink := mockWriter(func(key key.Key, val []byte) error {
+		attempts++
+		if failing {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+
+	deadLetter := mockWriter(func(key key.Key, val []byte) error {
+		dead++
+		return nil
+	})
+
+	w := New("test", sink, Policy{
+		Backoff:     20 * time.Millisecond,
+		MaxBackoff:  40 * time.Millisecond,
+		MaxFailures: 2,
+	}, deadLetter, monitor.NewNoop())
+
+	// The first failure is returned, so the data remains buffered
+	assert.Error(t, w.Write(key.Key("a"), []byte("a")))
+	assert.Equal(t, 1, attempts)
+
+	// While backing off, the sink is not attempted
+	assert.Error(t, w.Write(key.Key("a"), []byte("a")))
+	assert.Equal(t, 1, attempts)
+
+	// Once the sink fails too many times, the data is dead-lettered
+	time.Sleep(25 * time.Millisecond)
+	assert.NoError(t, w.Write(key.Key("a"), []byte("a")))
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, 1, dead)
+
+	// The backoff is capped, and the sink is used again once it recovers
+	failing = false
+	assert.NoError(t, w.Write(key.Key("b"), []byte("b")))
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, 2, dead)
+
+	time.Sleep(45 * time.Millisecond)
+	assert.NoError(t, w.Write(key.Key("c"), []byte("c")))
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, 2, dead)
+}
+
+func TestRetry_NoDeadLetter(t *testing.T) {
+	sink := mockWriter(func(key key.Key, val []byte) error {
+		return errors.New("unavailable")
+	})
+
+	w := New("test", sink, Policy{MaxFailures: 1}, nil, monitor.NewNoop())
+	assert.Error(t, w.Write(key.Key("a"), []byte("a")))
+	assert.Error(t, w.Write(key.Key("a"), []byte("a")))
+}
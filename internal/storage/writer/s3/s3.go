@@ -5,6 +5,7 @@ package s3
 
 import (
 	"bytes"
+	"io/ioutil"
 	"path"
 	"runtime"
 	"strings"
@@ -28,10 +29,23 @@ type Uploader interface {
 	Upload(input *s3manager.UploadInput, options ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error)
 }
 
+// Reader lists and downloads the objects of the underlying backend
+type Reader interface {
+	ListObjectsV2Pages(input *s3.ListObjectsV2Input, fn func(*s3.ListObjectsV2Output, bool) bool) error
+	GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error)
+}
+
+// Deleter deletes the objects of the underlying backend
+type Deleter interface {
+	DeleteObject(input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error)
+}
+
 // Writer represents a writer for Amazon S3 and compatible storages.
 type Writer struct {
 	monitor  monitor.Monitor
 	uploader Uploader
+	reader   Reader
+	deleter  Deleter
 	bucket   string
 	prefix   string
 	sse      string
@@ -61,9 +75,11 @@ func New(monitor monitor.Monitor, bucket, prefix, region, endpoint, sse, access,
 		uploader: s3manager.NewUploaderWithClient(client, func(u *s3manager.Uploader) {
 			u.Concurrency = concurrency
 		}),
-		bucket: bucket,
-		prefix: cleanPrefix(prefix),
-		sse:    sse,
+		reader:  client,
+		deleter: client,
+		bucket:  bucket,
+		prefix:  cleanPrefix(prefix),
+		sse:     sse,
 	}, nil
 }
 
@@ -90,6 +106,64 @@ func (w *Writer) Write(key key.Key, val []byte) error {
 	return nil
 }
 
+// List returns the names of the objects which were written under a prefix, relative to the prefix of the writer.
+func (w *Writer) List(prefix string) ([]string, error) {
+	root := path.Join(w.prefix, prefix)
+	if root != "" {
+		root += "/"
+	}
+
+	var names []string
+	if err := w.reader.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(w.bucket),
+		Prefix: aws.String(root),
+	}, func(page *s3.ListObjectsV2Output, last bool) bool {
+		for _, object := range page.Contents {
+			name := strings.TrimPrefix(aws.StringValue(object.Key), w.prefix)
+			names = append(names, strings.TrimPrefix(name, "/"))
+		}
+		return true
+	}); err != nil {
+		w.monitor.Count1(ctxTag, "listerror")
+		return nil, errors.Internal("s3: unable to list", err)
+	}
+	return names, nil
+}
+
+// Read downloads an object which was written.
+func (w *Writer) Read(name string) ([]byte, error) {
+	start := time.Now()
+	output, err := w.reader.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(w.bucket),
+		Key:    aws.String(path.Join(w.prefix, name)),
+	})
+	if err != nil {
+		w.monitor.Count1(ctxTag, "readerror")
+		return nil, errors.Internal("s3: unable to read", err)
+	}
+
+	defer output.Body.Close()
+	b, err := ioutil.ReadAll(output.Body)
+	if err != nil {
+		return nil, errors.Internal("s3: unable to read", err)
+	}
+
+	w.monitor.Histogram(ctxTag, "readlatency", float64(time.Since(start)))
+	return b, nil
+}
+
+// Delete deletes an object which was written.
+func (w *Writer) Delete(name string) error {
+	if _, err := w.deleter.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(w.bucket),
+		Key:    aws.String(path.Join(w.prefix, name)),
+	}); err != nil {
+		w.monitor.Count1(ctxTag, "deleteerror")
+		return errors.Internal("s3: unable to delete", err)
+	}
+	return nil
+}
+
 func cleanPrefix(prefix string) string {
 	return strings.Trim(prefix, "/")
 }
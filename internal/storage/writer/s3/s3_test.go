@@ -4,8 +4,14 @@
 package s3
 
 import (
+	"fmt"
+	"io/ioutil"
+	"strings"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+
 	"github.com/kelindar/talaria/internal/encoding/key"
 	"github.com/kelindar/talaria/internal/monitor"
 	"github.com/kelindar/talaria/internal/monitor/logging"
@@ -36,3 +42,53 @@ func TestS3Writer_Write(t *testing.T) {
 
 	assert.Equal(t, err, nil)
 }
+
+type mockReader map[string]string
+
+func (r mockReader) ListObjectsV2Pages(input *s3.ListObjectsV2Input, fn func(*s3.ListObjectsV2Output, bool) bool) error {
+	page := new(s3.ListObjectsV2Output)
+	for k := range r {
+		if strings.HasPrefix(k, aws.StringValue(input.Prefix)) {
+			page.Contents = append(page.Contents, &s3.Object{Key: aws.String(k)})
+		}
+	}
+
+	fn(page, true)
+	return nil
+}
+
+func (r mockReader) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	v, ok := r[aws.StringValue(input.Key)]
+	if !ok {
+		return nil, fmt.Errorf("not found")
+	}
+
+	return &s3.GetObjectOutput{Body: ioutil.NopCloser(strings.NewReader(v))}, nil
+}
+
+func TestS3Writer_Read(t *testing.T) {
+	s3Writer := &Writer{
+		monitor: monitor.NewNoop(),
+		reader: mockReader{
+			"prefix/year=2020/month=1/day=2/a.orc": "hello",
+			"other/b.orc":                          "world",
+		},
+		bucket: "testBucket",
+		prefix: "prefix",
+	}
+
+	names, err := s3Writer.List("")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"year=2020/month=1/day=2/a.orc"}, names)
+
+	names, err = s3Writer.List("year=2020/month=1/day=3")
+	assert.NoError(t, err)
+	assert.Empty(t, names)
+
+	b, err := s3Writer.Read("year=2020/month=1/day=2/a.orc")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+
+	_, err = s3Writer.Read("missing.orc")
+	assert.Error(t, err)
+}
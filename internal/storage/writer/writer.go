@@ -3,19 +3,23 @@ package writer
 import (
 	"context"
 	"fmt"
-	"hash/maphash"
-	"sort"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/kelindar/talaria/internal/column"
 	"github.com/kelindar/talaria/internal/config"
+	"github.com/kelindar/talaria/internal/encoding/merge"
 	"github.com/kelindar/talaria/internal/encoding/typeof"
 	"github.com/kelindar/talaria/internal/monitor"
 	"github.com/kelindar/talaria/internal/monitor/errors"
 	script "github.com/kelindar/talaria/internal/scripting"
 	"github.com/kelindar/talaria/internal/storage"
+	"github.com/kelindar/talaria/internal/storage/cold"
 	"github.com/kelindar/talaria/internal/storage/compact"
+	"github.com/kelindar/talaria/internal/storage/fanout"
 	"github.com/kelindar/talaria/internal/storage/flush"
+	"github.com/kelindar/talaria/internal/storage/tiered"
 	"github.com/kelindar/talaria/internal/storage/writer/azure"
 	"github.com/kelindar/talaria/internal/storage/writer/bigquery"
 	"github.com/kelindar/talaria/internal/storage/writer/file"
@@ -23,12 +27,11 @@ import (
 	"github.com/kelindar/talaria/internal/storage/writer/multi"
 	"github.com/kelindar/talaria/internal/storage/writer/noop"
 	"github.com/kelindar/talaria/internal/storage/writer/pubsub"
+	"github.com/kelindar/talaria/internal/storage/writer/retry"
 	"github.com/kelindar/talaria/internal/storage/writer/s3"
 	"github.com/kelindar/talaria/internal/storage/writer/talaria"
 )
 
-var seed = maphash.MakeSeed()
-
 // ForStreaming creates a streaming writer
 func ForStreaming(config config.Streams, monitor monitor.Monitor, loader *script.Loader) (storage.Streamer, error) {
 	writer, err := newStreamer(config, monitor, loader)
@@ -39,13 +42,148 @@ func ForStreaming(config config.Streams, monitor monitor.Monitor, loader *script
 	return writer.(storage.Streamer), nil
 }
 
-// ForCompaction creates a compaction writer
-func ForCompaction(config *config.Compaction, monitor monitor.Monitor, store storage.Storage, loader *script.Loader) (*compact.Storage, error) {
+// ForCompaction creates a compaction writer. When multiple sinks or pipelines are configured, each
+// of them consumes the shared buffer at its own pace, with its own encoder, interval and retry state,
+// so that a failing sink never holds back the others. The ttl is the ttl of the buffered blocks and
+// the sortBy column of the table is partitioned by its date.
+func ForCompaction(config *config.Compaction, ttl time.Duration, sortBy string, monitor monitor.Monitor, store storage.Storage, loader *script.Loader) (storage.Storage, error) {
+	pipelines := config.Split()
+	switch len(pipelines) {
+	case 0:
+		return newCompactor(config, sortBy, monitor, store, loader)
+	case 1:
+		return newCompactor(&pipelines[0], sortBy, monitor, store, loader)
+	}
+
+	names := make([]string, 0, len(pipelines))
+	byName := make(map[string]int, len(pipelines))
+	for i := range pipelines {
+		pipeline := &pipelines[i]
+		if _, ok := byName[pipeline.Name]; ok {
+			return nil, errors.New("compact: duplicate pipeline " + pipeline.Name)
+		}
+		byName[pipeline.Name] = i
+		names = append(names, pipeline.Name)
+
+		// Each pipeline needs its own commit log
+		if pipeline.CommitLog == "" || pipeline.CommitLog == config.CommitLog {
+			pipeline.CommitLog = commitLogOf(config.CommitLog, pipeline.Name)
+		}
+	}
+
+	return fanout.New(store, ttl, names, func(name string, buffer storage.Storage) (storage.Storage, error) {
+		return newCompactor(&pipelines[byName[name]], sortBy, monitor, buffer, loader)
+	})
+}
+
+// ForReadThrough creates a cold tier which reads the compacted files back from the first file or S3 sink
+func ForReadThrough(config *config.Compaction, hashBy, sortBy string, monitor monitor.Monitor) (*cold.Storage, error) {
+	for _, pipeline := range config.Split() {
+		if pipeline.File == nil && pipeline.S3 == nil {
+			continue
+		}
+
+		// The files need to be decoded back into blocks
+		format := strings.ToLower(pipeline.Encoder)
+		switch format {
+		case "":
+			format = "orc"
+		case "orc", "parquet", "arrow", "feather":
+		default:
+			return nil, errors.Newf("compact: read-through requires an orc, parquet or arrow encoder, got '%s'", pipeline.Encoder)
+		}
+
+		// The default names are under the date partition of the time they were written
+		dated := pipeline.NameFunc == "" && pipeline.Partition == nil
+
+		switch {
+		case pipeline.File != nil:
+			w, err := file.New(pipeline.File.Directory)
+			if err != nil {
+				return nil, err
+			}
+			return cold.New(w, format, dated, hashBy, sortBy, monitor), nil
+
+		default:
+			c := pipeline.S3
+			w, err := s3.New(monitor, c.Bucket, c.Prefix, c.Region, c.Endpoint, c.SSE, c.AccessKey, c.SecretKey, c.Concurrency)
+			if err != nil {
+				return nil, err
+			}
+			return cold.New(w, format, dated, hashBy, sortBy, monitor), nil
+		}
+	}
+
+	return nil, errors.New("compact: read-through requires a file or s3 sink")
+}
+
+// ForTiers creates a tiered storage which ages the blocks of the buffer into the configured tiers, the TTL
+// of the table being used to find out when the blocks were inserted
+func ForTiers(tiers *config.Tiers, ttl time.Duration, hashBy, sortBy string, monitor monitor.Monitor, store storage.Storage) (storage.Storage, error) {
+	interval := 60 * time.Second
+	if tiers.Interval > 0 {
+		interval = time.Duration(tiers.Interval) * time.Second
+	}
+
+	var levels []tiered.Level
+	for _, tier := range []*config.Tier{tiers.Warm, tiers.Cold} {
+		if tier == nil {
+			continue
+		}
+
+		var t tiered.Tier
+		switch {
+		case tier.File != nil:
+			w, err := file.New(tier.File.Directory)
+			if err != nil {
+				return nil, err
+			}
+			t = w
+		case tier.S3 != nil:
+			c := tier.S3
+			w, err := s3.New(monitor, c.Bucket, c.Prefix, c.Region, c.Endpoint, c.SSE, c.AccessKey, c.SecretKey, c.Concurrency)
+			if err != nil {
+				return nil, err
+			}
+			t = w
+		default:
+			return nil, errors.New("tiered: tier requires either a file or an s3 configuration")
+		}
+
+		levels = append(levels, tiered.Level{
+			Tier: t,
+			TTL:  time.Duration(tier.TTL) * time.Second,
+		})
+	}
+
+	monitor.Info("server: setting up tiered storage with %d tiers, aging every %.0fs...", len(levels), interval.Seconds())
+	return tiered.New(store, ttl, time.Duration(tiers.Hot)*time.Second, interval, hashBy, sortBy, monitor, levels...), nil
+}
+
+// commitLogOf returns the path of the commit log of a pipeline, e.g. "compaction-s3.log"
+func commitLogOf(commitLog, pipeline string) string {
+	if commitLog == "" {
+		return ""
+	}
+
+	ext := filepath.Ext(commitLog)
+	return strings.TrimSuffix(commitLog, ext) + "-" + pipeline + ext
+}
+
+// newCompactor creates a compaction pipeline which compacts the buffer into its sinks
+func newCompactor(config *config.Compaction, sortBy string, monitor monitor.Monitor, store storage.Storage, loader *script.Loader) (*compact.Storage, error) {
 	writer, err := newWriter(config.Sinks, monitor, loader)
 	if err != nil {
 		return nil, err
 	}
 
+	// Back off the failing sinks and divert the data to the dead-letter sinks, if configured
+	if config.Retry != nil {
+		if writer, err = withRetry(writer.(*multi.Writer), config.Retry, monitor, loader); err != nil {
+			return nil, err
+		}
+	}
+
 	// Configure the flush interval, default to 30s
 	interval := 30 * time.Second
 	if config.Interval > 0 {
@@ -53,10 +191,17 @@ func ForCompaction(config *config.Compaction, monitor monitor.Monitor, store sto
 	}
 
 	// If name function was specified, use it
-	nameFunc := defaultNameFunc
+	extension := merge.ExtensionOf(config.Encoder)
+	nameFunc := defaultNameFunc(extension)
+	var partitionBy []string
+	if config.Partition != nil {
+		partitionBy = config.Partition.By
+		nameFunc = partitionedNameFunc(extension)
+	}
+
 	if config.NameFunc != "" {
 		if fn, err := column.NewComputed("nameFunc", typeof.String, config.NameFunc, loader); err == nil {
-			nameFunc = func(row map[string]interface{}) (s string, e error) {
+			nameFunc = func(_ compact.Job, row map[string]interface{}) (s string, e error) {
 				val, err := fn.Value(row)
 				if err != nil {
 					monitor.Error(err)
@@ -71,13 +216,66 @@ func ForCompaction(config *config.Compaction, monitor monitor.Monitor, store sto
 	// Crate the flusher
 	monitor.Info("server: setting up compaction %T to run every %.0fs...", writer, interval.Seconds())
 
-	// TODO: once we have everything working, consider making the flusher per writer (requires changing all writers)
-	flusher, err := flush.ForCompaction(monitor, writer, config.Encoder, nameFunc)
+	mergeFn, err := newMerger(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Open the commit log, so the interrupted jobs can be retried
+	var journal *compact.Journal
+	if config.CommitLog != "" {
+		if journal, err = compact.OpenJournal(config.CommitLog); err != nil {
+			return nil, err
+		}
+	}
+
+	flusher := flush.ForCompaction(monitor, writer, mergeFn, nameFunc, sortBy, partitionBy...)
+	return compact.New(store, flusher, monitor, interval, compact.Trigger{
+		TargetSize: config.TargetSize,
+		MaxAge:     time.Duration(config.MaxAge) * time.Second,
+	}, journal), nil
+}
+
+// newMerger creates the merge function for the configured encoder, optionally sorting the rows
+func newMerger(config *config.Compaction) (merge.Func, error) {
+	mergeFn, err := merge.New(config.Encoder)
 	if err != nil {
 		return nil, err
 	}
 
-	return compact.New(store, flusher, monitor, interval), nil
+	// Parquet files are written with statistics, unless configured otherwise
+	if strings.EqualFold(config.Encoder, "parquet") {
+		mergeFn = merge.NewParquet(config.Parquet)
+	}
+
+	if config.Sort != nil {
+		mergeFn = merge.Sorted(mergeFn, config.Sort.Columns()...)
+	}
+	return mergeFn, nil
+}
+
+// withRetry wraps each sink of the writer so that it is retried with an exponential backoff
+func withRetry(writer *multi.Writer, config *config.Retry, monitor monitor.Monitor, loader *script.Loader) (flush.Writer, error) {
+	var deadLetter multi.SubWriter
+	if config.DeadLetter != nil {
+		w, err := newWriter(*config.DeadLetter, monitor, loader)
+		if err != nil {
+			return nil, err
+		}
+		deadLetter = w
+	}
+
+	policy := retry.Policy{
+		Backoff:     time.Duration(config.Backoff) * time.Second,
+		MaxBackoff:  time.Duration(config.MaxBackoff) * time.Second,
+		MaxFailures: config.MaxFailures,
+	}
+
+	sinks := writer.Writers()
+	for i, w := range sinks {
+		sinks[i] = retry.New(multi.NameOf(w), w, policy, deadLetter, monitor)
+	}
+	return multi.New(sinks...), nil
 }
 
 // NewWriter creates a new writer from the configuration.
@@ -188,29 +386,25 @@ func newStreamer(config config.Streams, monitor monitor.Monitor, loader *script.
 	return multiWriters, err
 }
 
-// defaultNameFunc represents a default name function
-func defaultNameFunc(row map[string]interface{}) (s string, e error) {
-	return fmt.Sprintf("%s-%x.orc",
-		time.Now().UTC().Format("year=2006/month=1/day=2/15-04-05"),
-		hashOfRow(row),
-	), nil
-}
-
-// hashOfRow computes a hash of the row, for the default filename
-func hashOfRow(row map[string]interface{}) uint64 {
-
-	// Sort the map keys
-	str := make([]string, 0, len(row))
-	for k, v := range row {
-		str = append(str, fmt.Sprintf("%s=%v", k, v))
+// defaultNameFunc represents a default name function, under the date partition of the time the job was
+// created. The identifier of the job makes the name deterministic, so a retried job overwrites its files.
+func defaultNameFunc(extension string) flush.NameFunc {
+	return func(job compact.Job, _ map[string]interface{}) (s string, e error) {
+		return fmt.Sprintf("%s-%s.%s",
+			job.Time.UTC().Format("year=2006/month=1/day=2/15-04-05"),
+			job.ID,
+			extension,
+		), nil
 	}
-	sort.Strings(str)
+}
 
-	// Compute the hash
-	var hash maphash.Hash
-	hash.SetSeed(seed)
-	for _, v := range str {
-		hash.WriteString(v)
+// partitionedNameFunc represents a default name function within a partition
+func partitionedNameFunc(extension string) flush.NameFunc {
+	return func(job compact.Job, _ map[string]interface{}) (s string, e error) {
+		return fmt.Sprintf("%s-%s.%s",
+			job.Time.UTC().Format("15-04-05"),
+			job.ID,
+			extension,
+		), nil
 	}
-	return hash.Sum64()
 }
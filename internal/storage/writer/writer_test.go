@@ -1,14 +1,29 @@
 package writer
 
 import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	goparquet "github.com/fraugster/parquet-go"
+	"github.com/kelindar/talaria/internal/column"
 	"github.com/kelindar/talaria/internal/config"
+	"github.com/kelindar/talaria/internal/encoding/block"
+	"github.com/kelindar/talaria/internal/encoding/merge"
+	"github.com/kelindar/talaria/internal/encoding/typeof"
 	"github.com/kelindar/talaria/internal/monitor"
 	"github.com/kelindar/talaria/internal/monitor/logging"
 	"github.com/kelindar/talaria/internal/monitor/statsd"
 	script "github.com/kelindar/talaria/internal/scripting"
+	"github.com/kelindar/talaria/internal/storage/compact"
 	"github.com/kelindar/talaria/internal/storage/disk"
+	"github.com/kelindar/talaria/internal/storage/fanout"
+	"github.com/kelindar/talaria/internal/storage/tiered"
+	"github.com/kelindar/talaria/internal/storage/writer/multi"
+	"github.com/kelindar/talaria/internal/storage/writer/retry"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -21,7 +36,7 @@ func TestForCompaction(t *testing.T) {
 		},
 	}
 
-	compact, err := ForCompaction(cfg,
+	compact, err := ForCompaction(cfg, time.Hour, "",
 		monitor.New(logging.NewStandard(), statsd.NewNoop(), "x", "x"),
 		disk.New(monitor.NewNoop()),
 		script.NewLoader(nil),
@@ -41,15 +56,145 @@ func TestForStreaming(t *testing.T) {
 	assert.NotNil(t, compact)
 }
 
-func TestHash(t *testing.T) {
+func TestNameFunc(t *testing.T) {
+	job := compact.Job{
+		ID:   "8c0f7fb5d9e3a1c2",
+		Time: time.Unix(1600000000, 0),
+	}
+
+	n1, err := defaultNameFunc("orc")(job, nil)
+	assert.NoError(t, err)
+	n2, err := defaultNameFunc("orc")(job, map[string]interface{}{"a": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, n1, n2)
+	assert.Equal(t, "year=2020/month=9/day=13/12-26-40-8c0f7fb5d9e3a1c2.orc", n1)
+
+	n3, err := partitionedNameFunc("orc")(job, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "12-26-40-8c0f7fb5d9e3a1c2.orc", n3)
+
+	// The extension follows the encoder
+	for encoder, extension := range map[string]string{
+		"":        "orc",
+		"Parquet": "parquet",
+		"arrow":   "arrow",
+		"feather": "feather",
+	} {
+		n, err := defaultNameFunc(merge.ExtensionOf(encoder))(job, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "year=2020/month=9/day=13/12-26-40-8c0f7fb5d9e3a1c2."+extension, n)
+	}
+}
+
+func TestNewMerger(t *testing.T) {
+	schema := typeof.Schema{"flag": typeof.Bool}
+	cols := column.MakeColumns(&schema)
+	cols.Append("flag", true, typeof.Bool)
+	blk, err := block.FromColumns("event", cols)
+	assert.NoError(t, err)
+
+	// The parquet encoder is matched regardless of its case, and writes statistics by default
+	mergeFn, err := newMerger(&config.Compaction{Encoder: "Parquet"})
+	assert.NoError(t, err)
+	out, err := mergeFn([]block.Block{blk}, schema)
+	assert.NoError(t, err)
+
+	reader, err := goparquet.NewFileReader(bytes.NewReader(out))
+	assert.NoError(t, err)
+	assert.NoError(t, reader.PreLoad())
+	assert.Equal(t, []byte{1}, reader.CurrentRowGroup().Columns[0].MetaData.Statistics.MaxValue)
+}
+
+func TestWithRetry(t *testing.T) {
+	sinks, err := newWriter(config.Sinks{
+		File: &config.FileSink{Directory: "./"},
+	}, monitor.NewNoop(), script.NewLoader(nil))
+	assert.NoError(t, err)
+
+	writer, err := withRetry(sinks.(*multi.Writer), &config.Retry{
+		MaxFailures: 3,
+		DeadLetter: &config.Sinks{
+			File: &config.FileSink{Directory: "./deadletter"},
+		},
+	}, monitor.NewNoop(), script.NewLoader(nil))
+	assert.NoError(t, err)
+
+	wrapped := writer.(*multi.Writer).Writers()
+	assert.Len(t, wrapped, 1)
+	assert.IsType(t, new(retry.Writer), wrapped[0])
+	assert.Equal(t, "file", multi.NameOf(sinks.(*multi.Writer).Writers()[0]))
+}
 
-	row := map[string]interface{}{
-		"a": 1654,
-		"b": "hello world",
-		"c": true,
+func TestForCompaction_Pipelines(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pipelines")
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	cfg := &config.Compaction{
+		CommitLog: filepath.Join(dir, "compaction.log"),
+		Sinks: config.Sinks{
+			File: &config.FileSink{Directory: dir},
+		},
+		Pipelines: []config.Compaction{{
+			Name:    "archive",
+			Encoder: "parquet",
+			Sinks: config.Sinks{
+				File: &config.FileSink{Directory: dir},
+			},
+		}},
+	}
+
+	buffer := disk.Open(dir, "buffer", monitor.NewNoop(), config.Badger{})
+	store, err := ForCompaction(cfg, time.Hour, "", monitor.NewNoop(), buffer, script.NewLoader(nil))
+	assert.NoError(t, err)
+	defer store.Close()
+	assert.IsType(t, new(fanout.Storage), store)
+	assert.FileExists(t, filepath.Join(dir, "compaction-file.log"))
+	assert.FileExists(t, filepath.Join(dir, "compaction-archive.log"))
+
+	// Names of the pipelines must be unique
+	cfg.Pipelines[0].Name = "file"
+	_, err = ForCompaction(cfg, time.Hour, "", monitor.NewNoop(), buffer, script.NewLoader(nil))
+	assert.Error(t, err)
+}
+
+func TestForReadThrough(t *testing.T) {
+	cfg := &config.Compaction{
+		Sinks: config.Sinks{
+			File: &config.FileSink{Directory: "./"},
+		},
 	}
 
-	h1 := hashOfRow(row)
-	h2 := hashOfRow(row)
-	assert.Equal(t, h1, h2)
+	tier, err := ForReadThrough(cfg, "event", "tsi", monitor.NewNoop())
+	assert.NoError(t, err)
+	assert.NotNil(t, tier)
+
+	_, err = ForReadThrough(&config.Compaction{}, "event", "tsi", monitor.NewNoop())
+	assert.Error(t, err)
+
+	// The files need to be decoded back
+	cfg.Encoder = "parquet"
+	_, err = ForReadThrough(cfg, "event", "tsi", monitor.NewNoop())
+	assert.NoError(t, err)
+
+	cfg.Encoder = "csv"
+	_, err = ForReadThrough(cfg, "event", "tsi", monitor.NewNoop())
+	assert.Error(t, err)
+}
+
+func TestForTiers(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "tiers")
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	buffer := disk.Open(dir, "buffer", monitor.NewNoop(), config.Badger{})
+	store, err := ForTiers(&config.Tiers{
+		Hot:  3600,
+		Warm: &config.Tier{TTL: 86400, File: &config.FileSink{Directory: filepath.Join(dir, "warm")}},
+		Cold: &config.Tier{S3: &config.S3Sink{Bucket: "bucket", Region: "us-east-1"}},
+	}, time.Hour, "event", "tsi", monitor.NewNoop(), buffer)
+	assert.NoError(t, err)
+	assert.IsType(t, new(tiered.Storage), store)
+	assert.NoError(t, store.Close())
+
+	_, err = ForTiers(&config.Tiers{Warm: &config.Tier{}}, time.Hour, "event", "tsi", monitor.NewNoop(), buffer)
+	assert.Error(t, err)
 }
@@ -4,6 +4,7 @@
 package log
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -84,7 +85,7 @@ func (t *Table) Append(msg string, level logging.Level) error {
 		return err
 	}
 
-	return t.Table.Append(block)
+	return t.Table.Append(context.Background(), block)
 }
 
 func (t *Table) toColumns(msg string, level logging.Level) column.Columns {
@@ -76,7 +76,7 @@ func TestLog(t *testing.T) {
 		assert.Equal(t, "127.0.0.1", splits[0].Addrs[0])
 
 		// Get the rows
-		page, err := logs.GetRows(splits[0].Key, []string{"message", "time", "level"}, 1*1024*1024)
+		page, err := logs.GetRows(context.Background(), splits[0].Key, []string{"message", "time", "level"}, 1*1024*1024)
 		assert.NotNil(t, page)
 		assert.NoError(t, err)
 		assert.Len(t, page.Columns, 3)
@@ -4,6 +4,7 @@
 package nodes
 
 import (
+	"context"
 	"encoding/json"
 	"net"
 	"time"
@@ -90,7 +91,7 @@ func (t *Table) GetSplits(desiredColumns []string, outputConstraint *presto.Pres
 }
 
 // GetRows retrieves the data
-func (t *Table) GetRows(splitID []byte, columns []string, maxBytes int64) (*table.PageResult, error) {
+func (t *Table) GetRows(_ context.Context, splitID []byte, columns []string, maxBytes int64) (*table.PageResult, error) {
 	result := &table.PageResult{
 		Columns: make([]presto.Column, 0, len(columns)),
 	}
@@ -4,6 +4,7 @@
 package nodes_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/kelindar/talaria/internal/table/nodes"
@@ -38,7 +39,7 @@ func TestNodes(t *testing.T) {
 	assert.Equal(t, "127.0.0.1", splits[0].Addrs[0])
 
 	// Get the rows
-	page, err := table.GetRows(splits[0].Key, []string{"private", "uptime", "started", "public", "peers", "address"}, 1*1024*1024)
+	page, err := table.GetRows(context.Background(), splits[0].Key, []string{"private", "uptime", "started", "public", "peers", "address"}, 1*1024*1024)
 	assert.NotNil(t, page)
 	assert.NoError(t, err)
 	assert.Len(t, page.Columns, 6)
@@ -56,7 +57,7 @@ func TestNodes_NoColumn(t *testing.T) {
 	defer table.Close()
 
 	// Get the rows
-	page, err := table.GetRows(nil, []string{"xxx"}, 1*1024*1024)
+	page, err := table.GetRows(context.Background(), nil, []string{"xxx"}, 1*1024*1024)
 	assert.NotNil(t, page)
 	assert.NoError(t, err)
 }
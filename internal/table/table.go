@@ -4,6 +4,7 @@
 package table
 
 import (
+	"context"
 	"errors"
 	"io"
 
@@ -23,14 +24,14 @@ type Table interface {
 	Name() string
 	Schema() (typeof.Schema, bool)
 	GetSplits(desiredColumns []string, outputConstraint *presto.PrestoThriftTupleDomain, maxSplitCount int) ([]Split, error)
-	GetRows(splitID []byte, columns []string, maxBytes int64) (*PageResult, error)
+	GetRows(ctx context.Context, splitID []byte, columns []string, maxBytes int64) (*PageResult, error)
 	HashBy() string
 	SortBy() string
 }
 
 // Appender represents an appender of data to the table.
 type Appender interface {
-	Append(block.Block) error
+	Append(context.Context, block.Block) error
 	HashBy() string
 }
 
@@ -20,9 +20,11 @@ import (
 	"github.com/kelindar/talaria/internal/encoding/typeof"
 	"github.com/kelindar/talaria/internal/monitor"
 	"github.com/kelindar/talaria/internal/monitor/errors"
+	"github.com/kelindar/talaria/internal/monitor/tracing"
 	"github.com/kelindar/talaria/internal/presto"
 	"github.com/kelindar/talaria/internal/storage"
 	"github.com/kelindar/talaria/internal/table"
+	"go.opentelemetry.io/otel/attribute"
 	"gopkg.in/yaml.v2"
 )
 
@@ -176,8 +178,8 @@ func (t *Table) GetSplits(desiredColumns []string, outputConstraint *presto.Pres
 	return splits, nil
 }
 
-// GetRows retrieves the data
-func (t *Table) GetRows(splitID []byte, requestedColumns []string, maxBytes int64) (result *table.PageResult, err error) {
+// GetRows retrieves the data, the scan stops as soon as the context is cancelled
+func (t *Table) GetRows(ctx context.Context, splitID []byte, requestedColumns []string, maxBytes int64) (result *table.PageResult, err error) {
 	result = &table.PageResult{
 		Columns: make([]presto.Column, 0, len(requestedColumns)),
 	}
@@ -202,12 +204,18 @@ func (t *Table) GetRows(splitID []byte, requestedColumns []string, maxBytes int6
 	}
 
 	// Range through the keys in our data store
+	_, span := tracing.Start(ctx, "timeseries.range", tracing.Table(t.Name()))
 	bytesLeft := int(float64(maxBytes) * 0.95) // Leave 5% buffer in case we estimating the size poorly
 	frames := make(map[string][]presto.Column, len(requestedColumns))
-	if err = t.store.Range(query.Begin, query.Until, func(key, value []byte) bool {
+	var decoding time.Duration
+	var decoded int
+	err = storage.RangeContext(ctx, t.store, query.Begin, query.Until, func(key, value []byte) bool {
 
 		// Read the data frame from the specified offset
+		start := time.Now()
 		frame, readError := t.readDataFrame(localSchema, value, bytesLeft)
+		decoding += time.Since(start)
+		decoded++
 
 		// Set the next token if we don't have enough to process
 		if readError == io.ErrShortBuffer {
@@ -229,9 +237,18 @@ func (t *Table) GetRows(splitID []byte, requestedColumns []string, maxBytes int6
 
 		bytesLeft -= frame.Size()
 		return readError != io.EOF
-	}); err != nil {
-		t.monitor.Warning(errors.Internal("range through the key failed", err))
-		return
+	})
+
+	span.SetAttributes(
+		attribute.Int("frames", decoded),
+		attribute.Int64("decode.duration_ns", int64(decoding)),
+	)
+	tracing.End(span, err)
+	if err != nil {
+		if ctx.Err() == nil {
+			t.monitor.Warning(errors.Internal("range through the key failed", err))
+		}
+		return nil, err
 	}
 
 	// Merge columns together at once, reducing allocations
@@ -263,8 +280,8 @@ func (t *Table) readDataFrame(schema typeof.Schema, buffer []byte, maxBytes int)
 	return result, io.EOF
 }
 
-// Append appends a block to the store.
-func (t *Table) Append(block block.Block) error {
+// Append appends a block to the store, unless the context is cancelled.
+func (t *Table) Append(ctx context.Context, block block.Block) error {
 
 	// Get the min timestamp of the block
 	ts, hasTs := block.Min(t.sortBy)
@@ -283,7 +300,7 @@ func (t *Table) Append(block block.Block) error {
 	t.schema.Store(block.Schema())
 
 	// Append the block to the store
-	return t.store.Append(key.New(string(block.Key), time.Unix(0, ts)), buffer, t.ttl)
+	return storage.AppendContext(ctx, t.store, key.New(string(block.Key), time.Unix(0, ts)), buffer, t.ttl)
 }
 
 // getSchema gets the latest ingested schema.
@@ -4,6 +4,7 @@
 package timeseries_test
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
 	"testing"
@@ -72,7 +73,7 @@ func TestTimeseries_DynamicSchema(t *testing.T) {
 		blocks, err := block.FromOrcBy(b, tableConf.HashBy, nil, apply)
 		assert.NoError(t, err)
 		for _, block := range blocks {
-			assert.NoError(t, eventlog.Append(block))
+			assert.NoError(t, eventlog.Append(context.Background(), block))
 		}
 	}
 
@@ -91,11 +92,18 @@ func TestTimeseries_DynamicSchema(t *testing.T) {
 		assert.Equal(t, "127.0.0.1", splits[0].Addrs[0])
 
 		// Get the rows
-		page, err := eventlog.GetRows(splits[0].Key, []string{"string1"}, 1*1024*1024)
+		page, err := eventlog.GetRows(context.Background(), splits[0].Key, []string{"string1"}, 1*1024*1024)
 		assert.NotNil(t, page)
 		assert.NoError(t, err)
 		assert.Len(t, page.Columns, 1)
 		assert.Equal(t, 5, page.Columns[0].Count())
+
+		// A cancelled request stops scanning
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		page, err = eventlog.GetRows(ctx, splits[0].Key, []string{"string1"}, 1*1024*1024)
+		assert.Nil(t, page)
+		assert.Equal(t, context.Canceled, err)
 	}
 
 	// Append a file with a different schema
@@ -106,7 +114,7 @@ func TestTimeseries_DynamicSchema(t *testing.T) {
 		blocks, err := block.FromOrcBy(b, tableConf.HashBy, nil, apply)
 		assert.NoError(t, err)
 		for _, block := range blocks {
-			assert.NoError(t, eventlog.Append(block))
+			assert.NoError(t, eventlog.Append(context.Background(), block))
 		}
 	}
 
@@ -125,7 +133,7 @@ func TestTimeseries_DynamicSchema(t *testing.T) {
 		assert.Equal(t, "127.0.0.1", splits[0].Addrs[0])
 
 		// Get the rows
-		page, err := eventlog.GetRows(splits[0].Key, []string{"string1", "long1"}, 1*1024*1024)
+		page, err := eventlog.GetRows(context.Background(), splits[0].Key, []string{"string1", "long1"}, 1*1024*1024)
 		assert.NotNil(t, page)
 		assert.NoError(t, err)
 		assert.Len(t, page.Columns, 2)
@@ -10,6 +10,7 @@ import (
 	_ "net/http/pprof"
 	"os"
 	"os/signal"
+	"path"
 	"syscall"
 	"time"
 
@@ -22,7 +23,9 @@ import (
 	"github.com/kelindar/talaria/internal/config/static"
 	"github.com/kelindar/talaria/internal/monitor"
 	"github.com/kelindar/talaria/internal/monitor/logging"
+	"github.com/kelindar/talaria/internal/monitor/prometheus"
 	"github.com/kelindar/talaria/internal/monitor/statsd"
+	"github.com/kelindar/talaria/internal/monitor/tracing"
 	script "github.com/kelindar/talaria/internal/scripting"
 	mlog "github.com/kelindar/talaria/internal/scripting/log"
 	mnet "github.com/kelindar/talaria/internal/scripting/net"
@@ -30,7 +33,9 @@ import (
 	"github.com/kelindar/talaria/internal/server"
 	"github.com/kelindar/talaria/internal/server/cluster"
 	"github.com/kelindar/talaria/internal/storage"
-	"github.com/kelindar/talaria/internal/storage/disk"
+	"github.com/kelindar/talaria/internal/storage/cold"
+	"github.com/kelindar/talaria/internal/storage/engine"
+	"github.com/kelindar/talaria/internal/storage/memory"
 	"github.com/kelindar/talaria/internal/storage/writer"
 	"github.com/kelindar/talaria/internal/table"
 	"github.com/kelindar/talaria/internal/table/log"
@@ -51,11 +56,19 @@ func main() {
 	configure := config.Load(ctx, 60*time.Second, static.New(), env.New("TALARIA"), s3Configurer)
 	conf := configure()
 
-	// Setup gossip
-	gossip := cluster.New(7946)
+	// Setup gossip, encrypted if the keys are configured
+	var gossipKeys [][]byte
+	if conf.Gossip != nil {
+		keys, err := cluster.DecodeKeys(conf.Gossip.Keys)
+		if err != nil {
+			panic(err)
+		}
+		gossipKeys = keys
+	}
+	gossip := cluster.New(7946, gossipKeys...)
 
 	// Create a log table and a simple stdout monitor
-	stats := statsd.New(conf.Statsd.Host, int(conf.Statsd.Port))
+	stats, metrics := newStats(conf)
 	logTable := log.New(configure, gossip, monitor.New(
 		logging.NewStandard(), stats, conf.AppName, conf.Env), // Use stdout monitor
 	)
@@ -67,6 +80,9 @@ func main() {
 	// Updating the logger to use the composite logger. This is to make sure the logs from the config is sent to log table as well as stdout
 	s3Configurer.SetLogger(logger)
 
+	// Setup the tracing of the requests, spans are only exported if an endpoint is configured
+	stopTracing := tracing.Setup(conf.Tracing, conf.AppName, conf.Env)
+
 	// Create a script loader
 	loader := script.NewLoader([]lua.Module{
 		mlog.New(monitor),
@@ -88,6 +104,11 @@ func main() {
 		cancel()       // Cancel the context
 		gossip.Close() // Close the gossip layer
 		server.Close() // Close the server and database
+
+		// Flush the pending spans
+		if err := stopTracing(context.Background()); err != nil {
+			monitor.Warning(err)
+		}
 	})
 
 	// Join the cluster
@@ -96,7 +117,7 @@ func main() {
 
 	// run HTTP server for readiness and liveness probes if k8s config is set
 	if conf.K8s != nil {
-		startHTTPServerAsync(conf.K8s.ProbePort)
+		startHTTPServerAsync(conf.K8s.ProbePort, metrics)
 	}
 
 	// Start listenHandler
@@ -112,10 +133,42 @@ func openTable(name string, storageConf config.Storage, tableConf config.Table,
 	monitor.Info("server: opening table %s...", name)
 
 	// Create a new storage layer and optional compaction
-	store := storage.Storage(disk.Open(storageConf.Directory, name, monitor, storageConf.Badger))
-	if tableConf.Compact != nil {
+	store := openStorage(name, storageConf, tableConf, monitor)
+	switch {
+	case tableConf.Compact != nil && tableConf.Tiers != nil:
+		panic(fmt.Errorf("server: table %s can not have both compaction and tiers configured", name))
+
+	case tableConf.Compact != nil:
+		for _, compact := range append([]config.Compaction{*tableConf.Compact}, tableConf.Compact.Pipelines...) {
+			if sort := compact.Sort; sort != nil && sort.By == "" {
+				sort.By = tableConf.SortBy
+			}
+		}
+		if tableConf.Compact.CommitLog == "" {
+			tableConf.Compact.CommitLog = path.Join(storageConf.Directory, name, "compaction.log")
+		}
+
+		var err error
+		ttl := time.Duration(tableConf.TTL) * time.Second
+		store, err = writer.ForCompaction(tableConf.Compact, ttl, tableConf.SortBy, monitor, store, loader)
+		if err != nil {
+			panic(err)
+		}
+
+		// Optionally, make the compacted files queryable through the table
+		if tableConf.Compact.ReadThrough {
+			tier, err := writer.ForReadThrough(tableConf.Compact, tableConf.HashBy, tableConf.SortBy, monitor)
+			if err != nil {
+				panic(err)
+			}
+			store = cold.ReadThrough(store, tier)
+		}
+
+	// Otherwise, optionally age the blocks into the storage tiers
+	case tableConf.Tiers != nil:
 		var err error
-		store, err = writer.ForCompaction(tableConf.Compact, monitor, store, loader)
+		ttl := time.Duration(tableConf.TTL) * time.Second
+		store, err = writer.ForTiers(tableConf.Tiers, ttl, tableConf.HashBy, tableConf.SortBy, monitor, store)
 		if err != nil {
 			panic(err)
 		}
@@ -130,6 +183,19 @@ func openTable(name string, storageConf config.Storage, tableConf config.Table,
 	return timeseries.New(name, cluster, monitor, store, &tableConf, streams)
 }
 
+// openStorage opens the buffer of a table, either in memory or on disk with its storage engine
+func openStorage(name string, storageConf config.Storage, tableConf config.Table, monitor monitor.Monitor) storage.Storage {
+	if tableConf.Memory != nil {
+		return memory.New(tableConf.Memory.MaxSize, monitor)
+	}
+
+	store, err := engine.Open(tableConf.Engine, name, storageConf, monitor)
+	if err != nil {
+		panic(err)
+	}
+	return store
+}
+
 // onSignal hooks a callback for a signal.
 func onSignal(callback func(sig os.Signal)) {
 	c := make(chan os.Signal, 1)
@@ -141,13 +207,40 @@ func onSignal(callback func(sig os.Signal)) {
 	}()
 }
 
-func startHTTPServerAsync(portNum int32) {
+// newStats creates the client of the configured metrics exporter, along with the handler which
+// exposes the metrics if they are scraped
+func newStats(conf *config.Config) (monitor.StatsdClient, http.Handler) {
+	exporter := config.MetricsStatsD
+	if conf.Metrics != nil && conf.Metrics.Exporter != "" {
+		exporter = conf.Metrics.Exporter
+	}
+
+	switch exporter {
+	case config.MetricsStatsD:
+		return statsd.New(conf.Statsd.Host, int(conf.Statsd.Port)), nil
+	case config.MetricsPrometheus:
+		if conf.K8s == nil {
+			panic(fmt.Errorf("the prometheus metrics exporter requires the k8s section, which serves the metrics"))
+		}
+
+		prom := prometheus.New()
+		return prom, prom.Handler()
+	default:
+		panic(fmt.Errorf("unsupported metrics exporter %s", exporter))
+	}
+}
+
+// startHTTPServerAsync runs the HTTP server for the probes, the profiler and the metrics
+func startHTTPServerAsync(portNum int32, metrics http.Handler) {
 	go func() {
 		handler := mux.NewRouter()
 		handler.HandleFunc("/healthz", func(resp http.ResponseWriter, req *http.Request) {
 			_, _ = resp.Write([]byte(`talaria-health-check`))
 		}).Methods(http.MethodGet, http.MethodHead)
 		handler.PathPrefix("/debug/pprof/").Handler(http.DefaultServeMux)
+		if metrics != nil {
+			handler.Handle("/metrics", metrics).Methods(http.MethodGet)
+		}
 
 		server := &http.Server{
 			Addr:    fmt.Sprintf(":%d", portNum),
@@ -39,6 +39,7 @@ type IngestRequest struct {
 	//	*IngestRequest_Csv
 	//	*IngestRequest_Url
 	//	*IngestRequest_Parquet
+	//	*IngestRequest_Arrow
 	Data isIngestRequest_Data `protobuf_oneof:"data"`
 }
 
@@ -96,12 +97,16 @@ type IngestRequest_Url struct {
 type IngestRequest_Parquet struct {
 	Parquet []byte `protobuf:"bytes,5,opt,name=parquet,proto3,oneof" json:"parquet,omitempty"`
 }
+type IngestRequest_Arrow struct {
+	Arrow []byte `protobuf:"bytes,6,opt,name=arrow,proto3,oneof" json:"arrow,omitempty"`
+}
 
 func (*IngestRequest_Batch) isIngestRequest_Data()   {}
 func (*IngestRequest_Orc) isIngestRequest_Data()     {}
 func (*IngestRequest_Csv) isIngestRequest_Data()     {}
 func (*IngestRequest_Url) isIngestRequest_Data()     {}
 func (*IngestRequest_Parquet) isIngestRequest_Data() {}
+func (*IngestRequest_Arrow) isIngestRequest_Data()   {}
 
 func (m *IngestRequest) GetData() isIngestRequest_Data {
 	if m != nil {
@@ -145,6 +150,13 @@ func (m *IngestRequest) GetParquet() []byte {
 	return nil
 }
 
+func (m *IngestRequest) GetArrow() []byte {
+	if x, ok := m.GetData().(*IngestRequest_Arrow); ok {
+		return x.Arrow
+	}
+	return nil
+}
+
 // XXX_OneofWrappers is for the internal use of the proto package.
 func (*IngestRequest) XXX_OneofWrappers() []interface{} {
 	return []interface{}{
@@ -153,6 +165,7 @@ func (*IngestRequest) XXX_OneofWrappers() []interface{} {
 		(*IngestRequest_Csv)(nil),
 		(*IngestRequest_Url)(nil),
 		(*IngestRequest_Parquet)(nil),
+		(*IngestRequest_Arrow)(nil),
 	}
 }
 
@@ -1457,75 +1470,75 @@ func init() {
 func init() { proto.RegisterFile("talaria.proto", fileDescriptor_8f344df92059c5ff) }
 
 var fileDescriptor_8f344df92059c5ff = []byte{
-	// 1074 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x56, 0x4b, 0x6f, 0x1b, 0x55,
-	0x14, 0x9e, 0x6b, 0x7b, 0xfc, 0x38, 0x79, 0xf6, 0x12, 0x25, 0x53, 0x83, 0x46, 0xd1, 0x08, 0x85,
-	0x80, 0x5a, 0x23, 0x5c, 0x13, 0x41, 0x2b, 0x55, 0xaa, 0x9b, 0xb4, 0x09, 0x12, 0x20, 0x6e, 0x2b,
-	0x24, 0x96, 0x13, 0xe7, 0x26, 0x31, 0x1d, 0xcf, 0x75, 0xe7, 0x5e, 0xa7, 0x31, 0x0b, 0x84, 0xf8,
-	0x05, 0xec, 0xd8, 0xb3, 0x62, 0xc9, 0x92, 0x9f, 0xc0, 0x32, 0xcb, 0x2e, 0x89, 0x23, 0x24, 0x96,
-	0xfd, 0x09, 0xe8, 0xbe, 0xe6, 0x95, 0xb8, 0x12, 0xbb, 0x39, 0xdf, 0x79, 0x7c, 0xe7, 0x9e, 0x73,
-	0xe7, 0x9b, 0x81, 0x25, 0x11, 0x46, 0x61, 0x32, 0x0c, 0x3b, 0xe3, 0x84, 0x09, 0x86, 0x1b, 0xc6,
-	0x0c, 0x7e, 0x45, 0xb0, 0x74, 0x10, 0x9f, 0x50, 0x2e, 0x08, 0x7d, 0x39, 0xa1, 0x5c, 0xe0, 0x2d,
-	0x70, 0x0f, 0x43, 0x31, 0x38, 0xf5, 0xd0, 0x26, 0xda, 0x5e, 0xe8, 0x2e, 0x77, 0x6c, 0x66, 0x5f,
-	0xa2, 0xfb, 0x0e, 0xd1, 0x6e, 0x8c, 0xa1, 0xca, 0x92, 0x81, 0x57, 0xd9, 0x44, 0xdb, 0x8b, 0xfb,
-	0x0e, 0x91, 0x86, 0xc4, 0x06, 0xfc, 0xcc, 0xab, 0x5a, 0x6c, 0xc0, 0xcf, 0x24, 0x36, 0x49, 0x22,
-	0xaf, 0xb6, 0x89, 0xb6, 0x5b, 0x12, 0x9b, 0x24, 0x11, 0x6e, 0x43, 0x63, 0x1c, 0x26, 0x2f, 0x27,
-	0x54, 0x78, 0xae, 0x89, 0xb5, 0x40, 0xbf, 0x0e, 0xb5, 0xa3, 0x50, 0x84, 0xc1, 0x2a, 0x2c, 0xdb,
-	0xc6, 0xf8, 0x98, 0xc5, 0x9c, 0x06, 0xbf, 0x21, 0x70, 0x55, 0x13, 0xf8, 0x53, 0x68, 0x70, 0x91,
-	0x0c, 0xe3, 0x13, 0xee, 0xa1, 0xcd, 0xea, 0xf6, 0x42, 0xf7, 0xdd, 0x62, 0x97, 0x9d, 0x67, 0xda,
-	0xbb, 0x17, 0x8b, 0x64, 0x4a, 0x6c, 0x2c, 0xde, 0x82, 0x3a, 0x3d, 0xa3, 0xb1, 0xe0, 0x5e, 0x45,
-	0x65, 0x65, 0x67, 0xdb, 0x93, 0x30, 0x31, 0xde, 0xf6, 0x7d, 0x58, 0xcc, 0x17, 0xc0, 0xab, 0x50,
-	0x7d, 0x41, 0xa7, 0x6a, 0x20, 0x4b, 0x44, 0x3e, 0xe2, 0x35, 0x70, 0xcf, 0xc2, 0x68, 0x42, 0xf5,
-	0xf1, 0x89, 0x36, 0xee, 0x57, 0x3e, 0x43, 0xc1, 0xcf, 0x08, 0x5c, 0x55, 0x0d, 0x7f, 0x6c, 0x63,
-	0x74, 0x8b, 0xb7, 0x8b, 0x64, 0x9d, 0x6f, 0xa5, 0x4f, 0x37, 0xa8, 0xe3, 0xda, 0xfb, 0x00, 0x19,
-	0x78, 0x03, 0xe9, 0xfb, 0x79, 0xd2, 0x7c, 0xf7, 0x2a, 0x2b, 0xdf, 0xc4, 0x9f, 0x08, 0x5c, 0x05,
-	0xe2, 0x75, 0x70, 0x87, 0xb1, 0xb8, 0xd7, 0x55, 0x75, 0x5c, 0xb9, 0x3d, 0x65, 0x1a, 0x7c, 0xa7,
-	0xa7, 0x6a, 0x55, 0x0d, 0xbe, 0xd3, 0x93, 0x9b, 0x39, 0x8e, 0x58, 0x28, 0x3d, 0x72, 0x8b, 0x48,
-	0x6e, 0xc6, 0x00, 0xd8, 0x83, 0xba, 0x9e, 0xa4, 0x5a, 0xe6, 0xd2, 0xbe, 0x43, 0x8c, 0x8d, 0xd7,
-	0xa0, 0x76, 0xc8, 0x58, 0xa4, 0x96, 0xd9, 0xdc, 0x77, 0x88, 0xb2, 0x24, 0x2a, 0x86, 0x23, 0xea,
-	0xd5, 0x0d, 0x85, 0xb2, 0x24, 0xfa, 0x3d, 0x67, 0xb1, 0xd7, 0x30, 0x35, 0x94, 0xd5, 0x6f, 0x98,
-	0xb3, 0x05, 0xb7, 0x60, 0x65, 0x97, 0xf2, 0x41, 0x32, 0x3c, 0xa4, 0xe6, 0x46, 0x06, 0x0f, 0x61,
-	0x35, 0x83, 0xf4, 0x5d, 0xc0, 0x1f, 0x41, 0x5d, 0x84, 0x87, 0x11, 0xb5, 0x17, 0x00, 0xa7, 0xc3,
-	0x78, 0x2e, 0xe1, 0x2f, 0xa9, 0x08, 0x89, 0x89, 0x08, 0x4e, 0xa1, 0x95, 0x82, 0x78, 0x1d, 0xea,
-	0x7c, 0x70, 0x4a, 0x47, 0xa1, 0x9a, 0x48, 0x8b, 0x18, 0x4b, 0x6e, 0x54, 0x85, 0xab, 0x81, 0xb4,
-	0x88, 0x36, 0xf0, 0x5d, 0x68, 0x0c, 0x58, 0x34, 0x19, 0xc5, 0xdc, 0xab, 0x2a, 0x9e, 0x77, 0x52,
-	0x9e, 0xc7, 0x0a, 0x57, 0x44, 0x36, 0x26, 0xf8, 0x0a, 0x20, 0x83, 0x31, 0x86, 0x5a, 0x1c, 0x8e,
-	0xa8, 0x21, 0x52, 0xcf, 0x12, 0x13, 0xd3, 0xb1, 0x65, 0x51, 0xcf, 0xd8, 0x93, 0x24, 0xa3, 0x11,
-	0x8d, 0x85, 0x9a, 0x79, 0x8b, 0x58, 0x33, 0xf8, 0x03, 0xc1, 0xea, 0x53, 0x2a, 0x9e, 0x8d, 0xa3,
-	0xa1, 0xe0, 0xf6, 0x05, 0xfd, 0x7f, 0x27, 0xf0, 0x8a, 0x27, 0x68, 0xa5, 0xcd, 0x4a, 0xcf, 0xf1,
-	0x30, 0x12, 0x34, 0xe1, 0x5e, 0x4d, 0x7b, 0x8c, 0x89, 0xdf, 0x83, 0xd6, 0x28, 0x3c, 0xd7, 0xac,
-	0x6a, 0xa7, 0x2e, 0xc9, 0x00, 0xe9, 0x8d, 0xe9, 0xb9, 0x78, 0xce, 0x5e, 0xd0, 0x58, 0xed, 0x76,
-	0x91, 0x64, 0x40, 0xf0, 0x1d, 0xdc, 0xca, 0x75, 0x6c, 0xb6, 0xb5, 0x05, 0x75, 0xae, 0xab, 0xa1,
-	0xd2, 0x8b, 0xa7, 0x02, 0x89, 0xf1, 0x16, 0x4b, 0x57, 0xca, 0xa5, 0xbb, 0xd0, 0xdc, 0x8b, 0x8f,
-	0xc6, 0x6c, 0x18, 0x0b, 0x39, 0xc7, 0x53, 0xc6, 0x85, 0x9d, 0xad, 0x7c, 0x96, 0xd8, 0x98, 0x25,
-	0x42, 0x25, 0xba, 0x44, 0x3d, 0x07, 0x5f, 0x80, 0xab, 0x28, 0xe4, 0x69, 0x15, 0xc9, 0xc1, 0xae,
-	0xca, 0x59, 0x24, 0xd6, 0xc4, 0x1f, 0x80, 0x2b, 0xd3, 0xad, 0x28, 0xdc, 0xca, 0xde, 0x53, 0x43,
-	0x46, 0xb4, 0x3f, 0xf8, 0x11, 0x96, 0x9f, 0x52, 0x41, 0xd8, 0xab, 0x74, 0x15, 0xf3, 0x8b, 0xe6,
-	0xc6, 0x5e, 0x29, 0x8e, 0xbd, 0x0d, 0xcd, 0x51, 0x78, 0xde, 0x9f, 0x0a, 0xca, 0xd5, 0xba, 0xab,
-	0x24, 0xb5, 0x8b, 0xe7, 0xaf, 0x95, 0xcf, 0x7f, 0x06, 0x2b, 0x29, 0xbf, 0x19, 0xec, 0x87, 0x19,
-	0x8d, 0x9e, 0xec, 0x4a, 0xe9, 0x7e, 0x16, 0x78, 0x13, 0xf6, 0xea, 0x31, 0x9b, 0xc4, 0x76, 0x42,
-	0xa9, 0x5d, 0xe4, 0xad, 0x96, 0x79, 0xff, 0xa9, 0x40, 0x5d, 0x57, 0xc3, 0x9d, 0xbc, 0x9c, 0x2c,
-	0x74, 0xd7, 0x4b, 0x6c, 0x5f, 0x1f, 0x1f, 0x48, 0x6f, 0x26, 0x33, 0x9d, 0xbc, 0xcc, 0xcc, 0x89,
-	0xdf, 0xe9, 0x65, 0xf2, 0xd3, 0x2b, 0xca, 0xcf, 0x42, 0xd7, 0xbb, 0x96, 0xf1, 0x44, 0xfb, 0xf3,
-	0xc2, 0xf4, 0x49, 0x41, 0x98, 0x16, 0xba, 0x1b, 0xd7, 0x92, 0xb4, 0x9c, 0xe7, 0x14, 0xeb, 0x4e,
-	0x4e, 0xb1, 0x6e, 0xea, 0xab, 0xcf, 0x58, 0xc4, 0x53, 0x25, 0xbb, 0x93, 0x53, 0xb2, 0xb7, 0x9d,
-	0x42, 0x2b, 0xdc, 0xdd, 0x9c, 0xc2, 0xbd, 0xb5, 0x99, 0x92, 0xf4, 0x7d, 0x0e, 0x4b, 0x85, 0x31,
-	0xca, 0x37, 0x3a, 0x9e, 0x44, 0x91, 0xde, 0x6d, 0x93, 0x68, 0x43, 0x5e, 0xf3, 0xa1, 0xfd, 0x86,
-	0xb9, 0x44, 0x3d, 0x07, 0x0f, 0x0a, 0xa9, 0x3b, 0xbd, 0x39, 0xa9, 0x6b, 0xe0, 0x46, 0x4c, 0x7e,
-	0x35, 0x65, 0x6e, 0x95, 0x68, 0x23, 0x78, 0x04, 0x2b, 0xa5, 0xe1, 0xce, 0x49, 0xf7, 0xa0, 0x71,
-	0xc4, 0x26, 0x4a, 0x75, 0x65, 0x01, 0x44, 0xac, 0x99, 0xe7, 0x57, 0x93, 0x9b, 0xcf, 0x2f, 0xe7,
-	0xa9, 0xd3, 0x9b, 0x44, 0x1b, 0x01, 0x81, 0xe5, 0xe2, 0x68, 0xe6, 0x67, 0xf3, 0xe1, 0x0f, 0xd4,
-	0x9e, 0x5c, 0x1b, 0xaa, 0x66, 0xfa, 0x32, 0x2d, 0x12, 0x6d, 0x74, 0x9f, 0x40, 0xe3, 0x20, 0x3e,
-	0x49, 0x28, 0xe7, 0xf8, 0x01, 0xd4, 0xf5, 0x8f, 0x04, 0xce, 0x16, 0x57, 0xf8, 0xe5, 0x69, 0x6f,
-	0x5c, 0xc3, 0xcd, 0x1f, 0x87, 0xd3, 0xbd, 0x40, 0xe0, 0x7e, 0x33, 0xa1, 0xc9, 0x14, 0x3f, 0x82,
-	0xa6, 0xfd, 0x0a, 0xe1, 0xec, 0x56, 0x96, 0xbe, 0x55, 0xed, 0xdb, 0x37, 0x78, 0x6c, 0x31, 0xbc,
-	0x0b, 0xad, 0x54, 0x1b, 0x71, 0x16, 0x59, 0x56, 0xf8, 0x76, 0xfb, 0x26, 0x57, 0x5a, 0xe5, 0x21,
-	0x34, 0x8c, 0x0c, 0xe0, 0x8d, 0x7c, 0x60, 0x4e, 0x98, 0xda, 0xde, 0x75, 0x87, 0xcd, 0xef, 0xf7,
-	0x2e, 0x2e, 0x7d, 0xe7, 0xf5, 0xa5, 0xef, 0xbc, 0xb9, 0xf4, 0xd1, 0x4f, 0x33, 0x1f, 0xfd, 0x3e,
-	0xf3, 0xd1, 0x5f, 0x33, 0x1f, 0x5d, 0xcc, 0x7c, 0xf4, 0xf7, 0xcc, 0x47, 0xff, 0xce, 0x7c, 0xe7,
-	0xcd, 0xcc, 0x47, 0xbf, 0x5c, 0xf9, 0xce, 0xc5, 0x95, 0xef, 0xbc, 0xbe, 0xf2, 0x9d, 0xc3, 0xba,
-	0xfa, 0x71, 0xbc, 0xf7, 0x5f, 0x00, 0x00, 0x00, 0xff, 0xff, 0x79, 0x20, 0x85, 0x26, 0x49, 0x0a,
-	0x00, 0x00,
+	// 1080 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x56, 0x4d, 0x6f, 0x1b, 0x45,
+	0x18, 0xde, 0xb1, 0xbd, 0x6b, 0xfb, 0xcd, 0xf7, 0x10, 0x25, 0x5b, 0x83, 0x56, 0xd1, 0x0a, 0x85,
+	0x80, 0x5a, 0x23, 0x5c, 0x13, 0x41, 0x2b, 0x55, 0xaa, 0x9b, 0xb4, 0x09, 0x12, 0x20, 0xa6, 0x15,
+	0x12, 0xc7, 0x8d, 0x33, 0x49, 0x4c, 0xd7, 0x3b, 0xee, 0xce, 0x38, 0x1f, 0x1c, 0x10, 0xe2, 0x17,
+	0xf0, 0x1b, 0x38, 0x71, 0x83, 0x23, 0x3f, 0x81, 0x63, 0x8e, 0x3d, 0x12, 0x47, 0x48, 0x1c, 0xfb,
+	0x13, 0xd0, 0x7c, 0xed, 0x87, 0x13, 0x57, 0xe2, 0x36, 0xcf, 0xf3, 0x7e, 0x3c, 0x33, 0xef, 0x3b,
+	0xf3, 0xee, 0xc2, 0x82, 0x88, 0xe2, 0x28, 0x1d, 0x44, 0xed, 0x51, 0xca, 0x04, 0xc3, 0x75, 0x03,
+	0xc3, 0xdf, 0x11, 0x2c, 0xec, 0x27, 0xc7, 0x94, 0x0b, 0x42, 0x5f, 0x8d, 0x29, 0x17, 0x78, 0x13,
+	0xdc, 0x83, 0x48, 0xf4, 0x4f, 0x7c, 0xb4, 0x81, 0xb6, 0xe6, 0x3a, 0x8b, 0x6d, 0x1b, 0xd9, 0x93,
+	0xec, 0x9e, 0x43, 0xb4, 0x19, 0x63, 0xa8, 0xb2, 0xb4, 0xef, 0x57, 0x36, 0xd0, 0xd6, 0xfc, 0x9e,
+	0x43, 0x24, 0x90, 0x5c, 0x9f, 0x9f, 0xfa, 0x55, 0xcb, 0xf5, 0xf9, 0xa9, 0xe4, 0xc6, 0x69, 0xec,
+	0xd7, 0x36, 0xd0, 0x56, 0x53, 0x72, 0xe3, 0x34, 0xc6, 0x2d, 0xa8, 0x8f, 0xa2, 0xf4, 0xd5, 0x98,
+	0x0a, 0xdf, 0x35, 0xbe, 0x96, 0xc0, 0x6b, 0xe0, 0x46, 0x69, 0xca, 0xce, 0x7c, 0xcf, 0x58, 0x34,
+	0xec, 0x79, 0x50, 0x3b, 0x8c, 0x44, 0x14, 0x2e, 0xc3, 0xa2, 0xdd, 0x30, 0x1f, 0xb1, 0x84, 0xd3,
+	0xf0, 0x57, 0x04, 0xae, 0xda, 0x1c, 0xfe, 0x14, 0xea, 0x5c, 0xa4, 0x83, 0xe4, 0x98, 0xfb, 0x68,
+	0xa3, 0xba, 0x35, 0xd7, 0x79, 0xb7, 0xbc, 0xfb, 0xf6, 0x73, 0x6d, 0xdd, 0x4d, 0x44, 0x7a, 0x41,
+	0xac, 0x2f, 0xde, 0x04, 0x8f, 0x9e, 0xd2, 0x44, 0x70, 0xbf, 0xb2, 0x51, 0x2d, 0x9d, 0x79, 0x57,
+	0xd2, 0xc4, 0x58, 0x5b, 0x0f, 0x60, 0xbe, 0x98, 0x00, 0x2f, 0x43, 0xf5, 0x25, 0xbd, 0x50, 0x85,
+	0x5a, 0x20, 0x72, 0x89, 0x57, 0xc1, 0x3d, 0x8d, 0xe2, 0x31, 0xd5, 0x65, 0x21, 0x1a, 0x3c, 0xa8,
+	0x7c, 0x86, 0xc2, 0x9f, 0x11, 0xb8, 0x2a, 0x1b, 0xfe, 0xd8, 0xfa, 0xe8, 0x2d, 0xde, 0x29, 0x8b,
+	0xb5, 0xbf, 0x95, 0x36, 0xbd, 0x41, 0xed, 0xd7, 0xda, 0x03, 0xc8, 0xc9, 0x5b, 0x44, 0xdf, 0x2f,
+	0x8a, 0x16, 0x77, 0xaf, 0xa2, 0x8a, 0x9b, 0xf8, 0x13, 0x81, 0xab, 0x48, 0x59, 0xe5, 0x41, 0x22,
+	0xee, 0x77, 0x54, 0x1e, 0x57, 0x56, 0x59, 0x41, 0xc3, 0x6f, 0x77, 0x55, 0xae, 0xaa, 0xe1, 0xb7,
+	0xbb, 0xb2, 0x63, 0x47, 0x31, 0x8b, 0xa4, 0x45, 0x76, 0x17, 0xc9, 0x8e, 0x19, 0x02, 0xfb, 0xe0,
+	0xe9, 0x4a, 0xaa, 0x26, 0x2f, 0xec, 0x39, 0xc4, 0x60, 0xbc, 0x0a, 0xb5, 0x03, 0xc6, 0x62, 0xd5,
+	0xe4, 0xc6, 0x9e, 0x43, 0x14, 0x92, 0xac, 0x18, 0x0c, 0xa9, 0xef, 0x19, 0x09, 0x85, 0x24, 0xfb,
+	0x3d, 0x67, 0x89, 0x5f, 0x37, 0x39, 0x14, 0xea, 0xd5, 0xcd, 0xd9, 0xc2, 0x15, 0x58, 0xda, 0xa1,
+	0xbc, 0x9f, 0x0e, 0x0e, 0xa8, 0xb9, 0xa9, 0xe1, 0x23, 0x58, 0xce, 0x29, 0x7d, 0x17, 0xf0, 0x47,
+	0xe0, 0x89, 0xe8, 0x20, 0xa6, 0xf6, 0x02, 0xe0, 0xac, 0x18, 0x2f, 0x24, 0xfd, 0x25, 0x15, 0x11,
+	0x31, 0x1e, 0xe1, 0x09, 0x34, 0x33, 0x12, 0xaf, 0x81, 0xc7, 0xfb, 0x27, 0x74, 0x18, 0xa9, 0x8a,
+	0x34, 0x89, 0x41, 0xb2, 0xa3, 0xca, 0x5d, 0x15, 0xa4, 0x49, 0x34, 0xc0, 0xf7, 0xa0, 0xde, 0x67,
+	0xf1, 0x78, 0x98, 0x70, 0xbf, 0xaa, 0x74, 0xde, 0xc9, 0x74, 0x9e, 0x28, 0x5e, 0x09, 0x59, 0x9f,
+	0xf0, 0x2b, 0x80, 0x9c, 0xc6, 0x18, 0x6a, 0x49, 0x34, 0xa4, 0x46, 0x48, 0xad, 0x25, 0x27, 0x2e,
+	0x46, 0x56, 0x45, 0xad, 0xb1, 0x2f, 0x45, 0x86, 0x43, 0x9a, 0x08, 0x55, 0xf3, 0x26, 0xb1, 0x30,
+	0xfc, 0x03, 0xc1, 0xf2, 0x33, 0x2a, 0x9e, 0x8f, 0xe2, 0x81, 0xe0, 0xf6, 0xe1, 0xfe, 0xbf, 0x13,
+	0xf8, 0xe5, 0x13, 0x34, 0xb3, 0xcd, 0x4a, 0xcb, 0xd1, 0x20, 0x16, 0x34, 0xe5, 0x7e, 0x4d, 0x5b,
+	0x0c, 0xc4, 0xef, 0x41, 0x73, 0x18, 0x9d, 0x6b, 0x55, 0xd5, 0x53, 0x97, 0xe4, 0x84, 0xb4, 0x26,
+	0xf4, 0x5c, 0xbc, 0x60, 0x2f, 0x69, 0xa2, 0x1f, 0x2f, 0xc9, 0x89, 0xf0, 0x3b, 0x58, 0x29, 0xec,
+	0xd8, 0x74, 0x6b, 0x13, 0x3c, 0xae, 0xb3, 0xa1, 0xa9, 0x87, 0xa7, 0x1c, 0x89, 0xc7, 0x6f, 0x49,
+	0x5d, 0x99, 0x4e, 0xdd, 0x81, 0xc6, 0x6e, 0x72, 0x38, 0x62, 0x83, 0x44, 0xc8, 0x3a, 0x9e, 0x30,
+	0x2e, 0x6c, 0x6d, 0xe5, 0x5a, 0x72, 0x23, 0x96, 0x0a, 0x15, 0xe8, 0x12, 0xb5, 0x0e, 0xbf, 0x00,
+	0x57, 0x49, 0xc8, 0xd3, 0x2a, 0x91, 0xfd, 0x1d, 0x15, 0x33, 0x4f, 0x2c, 0xc4, 0x1f, 0x80, 0x2b,
+	0xc3, 0xed, 0x50, 0x58, 0xc9, 0xdf, 0xa9, 0x11, 0x23, 0xda, 0x1e, 0xfe, 0x08, 0x8b, 0xcf, 0xa8,
+	0x20, 0xec, 0x2c, 0x6b, 0xc5, 0xec, 0xa4, 0x85, 0xb2, 0x57, 0xca, 0x65, 0x6f, 0x41, 0x63, 0x18,
+	0x9d, 0xf7, 0x2e, 0x04, 0xe5, 0xaa, 0xdd, 0x55, 0x92, 0xe1, 0xf2, 0xf9, 0x6b, 0xd3, 0xe7, 0x3f,
+	0x85, 0xa5, 0x4c, 0xdf, 0x14, 0xf6, 0xc3, 0x5c, 0x46, 0x57, 0x76, 0x69, 0xea, 0x7e, 0x96, 0x74,
+	0x53, 0x76, 0xf6, 0x84, 0x8d, 0x13, 0x5b, 0xa1, 0x0c, 0x97, 0x75, 0xab, 0xd3, 0xba, 0xff, 0x54,
+	0xc0, 0xd3, 0xd9, 0x70, 0xbb, 0x38, 0x4e, 0xe6, 0x3a, 0x6b, 0x53, 0x6a, 0x5f, 0x1f, 0xed, 0x4b,
+	0x6b, 0x3e, 0x66, 0xda, 0xc5, 0x31, 0x33, 0xc3, 0x7f, 0xbb, 0x9b, 0x8f, 0x9f, 0x6e, 0x79, 0xfc,
+	0xcc, 0x75, 0xfc, 0x1b, 0x11, 0x4f, 0xb5, 0xbd, 0x38, 0x98, 0x3e, 0x29, 0x0d, 0xa6, 0xb9, 0xce,
+	0xfa, 0x8d, 0x20, 0x3d, 0xce, 0x0b, 0x13, 0xeb, 0x6e, 0x61, 0x62, 0xdd, 0xb6, 0xaf, 0x1e, 0x63,
+	0x31, 0xcf, 0x26, 0xd9, 0xdd, 0xc2, 0x24, 0x7b, 0xdb, 0x29, 0x94, 0x17, 0xbe, 0x57, 0x98, 0x70,
+	0x6f, 0xdd, 0xcc, 0xd4, 0xe8, 0xfb, 0x1c, 0x16, 0x4a, 0x65, 0x94, 0x2f, 0x3a, 0x19, 0xc7, 0xb1,
+	0xee, 0x6d, 0x83, 0x68, 0x20, 0xaf, 0xf9, 0xc0, 0x7e, 0xc3, 0x5c, 0xa2, 0xd6, 0xe1, 0xc3, 0x52,
+	0xe8, 0x76, 0x77, 0x46, 0xe8, 0x2a, 0xb8, 0x31, 0x4b, 0x8e, 0x75, 0x6c, 0x95, 0x68, 0x10, 0x3e,
+	0x86, 0xa5, 0xa9, 0xe2, 0xce, 0x08, 0xf7, 0xa1, 0x7e, 0xc8, 0xc6, 0x6a, 0xea, 0xca, 0x04, 0x88,
+	0x58, 0x58, 0xd4, 0x57, 0x95, 0x9b, 0xad, 0x2f, 0xeb, 0xa9, 0xc3, 0x1b, 0x44, 0x83, 0x90, 0xc0,
+	0x62, 0xb9, 0x34, 0xb3, 0xa3, 0xf9, 0xe0, 0x07, 0x6a, 0x4f, 0xae, 0x81, 0xca, 0x99, 0x3d, 0xa6,
+	0x79, 0xa2, 0x41, 0xe7, 0x29, 0xd4, 0xf7, 0x93, 0xe3, 0x94, 0x72, 0x8e, 0x1f, 0x82, 0xa7, 0x7f,
+	0x24, 0x70, 0xde, 0xb8, 0xd2, 0xaf, 0x50, 0x6b, 0xfd, 0x06, 0x6f, 0xfe, 0x38, 0x9c, 0xce, 0x25,
+	0x02, 0xf7, 0x9b, 0x31, 0x4d, 0x2f, 0xf0, 0x63, 0x68, 0xd8, 0xaf, 0x10, 0xce, 0x6f, 0xe5, 0xd4,
+	0xb7, 0xaa, 0x75, 0xe7, 0x16, 0x8b, 0x4d, 0x86, 0x77, 0xa0, 0x99, 0xcd, 0x46, 0x9c, 0x7b, 0x4e,
+	0x4f, 0xf8, 0x56, 0xeb, 0x36, 0x53, 0x96, 0xe5, 0x11, 0xd4, 0xcd, 0x18, 0xc0, 0xeb, 0x45, 0xc7,
+	0xc2, 0x60, 0x6a, 0xf9, 0x37, 0x0d, 0x36, 0xbe, 0xd7, 0xbd, 0xbc, 0x0a, 0x9c, 0xd7, 0x57, 0x81,
+	0xf3, 0xe6, 0x2a, 0x40, 0x3f, 0x4d, 0x02, 0xf4, 0xdb, 0x24, 0x40, 0x7f, 0x4d, 0x02, 0x74, 0x39,
+	0x09, 0xd0, 0xdf, 0x93, 0x00, 0xfd, 0x3b, 0x09, 0x9c, 0x37, 0x93, 0x00, 0xfd, 0x72, 0x1d, 0x38,
+	0x97, 0xd7, 0x81, 0xf3, 0xfa, 0x3a, 0x70, 0x0e, 0x3c, 0xf5, 0x43, 0x79, 0xff, 0xbf, 0x01, 0x00,
+	0x6d, 0x45, 0x8a, 0x4e, 0x61, 0x0a, 0x00, 0x00,
 }
 
 func (this *IngestRequest) Equal(that interface{}) bool {
@@ -1678,6 +1691,30 @@ func (this *IngestRequest_Parquet) Equal(that interface{}) bool {
 	}
 	return true
 }
+func (this *IngestRequest_Arrow) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*IngestRequest_Arrow)
+	if !ok {
+		that2, ok := that.(IngestRequest_Arrow)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if !bytes.Equal(this.Arrow, that1.Arrow) {
+		return false
+	}
+	return true
+}
 func (this *IngestResponse) Equal(that interface{}) bool {
 	if that == nil {
 		return this == nil
@@ -2729,6 +2766,14 @@ func (this *IngestRequest_Parquet) GoString() string {
 		`Parquet:` + fmt.Sprintf("%#v", this.Parquet) + `}`}, ", ")
 	return s
 }
+func (this *IngestRequest_Arrow) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&talaria.IngestRequest_Arrow{` +
+		`Arrow:` + fmt.Sprintf("%#v", this.Arrow) + `}`}, ", ")
+	return s
+}
 func (this *IngestResponse) GoString() string {
 	if this == nil {
 		return "nil"
@@ -3457,6 +3502,22 @@ func (m *IngestRequest_Parquet) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	}
 	return len(dAtA) - i, nil
 }
+func (m *IngestRequest_Arrow) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *IngestRequest_Arrow) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	if m.Arrow != nil {
+		i -= len(m.Arrow)
+		copy(dAtA[i:], m.Arrow)
+		i = encodeVarintTalaria(dAtA, i, uint64(len(m.Arrow)))
+		i--
+		dAtA[i] = 0x32
+	}
+	return len(dAtA) - i, nil
+}
 func (m *IngestResponse) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
@@ -4677,6 +4738,18 @@ func (m *IngestRequest_Parquet) Size() (n int) {
 	}
 	return n
 }
+func (m *IngestRequest_Arrow) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Arrow != nil {
+		l = len(m.Arrow)
+		n += 1 + l + sovTalaria(uint64(l))
+	}
+	return n
+}
 func (m *IngestResponse) Size() (n int) {
 	if m == nil {
 		return 0
@@ -5269,6 +5342,16 @@ func (this *IngestRequest_Parquet) String() string {
 	}, "")
 	return s
 }
+func (this *IngestRequest_Arrow) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&IngestRequest_Arrow{`,
+		`Arrow:` + fmt.Sprintf("%v", this.Arrow) + `,`,
+		`}`,
+	}, "")
+	return s
+}
 func (this *IngestResponse) String() string {
 	if this == nil {
 		return "nil"
@@ -5884,6 +5967,39 @@ func (m *IngestRequest) Unmarshal(dAtA []byte) error {
 			copy(v, dAtA[iNdEx:postIndex])
 			m.Data = &IngestRequest_Parquet{v}
 			iNdEx = postIndex
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Arrow", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTalaria
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTalaria
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTalaria
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := make([]byte, postIndex-iNdEx)
+			copy(v, dAtA[iNdEx:postIndex])
+			m.Data = &IngestRequest_Arrow{v}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipTalaria(dAtA[iNdEx:])